@@ -6,6 +6,7 @@ package tls
 
 import (
 	"crypto/rsa"
+	"crypto/x509"
 	"io"
 	"os"
 )
@@ -65,6 +66,10 @@ type ConnectionState struct {
 	CipherSuite        string
 	Error              alertType
 	NegotiatedProtocol string
+
+	// PeerCertificates contains the certificate chain presented by the
+	// server, leaf first. It is only set on the client side.
+	PeerCertificates []*x509.Certificate
 }
 
 // A Config structure is used to configure a TLS client or server. After one
@@ -193,7 +193,7 @@ func (h *serverHandshake) loop(writeChan chan<- interface{}, controlChan chan<-
 		return
 	}
 
-	controlChan <- ConnectionState{true, "TLS_RSA_WITH_RC4_128_SHA", 0, clientProtocol}
+	controlChan <- ConnectionState{true, "TLS_RSA_WITH_RC4_128_SHA", 0, clientProtocol, nil}
 
 	finishedHash.Write(clientFinished.marshal())
 
@@ -243,7 +243,7 @@ func (h *serverHandshake) error(e alertType) {
 			for _ = range h.msgChan {
 			}
 		}()
-		h.controlChan <- ConnectionState{false, "", e, ""}
+		h.controlChan <- ConnectionState{false, "", e, "", nil}
 		close(h.controlChan)
 		h.writeChan <- alert{alertLevelError, e}
 	}
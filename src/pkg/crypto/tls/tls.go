@@ -6,7 +6,10 @@
 package tls
 
 import (
+	"crypto/x509"
+	"encoding/pem"
 	"io"
+	"io/ioutil"
 	"os"
 	"net"
 	"time"
@@ -184,3 +187,46 @@ func NewListener(listener net.Listener, config *Config) (l *Listener) {
 	l.config = config
 	return
 }
+
+// LoadX509KeyPair reads and parses a public/private key pair from a pair of
+// files. The files must contain PEM encoded data: certFile holds one or more
+// certificates, leaf first, and keyFile holds an RSA private key.
+func LoadX509KeyPair(certFile string, keyFile string) (cert Certificate, err os.Error) {
+	certPEMBlock, err := ioutil.ReadFile(certFile)
+	if err != nil {
+		return
+	}
+
+	var certDERBlock *pem.Block
+	for {
+		certDERBlock, certPEMBlock = pem.Decode(certPEMBlock)
+		if certDERBlock == nil {
+			break
+		}
+		if certDERBlock.Type == "CERTIFICATE" {
+			certs := make([][]byte, len(cert.Certificate)+1)
+			copy(certs, cert.Certificate)
+			certs[len(cert.Certificate)] = certDERBlock.Bytes
+			cert.Certificate = certs
+		}
+	}
+
+	if len(cert.Certificate) == 0 {
+		err = os.ErrorString("crypto/tls: failed to parse certificate PEM data")
+		return
+	}
+
+	keyPEMBlock, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		return
+	}
+
+	keyDERBlock, _ := pem.Decode(keyPEMBlock)
+	if keyDERBlock == nil || keyDERBlock.Type != "RSA PRIVATE KEY" {
+		err = os.ErrorString("crypto/tls: failed to parse key PEM data")
+		return
+	}
+
+	cert.PrivateKey, err = x509.ParsePKCS1PrivateKey(keyDERBlock.Bytes)
+	return
+}
@@ -7,40 +7,341 @@
 package http
 
 import (
-	"bufio"
+	"container/vector"
+	"crypto/tls"
 	"encoding/base64"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net"
+	"once"
 	"os"
 	"strings"
+	"sync"
+	"time"
 )
 
 // Given a string of the form "host", "host:port", or "[ipv6::address]:port",
 // return true if the string includes a port.
 func hasPort(s string) bool { return strings.LastIndex(s, ":") > strings.LastIndex(s, "]") }
 
-// Used in Send to implement io.ReadCloser by bundling together the
-// io.BufReader through which we read the response, and the underlying
-// network connection.
-type readClose struct {
-	io.Reader
-	io.Closer
+// Given a string of the form "host:port" or "[ipv6::address]:port",
+// return the host without the port or brackets.
+func hostName(s string) string {
+	if hasPort(s) {
+		s = s[0:strings.LastIndex(s, ":")]
+	}
+	if len(s) > 1 && s[0] == '[' && s[len(s)-1] == ']' {
+		s = s[1 : len(s)-1]
+	}
+	return s
+}
+
+// RootCAs is the set of certificate authorities against which the
+// certificates of https servers are verified.  If RootCAs is nil,
+// the set is loaded from the system's certificate file on first use;
+// if no such file is found, https requests fail until RootCAs is set.
+var RootCAs *tls.CASet
+
+// Locations of the system-wide set of root certificates, in PEM format.
+var rootCAFiles = []string{
+	"/etc/ssl/certs/ca-certificates.crt",
+	"/etc/ssl/cert.pem",
+	"/etc/pki/tls/certs/ca-bundle.crt",
+}
+
+var errNoRootCAs = os.ErrorString("http: no root certificates found in " +
+	strings.Join(rootCAFiles, ", ") + "; set http.RootCAs")
+
+func loadRootCAs() {
+	if RootCAs != nil {
+		return
+	}
+	cas := tls.NewCASet()
+	for _, file := range rootCAFiles {
+		data, err := ioutil.ReadFile(file)
+		if err == nil && cas.SetFromPEM(data) {
+			RootCAs = cas
+			return
+		}
+	}
+}
+
+var urandom *os.File // source of randomness for TLS, opened by openURandom
+var urandomErr os.Error
+
+func openURandom() { urandom, urandomErr = os.Open("/dev/urandom", os.O_RDONLY, 0) }
+
+// tlsConfig returns a configuration suitable for TLS connections
+// made or accepted by this package.  All such connections share
+// one handle on /dev/urandom.
+func tlsConfig() (*tls.Config, os.Error) {
+	once.Do(openURandom)
+	if urandomErr != nil {
+		return nil, urandomErr
+	}
+	return &tls.Config{Rand: urandom, Time: time.Seconds}, nil
 }
 
-// Send issues an HTTP request.  Caller should close resp.Body when done reading it.
+// A tlsConn is a TLS connection layered over a network connection.
+// Closing it closes both.
+type tlsConn struct {
+	*tls.Conn
+	raw net.Conn
+}
+
+func (c *tlsConn) Close() os.Error {
+	c.Conn.Close()
+	return c.raw.Close()
+}
+
+// dialTLS connects to addr using TLS and verifies that the server
+// presents a certificate chain, rooted in RootCAs, that is valid for host.
+func dialTLS(addr, host string) (net.Conn, os.Error) {
+	raw, err := net.Dial("tcp", "", addr)
+	if err != nil {
+		return nil, err
+	}
+	return clientTLS(raw, addr, host)
+}
+
+// clientTLS runs a TLS client over the connection raw to addr,
+// verifying the server's certificate as dialTLS does.  On
+// failure, raw is closed.
+func clientTLS(raw net.Conn, addr, host string) (net.Conn, os.Error) {
+	config, err := tlsConfig()
+	if err != nil {
+		raw.Close()
+		return nil, err
+	}
+	once.Do(loadRootCAs)
+	if RootCAs == nil {
+		raw.Close()
+		return nil, errNoRootCAs
+	}
+	config.RootCAs = RootCAs
+
+	conn := &tlsConn{tls.Client(raw, config), raw}
+	state := conn.WaitConnectionState()
+	if !state.HandshakeComplete {
+		conn.Close()
+		return nil, os.ErrorString("tls handshake with " + addr + " failed")
+	}
+	if len(state.PeerCertificates) == 0 || !state.PeerCertificates[0].IsValidForHost(host) {
+		conn.Close()
+		return nil, &badStringError{"certificate is not valid for host", host}
+	}
+	return conn, nil
+}
+
+// A Client is an HTTP client.  Its zero value is a usable
+// client that keeps at most DefaultMaxIdleConnsPerHost idle connections
+// per host and follows up to 10 redirects.
 //
-// TODO: support persistent connections (multiple requests on a single connection).
-// send() method is nonpublic because, when we refactor the code for persistent
-// connections, it may no longer make sense to have a method with this signature.
-func send(req *Request) (resp *Response, err os.Error) {
-	if req.URL.Scheme != "http" {
+// A Client keeps persistent (keep-alive) connections to the hosts it
+// has talked to and reuses them for later requests.  It is safe for
+// concurrent use by multiple goroutines.
+type Client struct {
+	// MaxIdleConnsPerHost, if non-zero, controls the maximum number
+	// of idle connections to keep per host.  If zero,
+	// DefaultMaxIdleConnsPerHost is used.  If negative, connections
+	// are closed after each request.
+	MaxIdleConnsPerHost int
+
+	// CheckRedirect specifies the policy for following redirects.
+	// Before following a redirect to req, Get calls CheckRedirect with
+	// the requests made so far, oldest first.  If it returns an error,
+	// Get stops and returns that error.  If CheckRedirect is nil, Get
+	// stops after 10 redirects.
+	CheckRedirect func(req *Request, via []*Request) os.Error
+
+	// Timeout, if non-zero, is the time limit in nanoseconds for each
+	// read and write on the connection while a request is sent and
+	// its response is read.
+	Timeout int64
+
+	// Jar, if non-nil, supplies the cookies sent with each request
+	// and is updated with the cookies set by each response, including
+	// those received while following redirects.
+	Jar CookieJar
+
+	// DisableCompression, if true, prevents the client from asking
+	// for a gzip-compressed response with "Accept-Encoding: gzip".
+	// Otherwise a gzip-encoded response body is decompressed
+	// transparently, and its Content-Encoding header removed.
+	// Requests that set their own Accept-Encoding header get
+	// the response as sent.
+	DisableCompression bool
+
+	// Proxy, if non-nil, returns the proxy to use for a request, or
+	// nil for a direct connection.  Plain http requests are sent to
+	// the proxy with absolute URLs; https requests are tunnelled
+	// through it with CONNECT.  See ProxyFromEnvironment.
+	Proxy func(req *Request) (*URL, os.Error)
+
+	lk   sync.Mutex
+	idle map[string]*vector.Vector // idle *persistConns by host key
+}
+
+// DefaultMaxIdleConnsPerHost is the default value of
+// Client.MaxIdleConnsPerHost.
+const DefaultMaxIdleConnsPerHost = 2
+
+// DefaultClient is the Client used by Get and Post.  It uses the
+// proxy, if any, named by the environment (see ProxyFromEnvironment).
+var DefaultClient = &Client{Proxy: ProxyFromEnvironment}
+
+// A persistConn is a connection to a host that may be kept
+// for reuse after the response to a request has been read.
+type persistConn struct {
+	key  string // scheme and address of the host
+	conn net.Conn
+	cc   *ClientConn
+}
+
+func (pc *persistConn) close() {
+	pc.cc.Close()
+	pc.conn.Close()
+}
+
+// Dial a new connection for the given scheme and address.
+func (c *Client) dial(key, scheme, addr string, proxy *URL) (pc *persistConn, err os.Error) {
+	var conn net.Conn
+	switch {
+	case proxy != nil:
+		if conn, err = dialProxy(proxy, scheme, addr); err != nil {
+			return nil, err
+		}
+		if scheme == "http" {
+			return &persistConn{key, conn, NewProxyClientConn(conn, nil)}, nil
+		}
+	case scheme == "https":
+		conn, err = dialTLS(addr, hostName(addr))
+	default:
+		conn, err = net.Dial("tcp", "", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &persistConn{key, conn, NewClientConn(conn, nil)}, nil
+}
+
+// Return an idle connection for key, or nil if there is none.
+func (c *Client) getIdle(key string) *persistConn {
+	c.lk.Lock()
+	defer c.lk.Unlock()
+	if v, ok := c.idle[key]; ok && v.Len() > 0 {
+		return v.Pop().(*persistConn)
+	}
+	return nil
+}
+
+// Put pc in the idle pool, or close it if the pool is full.
+func (c *Client) putIdle(pc *persistConn) {
+	max := c.MaxIdleConnsPerHost
+	if max == 0 {
+		max = DefaultMaxIdleConnsPerHost
+	}
+	c.lk.Lock()
+	defer c.lk.Unlock()
+	if c.idle == nil {
+		c.idle = make(map[string]*vector.Vector)
+	}
+	v, ok := c.idle[pc.key]
+	if !ok {
+		v = new(vector.Vector)
+		c.idle[pc.key] = v
+	}
+	if v.Len() >= max {
+		pc.close()
+		return
+	}
+	pc.conn.SetTimeout(0)
+	v.Push(pc)
+}
+
+// CloseIdleConnections closes all connections that are kept
+// for reuse but are not currently in use.
+func (c *Client) CloseIdleConnections() {
+	c.lk.Lock()
+	defer c.lk.Unlock()
+	for _, v := range c.idle {
+		for v.Len() > 0 {
+			v.Pop().(*persistConn).close()
+		}
+	}
+	c.idle = nil
+}
+
+// A connBody is the body of a response read from a persistConn.
+// Closing it returns the connection to the client's idle pool,
+// if it can be reused, or closes it.
+type connBody struct {
+	io.ReadCloser
+	client *Client
+	pc     *persistConn
+	reuse  bool
+}
+
+func (b *connBody) Close() os.Error {
+	if b.pc == nil {
+		return nil
+	}
+	err := b.ReadCloser.Close()
+	if err == nil && b.reuse {
+		b.client.putIdle(b.pc)
+	} else {
+		b.pc.close()
+	}
+	b.pc = nil
+	return err
+}
+
+// Write req to pc and read the response.
+func (c *Client) roundTrip(pc *persistConn, req *Request) (resp *Response, err os.Error) {
+	if c.Timeout > 0 {
+		pc.conn.SetTimeout(c.Timeout)
+	}
+	if err = pc.cc.Write(req); err != nil {
+		return nil, err
+	}
+	resp, err = pc.cc.Read()
+	reuse := err == nil && !req.Close && c.MaxIdleConnsPerHost >= 0
+	if err != nil && err != ErrPersistEOF {
+		return nil, err
+	}
+	resp.Body = &connBody{resp.Body, c, pc, reuse}
+	return resp, nil
+}
+
+// Add the cookies from a jar to req, unless req already
+// carries a cookie of the same name.
+func addJarCookies(req *Request, cookies []*Cookie) {
+	for _, c := range cookies {
+		present := false
+		for _, rc := range req.Cookie {
+			if rc.Name == c.Name {
+				present = true
+				break
+			}
+		}
+		if !present {
+			req.Cookie = appendCookie(req.Cookie, c)
+		}
+	}
+}
+
+// Do sends an HTTP request and returns the HTTP response.  Unlike Get,
+// Do does not follow redirects.  The connection used for the request is
+// kept for reuse once the caller has closed resp.Body.
+func (c *Client) Do(req *Request) (resp *Response, err os.Error) {
+	if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
 		return nil, &badStringError{"unsupported protocol scheme", req.URL.Scheme}
 	}
 
 	addr := req.URL.Host
 	if !hasPort(addr) {
-		addr += ":http"
+		addr += ":" + req.URL.Scheme
 	}
 	info := req.URL.Userinfo
 	if len(info) > 0 {
@@ -52,29 +353,88 @@ func send(req *Request) (resp *Response, err os.Error) {
 		}
 		req.Header["Authorization"] = "Basic " + string(encoded)
 	}
-	conn, err := net.Dial("tcp", "", addr)
-	if err != nil {
-		return nil, err
+
+	var proxy *URL
+	if c.Proxy != nil {
+		if proxy, err = c.Proxy(req); err != nil {
+			return nil, err
+		}
+	}
+	if req.Header == nil {
+		req.Header = make(map[string]string)
 	}
 
-	err = req.Write(conn)
-	if err != nil {
-		conn.Close()
-		return nil, err
+	key := req.URL.Scheme + "://" + addr
+	sentProxyAuth := false
+	if proxy != nil {
+		// Plain http requests to any host can share the connections
+		// to a proxy; tunnels lead to a single host.
+		if req.URL.Scheme == "http" {
+			key = ""
+			if auth := proxyAuth(proxy); auth != "" {
+				req.Header["Proxy-Authorization"] = auth
+				sentProxyAuth = true
+			}
+		}
+		key = "proxy " + proxy.String() + " " + key
 	}
 
-	reader := bufio.NewReader(conn)
-	resp, err = ReadResponse(reader, req.Method)
-	if err != nil {
-		conn.Close()
-		return nil, err
+	requestedGzip := false
+	if !c.DisableCompression && req.Method != "HEAD" {
+		if _, ok := req.Header["Accept-Encoding"]; !ok {
+			req.Header["Accept-Encoding"] = "gzip"
+			requestedGzip = true
+		}
 	}
 
-	resp.Body = readClose{resp.Body, conn}
+	// The jar's cookies go out with this request only; addJarCookies
+	// builds a new slice, so restoring req.Cookie undoes them.
+	origCookie := req.Cookie
+	if c.Jar != nil {
+		addJarCookies(req, c.Jar.Cookies(req.URL))
+	}
 
+	resp, err = c.send(req, key, addr, proxy)
+	req.Cookie = origCookie
+	if sentProxyAuth {
+		req.Header["Proxy-Authorization"] = "", false
+	}
+	if requestedGzip {
+		req.Header["Accept-Encoding"] = "", false
+		if err == nil {
+			decompressBody(resp)
+		}
+	}
 	return
 }
 
+// Send req to the server at addr, identified in the idle pool by key.
+func (c *Client) send(req *Request, key, addr string, proxy *URL) (resp *Response, err os.Error) {
+	for {
+		pc := c.getIdle(key)
+		reused := pc != nil
+		if !reused {
+			if pc, err = c.dial(key, req.URL.Scheme, addr, proxy); err != nil {
+				return nil, err
+			}
+		}
+		if resp, err = c.roundTrip(pc, req); err == nil {
+			if c.Jar != nil && len(resp.SetCookie) > 0 {
+				c.Jar.SetCookies(req.URL, resp.SetCookie)
+			}
+			return resp, nil
+		}
+		pc.close()
+		// An idle connection may have been closed by the server
+		// in the meantime.  Retry on a fresh connection if it is
+		// safe to send the request again.
+		if !reused || req.Body != nil {
+			return nil, err
+		}
+	}
+	panic("not reached")
+}
+
 // True if the specified HTTP status code is one for which the Get utility should
 // automatically redirect.
 func shouldRedirect(statusCode int) bool {
@@ -86,7 +446,8 @@ func shouldRedirect(statusCode int) bool {
 }
 
 // Get issues a GET to the specified URL.  If the response is one of the following
-// redirect codes, it follows the redirect, up to a maximum of 10 redirects:
+// redirect codes, it follows the redirect, subject to the client's
+// CheckRedirect policy:
 //
 //    301 (Moved Permanently)
 //    302 (Found)
@@ -97,21 +458,24 @@ func shouldRedirect(statusCode int) bool {
 // input URL unless redirects were followed.
 //
 // Caller should close r.Body when done reading it.
-func Get(url string) (r *Response, finalURL string, err os.Error) {
-	// TODO: if/when we add cookie support, the redirected request shouldn't
-	// necessarily supply the same cookies as the original.
+func (c *Client) Get(url string) (r *Response, finalURL string, err os.Error) {
 	// TODO: set referrer header on redirects.
-	for redirect := 0; ; redirect++ {
-		if redirect >= 10 {
-			err = os.ErrorString("stopped after 10 redirects")
-			break
-		}
-
-		var req Request
+	var via []*Request
+	for {
+		req := new(Request)
 		if req.URL, err = ParseURL(url); err != nil {
 			break
 		}
-		if r, err = send(&req); err != nil {
+		if len(via) > 0 {
+			checkRedirect := c.CheckRedirect
+			if checkRedirect == nil {
+				checkRedirect = defaultCheckRedirect
+			}
+			if err = checkRedirect(req, via); err != nil {
+				break
+			}
+		}
+		if r, err = c.Do(req); err != nil {
 			break
 		}
 		if shouldRedirect(r.StatusCode) {
@@ -120,6 +484,10 @@ func Get(url string) (r *Response, finalURL string, err os.Error) {
 				err = os.ErrorString(fmt.Sprintf("%d response missing Location header", r.StatusCode))
 				break
 			}
+			v := make([]*Request, len(via)+1)
+			copy(v, via)
+			v[len(via)] = req
+			via = v
 			continue
 		}
 		finalURL = url
@@ -130,16 +498,29 @@ func Get(url string) (r *Response, finalURL string, err os.Error) {
 	return
 }
 
+func defaultCheckRedirect(req *Request, via []*Request) os.Error {
+	if len(via) >= 10 {
+		return os.ErrorString("stopped after 10 redirects")
+	}
+	return nil
+}
+
+// Get issues a GET to the specified URL using DefaultClient.
+// See Client.Get for details.
+//
+// Caller should close r.Body when done reading it.
+func Get(url string) (r *Response, finalURL string, err os.Error) {
+	return DefaultClient.Get(url)
+}
 
 // Post issues a POST to the specified URL.
 //
 // Caller should close r.Body when done reading it.
-func Post(url string, bodyType string, body io.Reader) (r *Response, err os.Error) {
+func (c *Client) Post(url string, bodyType string, body io.Reader) (r *Response, err os.Error) {
 	var req Request
 	req.Method = "POST"
 	req.ProtoMajor = 1
 	req.ProtoMinor = 1
-	req.Close = true
 	req.Body = nopCloser{body}
 	req.Header = map[string]string{
 		"Content-Type": bodyType,
@@ -151,7 +532,14 @@ func Post(url string, bodyType string, body io.Reader) (r *Response, err os.Erro
 		return nil, err
 	}
 
-	return send(&req)
+	return c.Do(&req)
+}
+
+// Post issues a POST to the specified URL using DefaultClient.
+//
+// Caller should close r.Body when done reading it.
+func Post(url string, bodyType string, body io.Reader) (r *Response, err os.Error) {
+	return DefaultClient.Post(url, bodyType, body)
 }
 
 type nopCloser struct {
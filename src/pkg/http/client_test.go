@@ -7,7 +7,12 @@
 package http
 
 import (
+	"bufio"
+	"crypto/tls"
+	"io"
 	"io/ioutil"
+	"net"
+	"os"
 	"strings"
 	"testing"
 )
@@ -28,3 +33,167 @@ func TestClient(t *testing.T) {
 		t.Errorf("Incorrect page body (did not begin with User-agent): %q", s)
 	}
 }
+
+type hostNameTest struct {
+	in, out string
+}
+
+var hostNameTests = []hostNameTest{
+	hostNameTest{"www.google.com", "www.google.com"},
+	hostNameTest{"www.google.com:443", "www.google.com"},
+	hostNameTest{"[::1]:443", "::1"},
+	hostNameTest{"[::1]", "::1"},
+}
+
+func TestHostName(t *testing.T) {
+	for _, tt := range hostNameTests {
+		if out := hostName(tt.in); out != tt.out {
+			t.Errorf("hostName(%q) = %q, want %q", tt.in, out, tt.out)
+		}
+	}
+}
+
+// A countingListener counts the connections it has accepted.
+type countingListener struct {
+	net.Listener
+	n int
+}
+
+func (l *countingListener) Accept() (c net.Conn, err os.Error) {
+	c, err = l.Listener.Accept()
+	if err == nil {
+		l.n++
+	}
+	return
+}
+
+func helloServer(c *Conn, req *Request) {
+	if req.URL.Path == "/redirect" {
+		Redirect(c, "http://"+req.Host+"/redirect", StatusFound)
+		return
+	}
+	if req.URL.Path == "/cookie" && len(req.Cookie) == 0 {
+		c.SetCookie(&Cookie{Name: "session", Value: "1", Path: "/"})
+	}
+	io.WriteString(c, "hello")
+}
+
+func startCountingServer(t *testing.T) (l *countingListener, url string) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen: %v", err)
+	}
+	l = &countingListener{Listener: ln}
+	go Serve(l, HandlerFunc(helloServer))
+	return l, "http://" + ln.Addr().String()
+}
+
+func TestClientKeepAlive(t *testing.T) {
+	l, url := startCountingServer(t)
+	defer l.Close()
+
+	client := new(Client)
+	for i := 0; i < 3; i++ {
+		r, _, err := client.Get(url + "/")
+		if err != nil {
+			t.Fatalf("Get #%d: %v", i, err)
+		}
+		b, err := ioutil.ReadAll(r.Body)
+		r.Body.Close()
+		if err != nil || string(b) != "hello" {
+			t.Errorf("Get #%d: body = %q, %v; want %q", i, b, err, "hello")
+		}
+	}
+	if l.n != 1 {
+		t.Errorf("server accepted %d connections, want 1", l.n)
+	}
+	client.CloseIdleConnections()
+}
+
+func TestClientRedirectPolicy(t *testing.T) {
+	l, url := startCountingServer(t)
+	defer l.Close()
+
+	n := 0
+	errStop := os.NewError("too many redirects")
+	client := &Client{CheckRedirect: func(req *Request, via []*Request) os.Error {
+		if len(via) != n+1 {
+			t.Errorf("CheckRedirect: len(via) = %d, want %d", len(via), n+1)
+		}
+		if n++; n >= 3 {
+			return errStop
+		}
+		return nil
+	}}
+	_, _, err := client.Get(url + "/redirect")
+	if e, ok := err.(*URLError); !ok || e.Error != errStop {
+		t.Errorf("Get: err = %v, want %v", err, errStop)
+	}
+	if n != 3 {
+		t.Errorf("CheckRedirect called %d times, want 3", n)
+	}
+	client.CloseIdleConnections()
+}
+
+// startTLSServer serves helloServer over TLS using the certificate in
+// testdata, which is self-signed for the host name "test".
+func startTLSServer(t *testing.T) net.Listener {
+	config, err := tlsConfig()
+	if err != nil {
+		t.Fatalf("tlsConfig: %v", err)
+	}
+	config.Certificates = make([]tls.Certificate, 1)
+	config.Certificates[0], err = tls.LoadX509KeyPair("testdata/cert.pem", "testdata/key.pem")
+	if err != nil {
+		t.Fatalf("LoadX509KeyPair: %v", err)
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen: %v", err)
+	}
+	go Serve(&tlsListener{l, config}, HandlerFunc(helloServer))
+	return l
+}
+
+func TestDialTLS(t *testing.T) {
+	pemCert, err := ioutil.ReadFile("testdata/cert.pem")
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := RootCAs
+	defer func() { RootCAs = saved }()
+
+	l := startTLSServer(t)
+	defer l.Close()
+	addr := l.Addr().String()
+
+	RootCAs = tls.NewCASet()
+	RootCAs.SetFromPEM(pemCert)
+	for i := 0; i < 3; i++ {
+		c, err := dialTLS(addr, "test")
+		if err != nil {
+			t.Fatalf("dialTLS #%d: %v", i, err)
+		}
+		io.WriteString(c, "GET / HTTP/1.0\r\nHost: test\r\n\r\n")
+		r, err := ReadResponse(bufio.NewReader(c), "GET")
+		var b []byte
+		if err == nil {
+			b, err = ioutil.ReadAll(r.Body)
+		}
+		c.Close()
+		if err != nil || string(b) != "hello" {
+			t.Errorf("dialTLS #%d: body = %q, %v; want %q", i, b, err, "hello")
+		}
+	}
+
+	if c, err := dialTLS(addr, "example.com"); err == nil {
+		c.Close()
+		t.Error("dialTLS succeeded for a host the certificate does not name")
+	}
+
+	RootCAs = tls.NewCASet()
+	if c, err := dialTLS(addr, "test"); err == nil {
+		c.Close()
+		t.Error("dialTLS succeeded with a certificate not rooted in RootCAs")
+	}
+}
@@ -0,0 +1,208 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Content-Encoding support: compression of replies in the server
+// and decompression of responses in the client.
+
+package http
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// DefaultCompressMinSize is the size below which a Compressor
+// sends replies uncompressed, unless overridden by Compressor.MinSize.
+// Compressing shorter bodies rarely saves enough to be worthwhile.
+const DefaultCompressMinSize = 1024
+
+// DefaultCompressTypes lists the Content-Type prefixes of the replies
+// a Compressor compresses, unless overridden by Compressor.Types.
+var DefaultCompressTypes = []string{
+	"text/",
+	"application/javascript",
+	"application/json",
+	"application/x-javascript",
+	"application/xml",
+	"image/svg+xml",
+}
+
+// A Compressor is a Handler that compresses the replies of another
+// Handler with gzip or deflate, if the client accepts one of those
+// encodings in its Accept-Encoding header.  Only replies with a
+// compressible Content-Type and at least MinSize bytes of body are
+// compressed, and replies that already set a Content-Encoding are
+// left alone.
+//
+// Compressed replies cannot be flushed: the compressor holds back
+// data until the handler returns.  A handler that streams its reply
+// should call Flush before writing MinSize bytes, which sends the
+// reply uncompressed.
+type Compressor struct {
+	Handler Handler  // handler whose replies are compressed
+	MinSize int      // DefaultCompressMinSize if zero
+	Types   []string // Content-Type prefixes; DefaultCompressTypes if nil
+}
+
+// CompressHandler returns a Compressor for h with the default
+// size threshold and types.
+func CompressHandler(h Handler) Handler { return &Compressor{Handler: h} }
+
+func (z *Compressor) ServeHTTP(c *Conn, req *Request) {
+	enc := acceptedEncoding(req.Header["Accept-Encoding"])
+	if enc != "" && req.Method != "HEAD" && req.ProtoAtLeast(1, 0) {
+		s := &compressState{encoding: enc, minSize: z.MinSize, types: z.Types}
+		if s.minSize <= 0 {
+			s.minSize = DefaultCompressMinSize
+		}
+		if s.types == nil {
+			s.types = DefaultCompressTypes
+		}
+		c.compress = s
+	}
+	z.Handler.ServeHTTP(c, req)
+}
+
+// acceptedEncoding returns the content coding, "gzip" or "deflate",
+// that the Accept-Encoding header value h prefers, or "" if it
+// accepts neither.  Gzip wins ties.
+func acceptedEncoding(h string) string {
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(h, ",", 0) {
+		params := strings.Split(part, ";", 0)
+		name := strings.ToLower(strings.TrimSpace(params[0]))
+		if name != "gzip" && name != "deflate" {
+			continue
+		}
+		q := 1.0
+		for _, p := range params[1:] {
+			kv := strings.Split(strings.TrimSpace(p), "=", 2)
+			if len(kv) == 2 && strings.ToLower(kv[0]) == "q" {
+				if v, err := strconv.Atof64(strings.TrimSpace(kv[1])); err == nil {
+					q = v
+				}
+			}
+		}
+		if q > bestQ || q == bestQ && q > 0 && name == "gzip" {
+			best, bestQ = name, q
+		}
+	}
+	return best
+}
+
+// A compressState holds the start of a reply body while a Conn
+// decides whether to compress it.
+type compressState struct {
+	encoding string
+	minSize  int
+	types    []string
+	buf      bytes.Buffer
+}
+
+// wants reports whether a reply with the given status code and
+// the header in c should be compressed, if it is large enough.
+func (s *compressState) wants(c *Conn, code int) bool {
+	if code < 200 || code == StatusNoContent || code == StatusNotModified {
+		return false
+	}
+	if _, ok := c.header["Content-Encoding"]; ok {
+		return false
+	}
+	ct := strings.ToLower(c.header["Content-Type"])
+	for _, t := range s.types {
+		if strings.HasPrefix(ct, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// write buffers data until there is enough to decide
+// to compress the reply.
+func (s *compressState) write(c *Conn, data []byte) (n int, err os.Error) {
+	s.buf.Write(data)
+	if s.buf.Len() >= s.minSize {
+		err = c.startBody(true)
+	}
+	return len(data), err
+}
+
+// startBody ends the wait for enough reply body to decide whether
+// to compress it: it writes the reply header, saying whether the
+// body is compressed, followed by the body buffered so far.
+func (c *Conn) startBody(compress bool) os.Error {
+	s := c.compress
+	c.compress = nil
+	if compress {
+		c.header["Content-Encoding"] = s.encoding
+		c.header["Content-Length"] = "", false
+		if v, ok := c.header["Vary"]; ok {
+			c.header["Vary"] = v + ", Accept-Encoding"
+		} else {
+			c.header["Vary"] = "Accept-Encoding"
+		}
+	}
+	c.writeHeaderLines()
+	if !compress {
+		_, err := c.writeChunk(s.buf.Bytes())
+		return err
+	}
+
+	var err os.Error
+	w := chunkWriter{c}
+	switch s.encoding {
+	case "gzip":
+		c.zw, err = gzip.NewDeflater(w)
+	case "deflate":
+		c.zw, err = zlib.NewDeflater(w)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = c.zw.Write(s.buf.Bytes())
+	return err
+}
+
+// A chunkWriter writes the reply body of a Conn, as Conn.Write
+// would, after it has been compressed.
+type chunkWriter struct {
+	c *Conn
+}
+
+func (w chunkWriter) Write(data []byte) (n int, err os.Error) { return w.c.writeChunk(data) }
+
+// A gzipBody decompresses a gzip-encoded response body.
+// The gzip header is read on the first call to Read.
+type gzipBody struct {
+	body io.ReadCloser
+	zr   *gzip.Inflater
+}
+
+func (b *gzipBody) Read(p []byte) (n int, err os.Error) {
+	if b.zr == nil {
+		if b.zr, err = gzip.NewInflater(b.body); err != nil {
+			return 0, err
+		}
+	}
+	return b.zr.Read(p)
+}
+
+func (b *gzipBody) Close() os.Error { return b.body.Close() }
+
+// decompressBody arranges for resp.Body to return the decoded
+// body of a gzip-encoded response.
+func decompressBody(resp *Response) {
+	if resp.Header["Content-Encoding"] != "gzip" {
+		return
+	}
+	resp.Header["Content-Encoding"] = "", false
+	resp.Header["Content-Length"] = "", false
+	resp.ContentLength = -1
+	resp.Body = &gzipBody{body: resp.Body}
+}
@@ -0,0 +1,109 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Tests for compress.go
+
+package http
+
+import (
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"net"
+	"strings"
+	"testing"
+)
+
+type acceptedEncodingTest struct {
+	in, out string
+}
+
+var acceptedEncodingTests = []acceptedEncodingTest{
+	acceptedEncodingTest{"", ""},
+	acceptedEncodingTest{"gzip", "gzip"},
+	acceptedEncodingTest{"deflate, gzip", "gzip"},
+	acceptedEncodingTest{"Deflate", "deflate"},
+	acceptedEncodingTest{"gzip;q=0.5, deflate", "deflate"},
+	acceptedEncodingTest{"gzip; q=0, identity", ""},
+	acceptedEncodingTest{"compress, br", ""},
+}
+
+func TestAcceptedEncoding(t *testing.T) {
+	for _, tt := range acceptedEncodingTests {
+		if out := acceptedEncoding(tt.in); out != tt.out {
+			t.Errorf("acceptedEncoding(%q) = %q, want %q", tt.in, out, tt.out)
+		}
+	}
+}
+
+var longText = strings.Repeat("All work and no play makes Jack a dull boy.\n", 100)
+
+func compressServer(c *Conn, req *Request) {
+	switch req.URL.Path {
+	case "/short":
+		io.WriteString(c, "short")
+	case "/binary":
+		c.SetHeader("Content-Type", "image/png")
+		io.WriteString(c, longText)
+	default:
+		io.WriteString(c, longText)
+	}
+}
+
+func TestCompressHandler(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen: %v", err)
+	}
+	defer ln.Close()
+	go Serve(ln, CompressHandler(HandlerFunc(compressServer)))
+	url := "http://" + ln.Addr().String()
+
+	// The client asks for gzip and decodes it transparently.
+	client := new(Client)
+	for _, path := range []string{"/", "/short", "/binary"} {
+		r, _, err := client.Get(url + path)
+		if err != nil {
+			t.Fatalf("Get %s: %v", path, err)
+		}
+		b, err := ioutil.ReadAll(r.Body)
+		r.Body.Close()
+		want := longText
+		if path == "/short" {
+			want = "short"
+		}
+		if err != nil || string(b) != want {
+			t.Errorf("Get %s: body has %d bytes, %v; want %d bytes", path, len(b), err, len(want))
+		}
+		if enc, ok := r.Header["Content-Encoding"]; ok {
+			t.Errorf("Get %s: Content-Encoding %q was not removed", path, enc)
+		}
+	}
+	client.CloseIdleConnections()
+
+	// Only long, compressible replies are compressed.
+	client = &Client{DisableCompression: true}
+	for _, path := range []string{"/", "/short", "/binary"} {
+		req := &Request{Method: "GET", Header: map[string]string{"Accept-Encoding": "gzip"}}
+		req.URL, _ = ParseURL(url + path)
+		r, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("Do %s: %v", path, err)
+		}
+		enc := r.Header["Content-Encoding"]
+		if path == "/" {
+			if enc != "gzip" {
+				t.Errorf("Do %s: Content-Encoding = %q, want gzip", path, enc)
+			} else if zr, err := gzip.NewInflater(r.Body); err != nil {
+				t.Errorf("Do %s: gzip.NewInflater: %v", path, err)
+			} else if b, err := ioutil.ReadAll(zr); err != nil || string(b) != longText {
+				t.Errorf("Do %s: decompressed %d bytes, %v; want %d bytes", path, len(b), err, len(longText))
+			}
+		} else if enc != "" {
+			t.Errorf("Do %s: Content-Encoding = %q, want none", path, enc)
+		}
+		r.Body.Close()
+	}
+	client.CloseIdleConnections()
+}
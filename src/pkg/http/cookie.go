@@ -0,0 +1,266 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package http
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// This implementation is done according to RFC 2109 and RFC 2965,
+// accepting the looser syntax of the original Netscape specification
+// that most servers still send.
+
+// A Cookie represents an HTTP cookie as sent in the Set-Cookie header of an
+// HTTP response or the Cookie header of an HTTP request.
+type Cookie struct {
+	Name       string
+	Value      string
+	Path       string
+	Domain     string
+	Comment    string
+	Version    int
+	Expires    time.Time
+	RawExpires string
+
+	// MaxAge=0 means no 'Max-Age' attribute specified.
+	// MaxAge<0 means delete cookie now, equivalently 'Max-Age: 0'.
+	// MaxAge>0 means Max-Age attribute present and given in seconds.
+	MaxAge   int
+	Secure   bool
+	HttpOnly bool
+	Raw      string
+	Unparsed []string // Raw text of unparsed attribute-value pairs
+}
+
+// Layout used when writing the Expires attribute.
+const cookieTimeLayout = "Mon, 02 Jan 2006 15:04:05 GMT"
+
+// Layouts accepted for the Expires attribute, after any dashes
+// in the date have been replaced by spaces: RFC 1123, the Netscape
+// cookie format, RFC 850 and ANSI C asctime.
+var cookieExpiresLayouts = []string{
+	time.RFC1123,
+	"Mon, 02 Jan 06 15:04:05 MST",
+	"Monday, 02 Jan 06 15:04:05 MST",
+	time.ANSIC,
+}
+
+func dashToSpace(c int) int {
+	if c == '-' {
+		return ' '
+	}
+	return c
+}
+
+func parseCookieExpires(s string) (*time.Time, os.Error) {
+	s = strings.Map(dashToSpace, s)
+	var err os.Error
+	for _, layout := range cookieExpiresLayouts {
+		var t *time.Time
+		if t, err = time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return nil, err
+}
+
+// Is s a valid cookie name (an HTTP token)?
+func isCookieName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !isToken(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Remove the quotes around a quoted cookie value.
+func unquoteCookieValue(s string) string {
+	if len(s) > 1 && s[0] == '"' && s[len(s)-1] == '"' {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+// Split a name=value pair, trimming surrounding white space.
+func splitCookiePair(s string) (name, value string) {
+	kv := strings.Split(s, "=", 2)
+	name = strings.TrimSpace(kv[0])
+	if len(kv) > 1 {
+		value = strings.TrimSpace(kv[1])
+	}
+	return
+}
+
+// readSetCookie parses the value of a single Set-Cookie header line.
+// It returns nil if the line does not start with a valid name=value pair.
+func readSetCookie(line string) *Cookie {
+	parts := strings.Split(strings.TrimSpace(line), ";", 0)
+	name, value := splitCookiePair(parts[0])
+	if !isCookieName(name) || strings.Index(parts[0], "=") < 0 {
+		return nil
+	}
+	c := &Cookie{
+		Name:  name,
+		Value: unquoteCookieValue(value),
+		Raw:   line,
+	}
+	var unparsed []string
+	for _, part := range parts[1:] {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		attr, val := splitCookiePair(part)
+		val = unquoteCookieValue(val)
+		switch strings.ToLower(attr) {
+		case "secure":
+			c.Secure = true
+			continue
+		case "httponly":
+			c.HttpOnly = true
+			continue
+		case "domain":
+			c.Domain = val
+			continue
+		case "path":
+			c.Path = val
+			continue
+		case "comment":
+			c.Comment = val
+			continue
+		case "version":
+			if v, err := strconv.Atoi(val); err == nil {
+				c.Version = v
+				continue
+			}
+		case "max-age":
+			secs, err := strconv.Atoi(val)
+			if err != nil || secs < 0 || secs != 0 && val[0] == '0' {
+				break
+			}
+			if secs == 0 {
+				c.MaxAge = -1
+			} else {
+				c.MaxAge = secs
+			}
+			continue
+		case "expires":
+			c.RawExpires = val
+			t, err := parseCookieExpires(val)
+			if err != nil {
+				break
+			}
+			c.Expires = *t
+			continue
+		}
+		unparsed = appendCookieString(unparsed, strings.TrimSpace(part))
+	}
+	c.Unparsed = unparsed
+	return c
+}
+
+// readCookies parses the value of a Cookie request header.
+// Several Cookie headers joined with commas are accepted,
+// and RFC 2965 attributes such as $Version and $Path are skipped.
+func readCookies(line string) []*Cookie {
+	var cookies []*Cookie
+	for _, group := range strings.Split(line, ",", 0) {
+		for _, part := range strings.Split(group, ";", 0) {
+			name, value := splitCookiePair(part)
+			if !isCookieName(name) || name[0] == '$' {
+				continue
+			}
+			cookies = appendCookie(cookies, &Cookie{Name: name, Value: unquoteCookieValue(value)})
+		}
+	}
+	return cookies
+}
+
+func appendCookie(cookies []*Cookie, c *Cookie) []*Cookie {
+	n := make([]*Cookie, len(cookies)+1)
+	copy(n, cookies)
+	n[len(cookies)] = c
+	return n
+}
+
+func appendCookieString(a []string, s string) []string {
+	n := make([]string, len(a)+1)
+	copy(n, a)
+	n[len(a)] = s
+	return n
+}
+
+// String returns the serialization of the cookie for use in a
+// Set-Cookie response header.
+func (c *Cookie) String() string {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%s=%s", c.Name, c.Value)
+	if c.Version > 0 {
+		fmt.Fprintf(&b, "; Version=%d", c.Version)
+	}
+	if c.Path != "" {
+		fmt.Fprintf(&b, "; Path=%s", c.Path)
+	}
+	if c.Domain != "" {
+		fmt.Fprintf(&b, "; Domain=%s", c.Domain)
+	}
+	if c.Expires.Seconds() > 0 {
+		fmt.Fprintf(&b, "; Expires=%s", time.SecondsToUTC(c.Expires.Seconds()).Format(cookieTimeLayout))
+	}
+	if c.MaxAge > 0 {
+		fmt.Fprintf(&b, "; Max-Age=%d", c.MaxAge)
+	} else if c.MaxAge < 0 {
+		b.WriteString("; Max-Age=0")
+	}
+	if c.Comment != "" {
+		fmt.Fprintf(&b, "; Comment=%s", strconv.Quote(c.Comment))
+	}
+	if c.HttpOnly {
+		b.WriteString("; HttpOnly")
+	}
+	if c.Secure {
+		b.WriteString("; Secure")
+	}
+	return b.String()
+}
+
+// writeSetCookies writes the wire representation of the cookies
+// to w, one Set-Cookie header line per cookie.
+func writeSetCookies(w io.Writer, cookies []*Cookie) os.Error {
+	for _, c := range cookies {
+		if _, err := io.WriteString(w, "Set-Cookie: "+c.String()+"\r\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeCookies writes the wire representation of the cookies to w
+// as a single Cookie header line.
+func writeCookies(w io.Writer, cookies []*Cookie) os.Error {
+	if len(cookies) == 0 {
+		return nil
+	}
+	var b bytes.Buffer
+	b.WriteString("Cookie: ")
+	for i, c := range cookies {
+		if i > 0 {
+			b.WriteString("; ")
+		}
+		b.WriteString(c.Name + "=" + c.Value)
+	}
+	b.WriteString("\r\n")
+	_, err := w.Write(b.Bytes())
+	return err
+}
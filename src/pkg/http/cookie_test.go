@@ -0,0 +1,242 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package http
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+type readSetCookieTest struct {
+	Line   string
+	Cookie *Cookie
+}
+
+var readSetCookieTests = []readSetCookieTest{
+	readSetCookieTest{
+		"Cookie-1=v$1",
+		&Cookie{Name: "Cookie-1", Value: "v$1", Raw: "Cookie-1=v$1"},
+	},
+	readSetCookieTest{
+		"NID=99; path=/; domain=.google.com; HttpOnly; secure",
+		&Cookie{
+			Name:     "NID",
+			Value:    "99",
+			Path:     "/",
+			Domain:   ".google.com",
+			HttpOnly: true,
+			Secure:   true,
+			Raw:      "NID=99; path=/; domain=.google.com; HttpOnly; secure",
+		},
+	},
+	readSetCookieTest{
+		`id="abc"; Version=1; Max-Age=3600; Comment=test; foo=bar`,
+		&Cookie{
+			Name:     "id",
+			Value:    "abc",
+			Version:  1,
+			MaxAge:   3600,
+			Comment:  "test",
+			Raw:      `id="abc"; Version=1; Max-Age=3600; Comment=test; foo=bar`,
+			Unparsed: []string{"foo=bar"},
+		},
+	},
+	readSetCookieTest{
+		"gone=; Max-Age=0",
+		&Cookie{Name: "gone", MaxAge: -1, Raw: "gone=; Max-Age=0"},
+	},
+	readSetCookieTest{"=novalue", nil},
+	readSetCookieTest{"noequals", nil},
+}
+
+func TestReadSetCookie(t *testing.T) {
+	for i, tt := range readSetCookieTests {
+		c := readSetCookie(tt.Line)
+		if !reflect.DeepEqual(c, tt.Cookie) {
+			t.Errorf("#%d readSetCookie(%q):\nhave %#v\nwant %#v", i, tt.Line, c, tt.Cookie)
+		}
+	}
+}
+
+func TestReadSetCookieExpires(t *testing.T) {
+	for _, v := range []string{"Wed, 09 Jun 2021 10:18:14 GMT", "Wed, 09-Jun-2021 10:18:14 GMT"} {
+		c := readSetCookie("a=b; expires=" + v)
+		if c == nil {
+			t.Errorf("readSetCookie with expires=%q failed", v)
+			continue
+		}
+		if c.RawExpires != v {
+			t.Errorf("RawExpires = %q, want %q", c.RawExpires, v)
+		}
+		e := c.Expires
+		if e.Year != 2021 || e.Month != 6 || e.Day != 9 || e.Hour != 10 || e.Minute != 18 || e.Second != 14 {
+			t.Errorf("expires=%q: parsed as %v", v, e.String())
+		}
+	}
+}
+
+type readCookiesTest struct {
+	Line    string
+	Cookies []*Cookie
+}
+
+var readCookiesTests = []readCookiesTest{
+	readCookiesTest{
+		"Cookie-1=v$1; c2=v2",
+		[]*Cookie{&Cookie{Name: "Cookie-1", Value: "v$1"}, &Cookie{Name: "c2", Value: "v2"}},
+	},
+	readCookiesTest{
+		`$Version=1; a="1"; $Path=/,b=2`,
+		[]*Cookie{&Cookie{Name: "a", Value: "1"}, &Cookie{Name: "b", Value: "2"}},
+	},
+	readCookiesTest{"", nil},
+}
+
+func TestReadCookies(t *testing.T) {
+	for i, tt := range readCookiesTests {
+		c := readCookies(tt.Line)
+		if !reflect.DeepEqual(c, tt.Cookies) {
+			t.Errorf("#%d readCookies(%q): have %v want %v", i, tt.Line, c, tt.Cookies)
+		}
+	}
+}
+
+type writeSetCookiesTest struct {
+	Cookies []*Cookie
+	Raw     string
+}
+
+var writeSetCookiesTests = []writeSetCookiesTest{
+	writeSetCookiesTest{
+		[]*Cookie{&Cookie{Name: "cookie-1", Value: "v$1"}},
+		"Set-Cookie: cookie-1=v$1\r\n",
+	},
+	writeSetCookiesTest{
+		[]*Cookie{
+			&Cookie{Name: "cookie-2", Value: "two", MaxAge: 3600, Path: "/", HttpOnly: true},
+			&Cookie{Name: "cookie-3", Value: "three", Domain: ".example.com", MaxAge: -1, Secure: true},
+		},
+		"Set-Cookie: cookie-2=two; Path=/; Max-Age=3600; HttpOnly\r\n" +
+			"Set-Cookie: cookie-3=three; Domain=.example.com; Max-Age=0; Secure\r\n",
+	},
+}
+
+func TestWriteSetCookies(t *testing.T) {
+	for i, tt := range writeSetCookiesTests {
+		var b bytes.Buffer
+		writeSetCookies(&b, tt.Cookies)
+		if b.String() != tt.Raw {
+			t.Errorf("#%d: have %q want %q", i, b.String(), tt.Raw)
+		}
+	}
+}
+
+func TestWriteCookies(t *testing.T) {
+	var b bytes.Buffer
+	writeCookies(&b, []*Cookie{&Cookie{Name: "a", Value: "1"}, &Cookie{Name: "b", Value: "2"}})
+	if want := "Cookie: a=1; b=2\r\n"; b.String() != want {
+		t.Errorf("have %q want %q", b.String(), want)
+	}
+}
+
+func mustParseURL(t *testing.T, s string) *URL {
+	u, err := ParseURL(s)
+	if err != nil {
+		t.Fatalf("ParseURL(%q): %v", s, err)
+	}
+	return u
+}
+
+func jarString(jar CookieJar, u *URL) string {
+	var b bytes.Buffer
+	for i, c := range jar.Cookies(u) {
+		if i > 0 {
+			b.WriteString(" ")
+		}
+		fmt.Fprintf(&b, "%s=%s", c.Name, c.Value)
+	}
+	return b.String()
+}
+
+type jarTest struct {
+	URL     string
+	Cookies string
+}
+
+var jarTests = []jarTest{
+	jarTest{"http://www.example.com/", "all=1 host=1"},
+	jarTest{"http://www.example.com/a/b/c", "deep=1 all=1 host=1"},
+	jarTest{"http://other.example.com/a/b", "deep=1 all=1"},
+	jarTest{"https://www.example.com/", "all=1 host=1 secure=1"},
+	jarTest{"http://www.example.org/", ""},
+}
+
+func TestCookieJar(t *testing.T) {
+	jar := NewCookieJar()
+	jar.SetCookies(mustParseURL(t, "http://www.example.com/a/b/index.html"), []*Cookie{
+		&Cookie{Name: "host", Value: "1", Path: "/"},
+		&Cookie{Name: "all", Value: "1", Path: "/", Domain: ".example.com"},
+		&Cookie{Name: "deep", Value: "1", Domain: "example.com"},
+		&Cookie{Name: "secure", Value: "1", Path: "/", Secure: true},
+		&Cookie{Name: "other", Value: "1", Domain: "example.org"},
+		&Cookie{Name: "tld", Value: "1", Domain: "com"},
+	})
+	for _, tt := range jarTests {
+		if s := jarString(jar, mustParseURL(t, tt.URL)); s != tt.Cookies {
+			t.Errorf("Cookies(%q) = %q, want %q", tt.URL, s, tt.Cookies)
+		}
+	}
+
+	// Deleting a cookie.
+	u := mustParseURL(t, "http://www.example.com/")
+	jar.SetCookies(u, []*Cookie{&Cookie{Name: "host", Path: "/", MaxAge: -1}})
+	if s := jarString(jar, u); s != "all=1" {
+		t.Errorf("after delete: Cookies = %q, want %q", s, "all=1")
+	}
+}
+
+func TestClientCookies(t *testing.T) {
+	l, url := startCountingServer(t)
+	defer l.Close()
+
+	client := &Client{Jar: NewCookieJar()}
+	r, _, err := client.Get(url + "/cookie")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	r.Body.Close()
+	if len(r.SetCookie) != 1 || r.SetCookie[0].Name != "session" {
+		t.Fatalf("SetCookie = %v, want session cookie", r.SetCookie)
+	}
+	r, _, err = client.Get(url + "/cookie")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	r.Body.Close()
+	if len(r.SetCookie) != 0 {
+		t.Errorf("second Get: server set %v; cookie was not sent", r.SetCookie)
+	}
+
+	// Sending the same request twice must not copy
+	// the jar's cookies into it.
+	req := &Request{Method: "GET"}
+	req.URL = mustParseURL(t, url+"/cookie")
+	for i := 0; i < 2; i++ {
+		r, err = client.Do(req)
+		if err != nil {
+			t.Fatalf("Do #%d: %v", i, err)
+		}
+		r.Body.Close()
+		if len(r.SetCookie) != 0 {
+			t.Errorf("Do #%d: server set %v; cookie was not sent", i, r.SetCookie)
+		}
+		if len(req.Cookie) != 0 {
+			t.Errorf("Do #%d: req.Cookie = %v, want none", i, req.Cookie)
+		}
+	}
+	client.CloseIdleConnections()
+}
@@ -10,12 +10,20 @@ import (
 	"fmt"
 	"io"
 	"mime"
+	"mime/multipart"
 	"os"
 	"path"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 	"utf8"
 )
 
+// TimeFormat is the format of the times in HTTP headers such as
+// Last-Modified and If-Modified-Since (RFC 1123 dates in GMT).
+const TimeFormat = "Mon, 02 Jan 2006 15:04:05 GMT"
+
 // Heuristic: b is text if it is valid UTF-8 and doesn't
 // contain any unprintable ASCII or Unicode characters.
 func isText(b []byte) bool {
@@ -43,24 +51,210 @@ func isText(b []byte) bool {
 }
 
 func dirList(c *Conn, f *os.File) {
-	fmt.Fprintf(c, "<pre>\n")
+	var names []string
 	for {
 		dirs, err := f.Readdir(100)
 		if err != nil || len(dirs) == 0 {
 			break
 		}
-		for _, d := range dirs {
+		n := make([]string, len(names)+len(dirs))
+		copy(n, names)
+		for i, d := range dirs {
 			name := d.Name
 			if d.IsDirectory() {
 				name += "/"
 			}
-			// TODO htmlescape
-			fmt.Fprintf(c, "<a href=\"%s\">%s</a>\n", name, name)
+			n[len(names)+i] = name
 		}
+		names = n
+	}
+	sort.SortStrings(names)
+
+	fmt.Fprintf(c, "<pre>\n")
+	for _, name := range names {
+		// TODO htmlescape
+		fmt.Fprintf(c, "<a href=\"%s\">%s</a>\n", name, name)
 	}
 	fmt.Fprintf(c, "</pre>\n")
 }
 
+// An httpRange is a byte range of a file: length bytes from start.
+type httpRange struct {
+	start, length int64
+}
+
+// parseRange parses a Range header value such as "bytes=0-499,-500"
+// for a file of the given size.  Ranges that start beyond the end of
+// the file, and all ranges of an empty file, are dropped; if none
+// remain, parseRange returns an error.
+func parseRange(s string, size int64) ([]httpRange, os.Error) {
+	const b = "bytes="
+	if !strings.HasPrefix(s, b) {
+		return nil, os.NewError("invalid range")
+	}
+	var ranges []httpRange
+	for _, ra := range strings.Split(s[len(b):], ",", 0) {
+		ra = strings.TrimSpace(ra)
+		if ra == "" {
+			continue
+		}
+		i := strings.Index(ra, "-")
+		if i < 0 {
+			return nil, os.NewError("invalid range")
+		}
+		start, end := strings.TrimSpace(ra[0:i]), strings.TrimSpace(ra[i+1:])
+		var r httpRange
+		if start == "" {
+			// If no start is specified, end specifies the
+			// range start relative to the end of the file.
+			n, err := strconv.Atoi64(end)
+			if err != nil || n <= 0 {
+				return nil, os.NewError("invalid range")
+			}
+			if size == 0 {
+				// An empty file has no bytes to return; skip it.
+				continue
+			}
+			if n > size {
+				n = size
+			}
+			r.start = size - n
+			r.length = size - r.start
+		} else {
+			n, err := strconv.Atoi64(start)
+			if err != nil || n < 0 {
+				return nil, os.NewError("invalid range")
+			}
+			if n >= size {
+				// Not satisfiable; skip it.
+				continue
+			}
+			r.start = n
+			if end == "" {
+				// If no end is specified, range extends to end of the file.
+				r.length = size - r.start
+			} else {
+				n, err := strconv.Atoi64(end)
+				if err != nil || r.start > n {
+					return nil, os.NewError("invalid range")
+				}
+				if n >= size {
+					n = size - 1
+				}
+				r.length = n - r.start + 1
+			}
+		}
+		n := make([]httpRange, len(ranges)+1)
+		copy(n, ranges)
+		n[len(ranges)] = r
+		ranges = n
+	}
+	if len(ranges) == 0 {
+		return nil, os.NewError("range not satisfiable")
+	}
+	return ranges, nil
+}
+
+func (r httpRange) contentRange(size int64) string {
+	return fmt.Sprintf("bytes %d-%d/%d", r.start, r.start+r.length-1, size)
+}
+
+// fileETag returns an entity tag for the file described by d,
+// which changes whenever the file is replaced or modified.
+func fileETag(d *os.Dir) string {
+	return fmt.Sprintf(`"%x-%x-%x"`, d.Ino, d.Size, d.Mtime_ns)
+}
+
+// etagMatch reports whether the value of an If-None-Match or
+// If-Match header, a list of entity tags, includes etag.
+func etagMatch(list, etag string) bool {
+	for _, t := range strings.Split(list, ",", 0) {
+		t = strings.TrimSpace(t)
+		if t == "*" || t == etag || t == "W/"+etag {
+			return true
+		}
+	}
+	return false
+}
+
+// notModified reports whether the request's conditional headers
+// show that the client's copy of the file is current.
+func notModified(r *Request, etag string, modtime int64) bool {
+	if r.Method != "GET" && r.Method != "HEAD" {
+		return false
+	}
+	if inm, ok := r.Header["If-None-Match"]; ok {
+		// If-None-Match takes precedence over If-Modified-Since.
+		return etagMatch(inm, etag)
+	}
+	if ims, ok := r.Header["If-Modified-Since"]; ok {
+		t, err := time.Parse(TimeFormat, ims)
+		return err == nil && modtime <= t.Seconds()
+	}
+	return false
+}
+
+// Reply with the contents of f, which has the given size, honoring
+// any Range header in the request.
+func serveContent(c *Conn, r *Request, f *os.File, size int64, etag string, modtime string) {
+	var ranges []httpRange
+	rangeHeader, ok := r.Header["Range"]
+	if ok && (r.Method == "GET" || r.Method == "HEAD") {
+		// A Range is ignored if an If-Range validator does not match.
+		if ir, ok := r.Header["If-Range"]; !ok || ir == etag || ir == modtime {
+			var err os.Error
+			if ranges, err = parseRange(rangeHeader, size); err != nil {
+				c.SetHeader("Content-Range", fmt.Sprintf("bytes */%d", size))
+				c.WriteHeader(StatusRequestedRangeNotSatisfiable)
+				return
+			}
+		}
+	}
+
+	switch len(ranges) {
+	case 0:
+		c.SetHeader("Content-Length", strconv.Itoa64(size))
+		c.WriteHeader(StatusOK)
+		if r.Method != "HEAD" {
+			io.Copy(c, f)
+		}
+	case 1:
+		ra := ranges[0]
+		c.SetHeader("Content-Range", ra.contentRange(size))
+		c.SetHeader("Content-Length", strconv.Itoa64(ra.length))
+		c.WriteHeader(StatusPartialContent)
+		if r.Method != "HEAD" {
+			if _, err := f.Seek(ra.start, 0); err == nil {
+				io.Copyn(c, f, ra.length)
+			}
+		}
+	default:
+		// Send each range as a part of a multipart/byteranges body.
+		mw := multipart.NewWriter(c)
+		ctype := c.header["Content-Type"]
+		c.SetHeader("Content-Type", "multipart/byteranges; boundary="+mw.Boundary())
+		c.WriteHeader(StatusPartialContent)
+		if r.Method == "HEAD" {
+			return
+		}
+		for _, ra := range ranges {
+			part, err := mw.CreatePart(map[string]string{
+				"Content-Range": ra.contentRange(size),
+				"Content-Type":  ctype,
+			})
+			if err != nil {
+				return
+			}
+			if _, err = f.Seek(ra.start, 0); err != nil {
+				return
+			}
+			if _, err = io.Copyn(part, f, ra.length); err != nil {
+				return
+			}
+		}
+		mw.Close()
+	}
+}
 
 func serveFileInternal(c *Conn, r *Request, name string, redirect bool) {
 	const indexPage = "/index.html"
@@ -123,6 +317,17 @@ func serveFileInternal(c *Conn, r *Request, name string, redirect bool) {
 		return
 	}
 
+	etag := fileETag(d)
+	modtime := time.SecondsToUTC(int64(d.Mtime_ns / 1e9)).Format(TimeFormat)
+	c.SetHeader("Etag", etag)
+	c.SetHeader("Last-Modified", modtime)
+	c.SetHeader("Accept-Ranges", "bytes")
+	if notModified(r, etag, int64(d.Mtime_ns/1e9)) {
+		c.header["Content-Type"] = "", false
+		c.WriteHeader(StatusNotModified)
+		return
+	}
+
 	// serve file
 	// use extension to find content type.
 	ext := path.Ext(name)
@@ -138,9 +343,12 @@ func serveFileInternal(c *Conn, r *Request, name string, redirect bool) {
 		} else {
 			c.SetHeader("Content-Type", "application/octet-stream") // generic binary
 		}
-		c.Write(b)
+		if _, err := f.Seek(0, 0); err != nil {
+			c.WriteHeader(StatusInternalServerError)
+			return
+		}
 	}
-	io.Copy(c, f)
+	serveContent(c, r, f, int64(d.Size), etag, modtime)
 }
 
 // ServeFile replies to the request with the contents of the named file or directory.
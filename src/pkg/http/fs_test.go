@@ -0,0 +1,133 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Tests for fs.go
+
+package http
+
+import (
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type parseRangeTest struct {
+	r    string
+	size int64
+	want []httpRange
+}
+
+var parseRangeTests = []parseRangeTest{
+	parseRangeTest{"bytes=0-4", 10, []httpRange{httpRange{0, 5}}},
+	parseRangeTest{"bytes=2-", 10, []httpRange{httpRange{2, 8}}},
+	parseRangeTest{"bytes=-3", 10, []httpRange{httpRange{7, 3}}},
+	parseRangeTest{"bytes=-20", 10, []httpRange{httpRange{0, 10}}},
+	parseRangeTest{"bytes=5-100", 10, []httpRange{httpRange{5, 5}}},
+	parseRangeTest{"bytes=0-0, 9-9", 10, []httpRange{httpRange{0, 1}, httpRange{9, 1}}},
+	parseRangeTest{"bytes=0-1, 20-30", 10, []httpRange{httpRange{0, 2}}},
+	parseRangeTest{"bytes=20-30", 10, nil},
+	parseRangeTest{"bytes=5-4", 10, nil},
+	parseRangeTest{"bytes=x-1", 10, nil},
+	parseRangeTest{"items=0-1", 10, nil},
+	parseRangeTest{"bytes=-3", 0, nil},
+	parseRangeTest{"bytes=0-", 0, nil},
+}
+
+func TestParseRange(t *testing.T) {
+	for _, tt := range parseRangeTests {
+		r, err := parseRange(tt.r, tt.size)
+		if tt.want == nil && err == nil {
+			t.Errorf("parseRange(%q, %d) = %v, want error", tt.r, tt.size, r)
+		}
+		if tt.want != nil && !reflect.DeepEqual(r, tt.want) {
+			t.Errorf("parseRange(%q, %d) = %v, %v; want %v", tt.r, tt.size, r, err, tt.want)
+		}
+	}
+}
+
+const testFile = "testdata/file"
+
+func serveTestFile(c *Conn, req *Request) { ServeFile(c, req, testFile) }
+
+// Send a request for the test file with the given method and headers.
+func getTestFile(t *testing.T, url, method string, header map[string]string) (*Response, string) {
+	req := &Request{Method: method, Header: header}
+	req.URL, _ = ParseURL(url)
+	client := &Client{DisableCompression: true}
+	r, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("%s %v: %v", method, header, err)
+	}
+	b, err := ioutil.ReadAll(r.Body)
+	r.Body.Close()
+	client.CloseIdleConnections()
+	if err != nil {
+		t.Fatalf("%s %v: reading body: %v", method, header, err)
+	}
+	return r, string(b)
+}
+
+func TestServeFile(t *testing.T) {
+	data, err := ioutil.ReadFile(testFile)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	file := string(data)
+	ln := listenAndServe(t, HandlerFunc(serveTestFile))
+	defer ln.Close()
+	url := "http://" + ln.Addr().String() + "/file"
+
+	r, body := getTestFile(t, url, "GET", nil)
+	if r.StatusCode != StatusOK || body != file {
+		t.Errorf("GET: %d %q, want %d %q", r.StatusCode, body, StatusOK, file)
+	}
+	etag := r.Header["Etag"]
+	if etag == "" || r.Header["Last-Modified"] == "" {
+		t.Errorf("GET: missing validators in header %v", r.Header)
+	}
+
+	r, body = getTestFile(t, url, "HEAD", nil)
+	if r.StatusCode != StatusOK || body != "" {
+		t.Errorf("HEAD: %d %q, want %d with no body", r.StatusCode, body, StatusOK)
+	}
+
+	r, body = getTestFile(t, url, "GET", map[string]string{"If-None-Match": etag})
+	if r.StatusCode != StatusNotModified || body != "" {
+		t.Errorf("If-None-Match: %d %q, want %d with no body", r.StatusCode, body, StatusNotModified)
+	}
+
+	r, body = getTestFile(t, url, "GET", map[string]string{"Range": "bytes=2-5"})
+	if r.StatusCode != StatusPartialContent || body != file[2:6] {
+		t.Errorf("Range: %d %q, want %d %q", r.StatusCode, body, StatusPartialContent, file[2:6])
+	}
+
+	r, body = getTestFile(t, url, "GET", map[string]string{"Range": "bytes=100-"})
+	if r.StatusCode != StatusRequestedRangeNotSatisfiable {
+		t.Errorf("unsatisfiable Range: status %d, want %d", r.StatusCode, StatusRequestedRangeNotSatisfiable)
+	}
+
+	r, body = getTestFile(t, url, "GET", map[string]string{"Range": "bytes=0-1,-2"})
+	mt, params := mime.ParseMediaType(r.Header["Content-Type"])
+	if r.StatusCode != StatusPartialContent || mt != "multipart/byteranges" {
+		t.Fatalf("multiple ranges: %d %q", r.StatusCode, r.Header["Content-Type"])
+	}
+	mr := multipart.NewReader(strings.NewReader(body), params["boundary"])
+	for _, want := range []string{file[0:2], file[len(file)-2:]} {
+		p, err := mr.NextPart()
+		if err != nil {
+			t.Fatalf("NextPart: %v", err)
+		}
+		b, _ := ioutil.ReadAll(p)
+		if string(b) != want {
+			t.Errorf("range part = %q, want %q", b, want)
+		}
+	}
+	if _, err := mr.NextPart(); err != os.EOF {
+		t.Errorf("NextPart after ranges: %v, want os.EOF", err)
+	}
+}
@@ -0,0 +1,187 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package http
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A CookieJar stores the cookies received in HTTP responses and
+// supplies the cookies to send in later requests.  A Client with a
+// non-nil Jar consults it for every request it sends, including the
+// requests made when following redirects.
+//
+// Implementations of CookieJar must be safe for concurrent use by
+// multiple goroutines.
+type CookieJar interface {
+	// SetCookies handles the receipt of the cookies in a reply
+	// to a request for url.
+	SetCookies(url *URL, cookies []*Cookie)
+
+	// Cookies returns the cookies to send in a request for url.
+	Cookies(url *URL) []*Cookie
+}
+
+// NewCookieJar returns a CookieJar that keeps cookies in memory.
+// Cookies are matched against requests by domain, path and the
+// Secure attribute, and are discarded when they expire.
+func NewCookieJar() CookieJar {
+	return &memoryJar{entries: make(map[string]*jarEntry)}
+}
+
+type memoryJar struct {
+	lk      sync.Mutex
+	entries map[string]*jarEntry // by domain;path;name
+}
+
+type jarEntry struct {
+	name, value string
+	domain      string // canonical (lower case, no leading dot)
+	hostOnly    bool   // cookie had no Domain attribute
+	path        string
+	secure      bool
+	expires     int64 // seconds since epoch; 0 for a session cookie
+}
+
+// Return the host of url in lower case, without port.
+func jarHost(url *URL) string { return strings.ToLower(hostName(url.Host)) }
+
+// Does the host name domain-match domain (RFC 2965, section 1)?
+func domainMatch(host, domain string) bool {
+	if host == domain {
+		return true
+	}
+	if !strings.HasSuffix(host, "."+domain) {
+		return false
+	}
+	// IP addresses only match exactly.
+	last := host[len(host)-1]
+	return last < '0' || last > '9'
+}
+
+// Does the request path path-match the cookie path?
+func cookiePathMatch(path, cookiePath string) bool {
+	if !strings.HasPrefix(path, cookiePath) {
+		return false
+	}
+	return len(path) == len(cookiePath) ||
+		cookiePath[len(cookiePath)-1] == '/' ||
+		path[len(cookiePath)] == '/'
+}
+
+// The default cookie path for url: its path up to, but
+// not including, the last slash.
+func defaultCookiePath(url *URL) string {
+	p := url.Path
+	if p == "" || p[0] != '/' {
+		return "/"
+	}
+	i := strings.LastIndex(p, "/")
+	if i == 0 {
+		return "/"
+	}
+	return p[0:i]
+}
+
+func (j *memoryJar) SetCookies(url *URL, cookies []*Cookie) {
+	host := jarHost(url)
+	now := time.Seconds()
+
+	j.lk.Lock()
+	defer j.lk.Unlock()
+	for _, c := range cookies {
+		e := &jarEntry{name: c.Name, value: c.Value, secure: c.Secure}
+
+		e.domain = strings.ToLower(c.Domain)
+		if len(e.domain) > 0 && e.domain[0] == '.' {
+			e.domain = e.domain[1:]
+		}
+		if e.domain == "" {
+			e.domain = host
+			e.hostOnly = true
+		} else if !domainMatch(host, e.domain) || strings.Index(e.domain, ".") < 0 && e.domain != host {
+			// Reject cookies for unrelated or top-level domains.
+			continue
+		}
+
+		e.path = c.Path
+		if e.path == "" || e.path[0] != '/' {
+			e.path = defaultCookiePath(url)
+		}
+
+		key := e.domain + ";" + e.path + ";" + e.name
+		switch {
+		case c.MaxAge < 0:
+			j.entries[key] = nil, false
+			continue
+		case c.MaxAge > 0:
+			e.expires = now + int64(c.MaxAge)
+		case c.Expires.Seconds() > 0:
+			if e.expires = c.Expires.Seconds(); e.expires <= now {
+				j.entries[key] = nil, false
+				continue
+			}
+		}
+		j.entries[key] = e
+	}
+}
+
+func (j *memoryJar) Cookies(url *URL) []*Cookie {
+	host := jarHost(url)
+	path := url.Path
+	if path == "" {
+		path = "/"
+	}
+	secure := url.Scheme == "https"
+	now := time.Seconds()
+
+	j.lk.Lock()
+	defer j.lk.Unlock()
+	var matched jarEntries
+	for key, e := range j.entries {
+		if e.expires != 0 && e.expires <= now {
+			j.entries[key] = nil, false
+			continue
+		}
+		if e.hostOnly && host != e.domain || !domainMatch(host, e.domain) {
+			continue
+		}
+		if !cookiePathMatch(path, e.path) || e.secure && !secure {
+			continue
+		}
+		matched.push(e)
+	}
+
+	// Cookies with more specific paths are listed first.
+	sort.Sort(matched)
+	cookies := make([]*Cookie, len(matched))
+	for i, e := range matched {
+		cookies[i] = &Cookie{Name: e.name, Value: e.value}
+	}
+	return cookies
+}
+
+type jarEntries []*jarEntry
+
+func (a *jarEntries) push(e *jarEntry) {
+	n := make([]*jarEntry, len(*a)+1)
+	copy(n, *a)
+	n[len(*a)] = e
+	*a = n
+}
+
+func (a jarEntries) Len() int { return len(a) }
+
+func (a jarEntries) Less(i, j int) bool {
+	if len(a[i].path) != len(a[j].path) {
+		return len(a[i].path) > len(a[j].path)
+	}
+	return a[i].name < a[j].name
+}
+
+func (a jarEntries) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
@@ -170,6 +170,7 @@ type ClientConn struct {
 	nread, nwritten int
 	reqm            list.List  // request methods in order of execution
 	lk              sync.Mutex // protects read/write to reqm,re,we
+	proxy           bool       // write requests for a proxy (WriteProxy)
 }
 
 // NewClientConn returns a new ClientConn reading and writing c.  If r is not
@@ -181,6 +182,14 @@ func NewClientConn(c net.Conn, r *bufio.Reader) *ClientConn {
 	return &ClientConn{c: c, r: r}
 }
 
+// NewProxyClientConn works like NewClientConn but writes requests
+// using Request's WriteProxy method, for a connection to an HTTP proxy.
+func NewProxyClientConn(c net.Conn, r *bufio.Reader) *ClientConn {
+	cc := NewClientConn(c, r)
+	cc.proxy = true
+	return cc
+}
+
 // Close detaches the ClientConn and returns the underlying connection as well
 // as the read-side bufio which may have some left over data. Close may be
 // called before the user or Read have signaled the end of the keep-alive
@@ -223,7 +232,7 @@ func (cc *ClientConn) Write(req *Request) os.Error {
 		cc.lk.Unlock()
 	}
 
-	err := req.Write(cc.c)
+	err := req.write(cc.c, cc.proxy)
 	if err != nil {
 		cc.lk.Lock()
 		defer cc.lk.Unlock()
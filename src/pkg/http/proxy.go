@@ -0,0 +1,144 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Client support for HTTP proxies.
+
+package http
+
+import (
+	"bufio"
+	"encoding/base64"
+	"io"
+	"net"
+	"os"
+	"strings"
+)
+
+// ProxyFromEnvironment returns the URL of the proxy to use for req,
+// as indicated by the environment variables $HTTP_PROXY or
+// $http_proxy, or nil if no proxy should be used.  A proxy
+// given without a scheme, such as "proxy.example.com:3128", is
+// taken to be an http proxy.  Requests for the hosts listed in
+// $NO_PROXY or $no_proxy, and for localhost, do not use the proxy.
+func ProxyFromEnvironment(req *Request) (*URL, os.Error) {
+	proxy := getenvEitherCase("HTTP_PROXY")
+	if proxy == "" || !useProxy(req.URL.Host) {
+		return nil, nil
+	}
+	u, err := ParseURL(proxy)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		if u, err = ParseURL("http://" + proxy); err != nil {
+			return nil, &badStringError{"invalid proxy address", proxy}
+		}
+	}
+	return u, nil
+}
+
+// ProxyURL returns a proxy function, for use as Client.Proxy,
+// that always returns the same URL.
+func ProxyURL(url *URL) func(*Request) (*URL, os.Error) {
+	return func(*Request) (*URL, os.Error) { return url, nil }
+}
+
+func getenvEitherCase(k string) string {
+	if v := os.Getenv(strings.ToUpper(k)); v != "" {
+		return v
+	}
+	return os.Getenv(strings.ToLower(k))
+}
+
+// useProxy returns true if requests to addr should use a proxy,
+// according to the NO_PROXY or no_proxy environment variable.
+// NO_PROXY is a comma-separated list of host names, optionally with
+// ports; a name also matches its subdomains.  "*" disables the proxy
+// for all hosts.
+func useProxy(addr string) bool {
+	host := strings.ToLower(hostName(addr))
+	if host == "" {
+		return true
+	}
+	if host == "localhost" || host == "::1" || strings.HasPrefix(host, "127.") {
+		return false
+	}
+
+	noProxy := getenvEitherCase("NO_PROXY")
+	if noProxy == "*" {
+		return false
+	}
+	for _, p := range strings.Split(noProxy, ",", 0) {
+		p = strings.ToLower(strings.TrimSpace(p))
+		if p == "" {
+			continue
+		}
+		if hasPort(p) {
+			if p != strings.ToLower(addr) {
+				continue
+			}
+			return false
+		}
+		if p[0] == '.' {
+			p = p[1:]
+		}
+		if host == p || strings.HasSuffix(host, "."+p) {
+			return false
+		}
+	}
+	return true
+}
+
+// proxyAuth returns the value of a Proxy-Authorization header
+// with the credentials in proxy, or "" if it has none.
+func proxyAuth(proxy *URL) string {
+	info := proxy.Userinfo
+	if info == "" {
+		return ""
+	}
+	enc := base64.StdEncoding
+	encoded := make([]byte, enc.EncodedLen(len(info)))
+	enc.Encode(encoded, []byte(info))
+	return "Basic " + string(encoded)
+}
+
+// dialProxy connects to the proxy and, for https requests, asks it
+// to open a tunnel to addr with CONNECT, over which it runs TLS.
+func dialProxy(proxy *URL, scheme, addr string) (net.Conn, os.Error) {
+	paddr := proxy.Host
+	if !hasPort(paddr) {
+		paddr += ":http"
+	}
+	conn, err := net.Dial("tcp", "", paddr)
+	if err != nil {
+		return nil, err
+	}
+	if scheme != "https" {
+		return conn, nil
+	}
+
+	// CONNECT takes a numeric port, but Do names it after the scheme.
+	target := addr
+	if strings.HasSuffix(target, ":https") {
+		target = target[0:len(target)-len("https")] + "443"
+	}
+	hdr := "CONNECT " + target + " HTTP/1.1\r\nHost: " + target + "\r\n"
+	if auth := proxyAuth(proxy); auth != "" {
+		hdr += "Proxy-Authorization: " + auth + "\r\n"
+	}
+	if _, err = io.WriteString(conn, hdr+"\r\n"); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	// The server says nothing until the TLS client speaks,
+	// so reading through a buffer cannot consume any of
+	// the tunnelled data.
+	resp, err := ReadResponse(bufio.NewReader(conn), "CONNECT")
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if resp.StatusCode != StatusOK {
+		conn.Close()
+		return nil, &badStringError{"proxy refused CONNECT", resp.Status}
+	}
+	return clientTLS(conn, addr, hostName(addr))
+}
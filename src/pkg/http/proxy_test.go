@@ -0,0 +1,198 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Tests for proxy.go
+
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"strings"
+	"testing"
+)
+
+type useProxyTest struct {
+	addr  string
+	proxy bool
+}
+
+var useProxyTests = []useProxyTest{
+	useProxyTest{"localhost:80", false},
+	useProxyTest{"127.0.0.1:8080", false},
+	useProxyTest{"[::1]:80", false},
+	useProxyTest{"example.com:80", true},
+	useProxyTest{"internal.example.com:80", true},
+	useProxyTest{"foo.com:80", false},
+	useProxyTest{"www.foo.com:80", false},
+	useProxyTest{"barfoo.com:80", true},
+	useProxyTest{"bar.org:80", false},
+	useProxyTest{"bar.org:8080", true},
+	useProxyTest{"x.local:80", false},
+}
+
+func TestUseProxy(t *testing.T) {
+	oldNoProxy := os.Getenv("NO_PROXY")
+	defer os.Setenv("NO_PROXY", oldNoProxy)
+
+	os.Setenv("NO_PROXY", "foo.com, bar.org:80, .local")
+	for _, tt := range useProxyTests {
+		if proxy := useProxy(tt.addr); proxy != tt.proxy {
+			t.Errorf("useProxy(%q) = %v, want %v", tt.addr, proxy, tt.proxy)
+		}
+	}
+}
+
+func TestWriteProxy(t *testing.T) {
+	req := &Request{Method: "GET"}
+	req.URL, _ = ParseURL("http://www.example.com/a?b=c")
+	var b bytes.Buffer
+	req.WriteProxy(&b)
+	want := "GET http://www.example.com/a?b=c HTTP/1.1\r\n"
+	if s := b.String(); len(s) < len(want) || s[0:len(want)] != want {
+		t.Errorf("WriteProxy wrote %q, want request line %q", s, want)
+	}
+}
+
+// proxyServer acts as the proxy for requests sent with absolute URLs.
+func proxyServer(c *Conn, req *Request) {
+	if req.URL.Host != "www.example.com" || req.RawURL[0] == '/' {
+		c.WriteHeader(StatusBadRequest)
+		return
+	}
+	io.WriteString(c, "proxied "+req.URL.Path+" "+req.Header["Proxy-Authorization"])
+}
+
+func TestClientProxy(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen: %v", err)
+	}
+	defer ln.Close()
+	go Serve(ln, HandlerFunc(proxyServer))
+
+	proxy, _ := ParseURL("http://user:pass@" + ln.Addr().String())
+	client := &Client{Proxy: ProxyURL(proxy)}
+	r, _, err := client.Get("http://www.example.com/path")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	b, err := ioutil.ReadAll(r.Body)
+	r.Body.Close()
+	if want := "proxied /path Basic dXNlcjpwYXNz"; err != nil || string(b) != want {
+		t.Errorf("Get through proxy: %q, %v; want %q", b, err, want)
+	}
+	client.CloseIdleConnections()
+}
+
+// A connectProxy answers CONNECT requests by opening a tunnel to
+// backend, if they carry the Proxy-Authorization value auth.
+type connectProxy struct {
+	net.Listener
+	backend string
+	auth    string
+	lines   chan string // request line of each CONNECT received
+}
+
+func (p *connectProxy) serve() {
+	for {
+		c, err := p.Accept()
+		if err != nil {
+			return
+		}
+		go p.tunnel(c)
+	}
+}
+
+func (p *connectProxy) tunnel(c net.Conn) {
+	defer c.Close()
+	br := bufio.NewReader(c)
+	line, err := br.ReadString('\n')
+	if err != nil {
+		return
+	}
+	auth := ""
+	for {
+		h, err := br.ReadString('\n')
+		if err != nil {
+			return
+		}
+		if h == "\r\n" {
+			break
+		}
+		if strings.HasPrefix(h, "Proxy-Authorization: ") {
+			auth = strings.TrimSpace(h[len("Proxy-Authorization: "):])
+		}
+	}
+	p.lines <- strings.TrimSpace(line)
+	if auth != p.auth {
+		io.WriteString(c, "HTTP/1.1 407 Proxy Authentication Required\r\nContent-Length: 0\r\n\r\n")
+		return
+	}
+	b, err := net.Dial("tcp", "", p.backend)
+	if err != nil {
+		io.WriteString(c, "HTTP/1.1 502 Bad Gateway\r\nContent-Length: 0\r\n\r\n")
+		return
+	}
+	io.WriteString(c, "HTTP/1.1 200 OK\r\n\r\n")
+	go func() {
+		io.Copy(b, br)
+		b.Close()
+	}()
+	io.Copy(c, b)
+}
+
+func TestClientProxyConnect(t *testing.T) {
+	pemCert, err := ioutil.ReadFile("testdata/cert.pem")
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := RootCAs
+	defer func() { RootCAs = saved }()
+	RootCAs = tls.NewCASet()
+	RootCAs.SetFromPEM(pemCert)
+
+	backend := startTLSServer(t)
+	defer backend.Close()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen: %v", err)
+	}
+	defer ln.Close()
+	p := &connectProxy{ln, backend.Addr().String(), "Basic dXNlcjpwYXNz", make(chan string, 10)}
+	go p.serve()
+
+	// The tunnel leads to the TLS server, whose certificate names "test".
+	proxy, _ := ParseURL("http://user:pass@" + ln.Addr().String())
+	client := &Client{Proxy: ProxyURL(proxy)}
+	r, _, err := client.Get("https://test/")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	b, err := ioutil.ReadAll(r.Body)
+	r.Body.Close()
+	client.CloseIdleConnections()
+	if err != nil || string(b) != "hello" {
+		t.Errorf("Get through tunnel: %q, %v; want %q", b, err, "hello")
+	}
+	if line, want := <-p.lines, "CONNECT test:443 HTTP/1.1"; line != want {
+		t.Errorf("proxy received %q, want %q", line, want)
+	}
+
+	// Wrong credentials: the proxy refuses the tunnel.
+	proxy, _ = ParseURL("http://user:wrong@" + ln.Addr().String())
+	client = &Client{Proxy: ProxyURL(proxy)}
+	if r, _, err = client.Get("https://test/"); err == nil {
+		r.Body.Close()
+		t.Errorf("Get succeeded through a proxy that refused CONNECT")
+	} else if strings.Index(err.String(), "407") < 0 {
+		t.Errorf("Get: %v; want 407 error", err)
+	}
+	<-p.lines
+}
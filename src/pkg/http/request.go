@@ -16,16 +16,19 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"mime"
+	"mime/multipart"
 	"os"
 	"strconv"
 	"strings"
 )
 
 const (
-	maxLineLength  = 4096 // assumed <= bufio.defaultBufSize
-	maxValueLength = 4096
-	maxHeaderLines = 1024
-	chunkSize      = 4 << 10 // 4 KB chunks
+	maxLineLength    = 4096 // assumed <= bufio.defaultBufSize
+	maxValueLength   = 4096
+	maxHeaderLines   = 1024
+	chunkSize        = 4 << 10  // 4 KB chunks
+	defaultMaxMemory = 32 << 20 // 32 MB
 )
 
 // HTTP request parsing errors.
@@ -40,6 +43,9 @@ var (
 	ErrNotSupported         = &ProtocolError{"feature not supported"}
 	ErrUnexpectedTrailer    = &ProtocolError{"trailer header without chunked transfer encoding"}
 	ErrMissingContentLength = &ProtocolError{"missing ContentLength in HEAD response"}
+	ErrNotMultipart         = &ProtocolError{"request Content-Type isn't multipart/form-data"}
+	ErrMissingBoundary      = &ProtocolError{"no multipart boundary param in Content-Type"}
+	ErrMissingFile          = &ProtocolError{"no such file"}
 )
 
 type badStringError struct {
@@ -123,9 +129,23 @@ type Request struct {
 	// The User-Agent: header string, if sent in the request.
 	UserAgent string
 
+	// The cookies sent with the request in the Cookie: header.
+	// Like Referer and UserAgent, the cookies are removed from
+	// the Header map when a request is read.
+	Cookie []*Cookie
+
 	// The parsed form. Only available after ParseForm is called.
 	Form map[string][]string
 
+	// The parsed multipart form, including file uploads.
+	// Only available after ParseMultipartForm is called.
+	MultipartForm *multipart.Form
+
+	// Vars holds the values of the path variables, such as
+	// {id} in "/users/{id}", in the ServeMux pattern that
+	// matched the request.
+	Vars map[string]string
+
 	// Trailer maps trailer keys to values.  Like for Header, if the
 	// response has multiple trailer lines with the same key, they will be
 	// concatenated, delimited by commas.
@@ -156,23 +176,38 @@ const defaultUserAgent = "Go http package"
 //	Method (defaults to "GET")
 //	UserAgent (defaults to defaultUserAgent)
 //	Referer
+//	Cookie
 //	Header
 //	Body
 //
 // If Body is present, Write forces "Transfer-Encoding: chunked" as a header
 // and then closes Body when finished sending it.
 func (req *Request) Write(w io.Writer) os.Error {
+	return req.write(w, false)
+}
+
+// WriteProxy is like Write but writes the request in the form
+// expected by an HTTP proxy: the request line carries the absolute
+// URI of the request, including the scheme and host.
+func (req *Request) WriteProxy(w io.Writer) os.Error {
+	return req.write(w, true)
+}
+
+func (req *Request) write(w io.Writer, usingProxy bool) os.Error {
 	host := req.Host
 	if host == "" {
 		host = req.URL.Host
 	}
 
 	uri := req.RawURL
-	if uri == "" {
+	if uri == "" || usingProxy && strings.Index(uri, "://") < 0 {
 		uri = valueOrDefault(urlEscape(req.URL.Path, false), "/")
 		if req.URL.RawQuery != "" {
 			uri += "?" + req.URL.RawQuery
 		}
+		if usingProxy {
+			uri = valueOrDefault(req.URL.Scheme, "http") + "://" + req.URL.Host + uri
+		}
 	}
 
 	fmt.Fprintf(w, "%s %s HTTP/1.1\r\n", valueOrDefault(req.Method, "GET"), uri)
@@ -183,6 +218,9 @@ func (req *Request) Write(w io.Writer) os.Error {
 	if req.Referer != "" {
 		fmt.Fprintf(w, "Referer: %s\r\n", req.Referer)
 	}
+	if err := writeCookies(w, req.Cookie); err != nil {
+		return err
+	}
 
 	// Process Body,ContentLength,Close,Trailer
 	tw, err := newTransferWriter(req)
@@ -396,9 +434,10 @@ func CanonicalHeaderKey(s string) string {
 }
 
 type chunkedReader struct {
-	r   *bufio.Reader
-	n   uint64 // unread bytes in chunk
-	err os.Error
+	r       *bufio.Reader
+	n       uint64            // unread bytes in chunk
+	err     os.Error
+	trailer map[string]string // if non-nil, receives the trailer lines
 }
 
 func newChunkedReader(r *bufio.Reader) *chunkedReader {
@@ -419,13 +458,21 @@ func (cr *chunkedReader) beginChunk() {
 	if cr.n == 0 {
 		// trailer CRLF
 		for {
-			line, cr.err = readLine(cr.r)
+			var key, value string
+			key, value, cr.err = readKeyValue(cr.r)
 			if cr.err != nil {
 				return
 			}
-			if line == "" {
+			if key == "" {
 				break
 			}
+			if cr.trailer != nil {
+				key = CanonicalHeaderKey(key)
+				if old := cr.trailer[key]; old != "" {
+					value = old + "," + value
+				}
+				cr.trailer[key] = value
+			}
 		}
 		cr.err = os.EOF
 	}
@@ -536,6 +583,10 @@ func ReadRequest(b *bufio.Reader) (req *Request, err os.Error) {
 		req.UserAgent = v
 		req.Header["User-Agent"] = "", false
 	}
+	if v, present := req.Header["Cookie"]; present {
+		req.Cookie = readCookies(v)
+		req.Header["Cookie"] = "", false
+	}
 
 	// TODO: Parse specific header values:
 	//	Accept
@@ -625,7 +676,10 @@ func (r *Request) ParseForm() (err os.Error) {
 				return err
 			}
 			query = string(b)
-		// TODO(dsymonds): Handle multipart/form-data
+		case "multipart/form-data":
+			// Handled by ParseMultipartForm, which leaves the
+			// body alone until it is called.
+			return nil
 		default:
 			return &badStringError{"unknown Content-Type", ct}
 		}
@@ -633,14 +687,88 @@ func (r *Request) ParseForm() (err os.Error) {
 	return parseForm(r.Form, query)
 }
 
+// MultipartReader returns a MIME multipart reader if this is a
+// multipart/form-data POST request, else returns nil and an error.
+// Use this function instead of ParseMultipartForm to
+// process the request body as a stream.
+func (r *Request) MultipartReader() (*multipart.Reader, os.Error) {
+	if r.MultipartForm != nil {
+		return nil, os.NewError("http: multipart handled by ParseMultipartForm")
+	}
+	ct, _ := r.Header["Content-Type"]
+	d, params := mime.ParseMediaType(ct)
+	if d != "multipart/form-data" {
+		return nil, ErrNotMultipart
+	}
+	boundary, ok := params["boundary"]
+	if !ok {
+		return nil, ErrMissingBoundary
+	}
+	if r.Body == nil {
+		return nil, os.ErrorString("missing form body")
+	}
+	return multipart.NewReader(r.Body, boundary), nil
+}
+
+// ParseMultipartForm parses a request body as multipart/form-data.
+// The whole request body is parsed and up to a total of maxMemory bytes of
+// its file parts are stored in memory, with the remainder stored on
+// disk in temporary files.  The form values are added to r.Form and
+// the files are available in r.MultipartForm.
+// ParseMultipartForm calls ParseForm if necessary.
+// After one call to ParseMultipartForm, subsequent calls have no effect.
+func (r *Request) ParseMultipartForm(maxMemory int64) os.Error {
+	if r.Form == nil {
+		if err := r.ParseForm(); err != nil {
+			return err
+		}
+	}
+	if r.MultipartForm != nil {
+		return nil
+	}
+
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return err
+	}
+	f, err := mr.ReadForm(maxMemory)
+	if err != nil {
+		return err
+	}
+	for k, v := range f.Value {
+		old := r.Form[k]
+		all := make([]string, len(old)+len(v))
+		copy(all, old)
+		copy(all[len(old):], v)
+		r.Form[k] = all
+	}
+	r.MultipartForm = f
+	return nil
+}
+
 // FormValue returns the first value for the named component of the query.
-// FormValue calls ParseForm if necessary.
+// FormValue calls ParseMultipartForm and ParseForm if necessary.
 func (r *Request) FormValue(key string) string {
 	if r.Form == nil {
-		r.ParseForm()
+		r.ParseMultipartForm(defaultMaxMemory)
 	}
 	if vs, ok := r.Form[key]; ok && len(vs) > 0 {
 		return vs[0]
 	}
 	return ""
 }
+
+// FormFile returns the first file for the provided form key.
+// FormFile calls ParseMultipartForm and ParseForm if necessary.
+func (r *Request) FormFile(key string) (multipart.File, *multipart.FileHeader, os.Error) {
+	if r.MultipartForm == nil {
+		if err := r.ParseMultipartForm(defaultMaxMemory); err != nil {
+			return nil, nil, err
+		}
+	}
+	if fhs := r.MultipartForm.File[key]; len(fhs) > 0 {
+		f, err := fhs[0].Open()
+		return f, fhs[0], err
+	}
+	return nil, nil, ErrMissingFile
+}
@@ -6,6 +6,9 @@ package http
 
 import (
 	"bytes"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
 	"testing"
 )
 
@@ -102,6 +105,45 @@ func TestPostContentTypeParsing(t *testing.T) {
 	}
 }
 
+func TestParseMultipartForm(t *testing.T) {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	w.WriteField("field", "value")
+	fw, _ := w.CreateFormFile("upload", "hello.txt")
+	io.WriteString(fw, "hello, world\n")
+	w.Close()
+
+	req := &Request{
+		Method: "POST",
+		Header: stringMap{"Content-Type": w.FormDataContentType()},
+		Body:   nopCloser{&body},
+	}
+	if v := req.FormValue("field"); v != "value" {
+		t.Errorf("FormValue(field) = %q, want %q", v, "value")
+	}
+	f, fh, err := req.FormFile("upload")
+	if err != nil {
+		t.Fatalf("FormFile: %v", err)
+	}
+	b, _ := ioutil.ReadAll(f)
+	f.Close()
+	if fh.Filename != "hello.txt" || string(b) != "hello, world\n" {
+		t.Errorf("FormFile = %q with %q", fh.Filename, b)
+	}
+	if _, _, err := req.FormFile("missing"); err != ErrMissingFile {
+		t.Errorf("FormFile(missing) error = %v, want ErrMissingFile", err)
+	}
+
+	req = &Request{
+		Method: "POST",
+		Header: stringMap{"Content-Type": "text/plain"},
+		Body:   nopCloser{bytes.NewBufferString("body")},
+	}
+	if err := req.ParseMultipartForm(1 << 20); err != ErrNotMultipart {
+		t.Errorf("ParseMultipartForm on text/plain = %v, want ErrNotMultipart", err)
+	}
+}
+
 func TestRedirect(t *testing.T) {
 	const (
 		start = "http://codesearch.google.com/"
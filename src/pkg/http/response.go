@@ -67,6 +67,14 @@ type Response struct {
 	// response has multiple trailer lines with the same key, they will be
 	// concatenated, delimited by commas.
 	Trailer map[string]string
+
+	// SetCookie records the cookies set by the response, one for each
+	// Set-Cookie header.  Set-Cookie lines are omitted from Header.
+	SetCookie []*Cookie
+
+	// rawSetCookie holds the Set-Cookie values exactly as received,
+	// including any that could not be parsed into SetCookie.
+	rawSetCookie []string
 }
 
 // ReadResponse reads and returns an HTTP response from r.  The RequestMethod
@@ -115,6 +123,15 @@ func ReadResponse(r *bufio.Reader, requestMethod string) (resp *Response, err os
 		if nheader++; nheader >= maxHeaderLines {
 			return nil, ErrHeaderTooLong
 		}
+		if CanonicalHeaderKey(key) == "Set-Cookie" {
+			// Set-Cookie values cannot be joined with commas,
+			// since commas appear in the Expires attribute.
+			resp.rawSetCookie = appendCookieString(resp.rawSetCookie, value)
+			if c := readSetCookie(value); c != nil {
+				resp.SetCookie = appendCookie(resp.SetCookie, c)
+			}
+			continue
+		}
 		resp.AddHeader(key, value)
 	}
 
@@ -179,6 +196,7 @@ func (r *Response) ProtoAtLeast(major, minor int) bool {
 //  Trailer
 //  Body
 //  ContentLength
+//  SetCookie
 //  Header, values for non-canonical keys will have unpredictable behavior
 //
 func (resp *Response) Write(w io.Writer) os.Error {
@@ -214,6 +232,10 @@ func (resp *Response) Write(w io.Writer) os.Error {
 	if err != nil {
 		return err
 	}
+	err = writeSetCookies(w, resp.SetCookie)
+	if err != nil {
+		return err
+	}
 
 	// End-of-header
 	io.WriteString(w, "\r\n")
@@ -0,0 +1,223 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// HTTP reverse proxy handler
+
+package http
+
+import (
+	"io"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ReverseProxy is an HTTP Handler that takes an incoming request and
+// sends it to another server, proxying the response back to the
+// client.
+type ReverseProxy struct {
+	// Director must be a function which modifies the request
+	// into a new request to be sent using a ClientConn.  Its
+	// response is then copied back to the original client
+	// unmodified, except for the hop-by-hop headers.
+	Director func(*Request)
+}
+
+// Hop-by-hop headers, which apply to a single connection and are
+// removed when a message is forwarded (RFC 2616, section 13.5.1).
+var hopHeaders = []string{
+	"Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Proxy-Connection", // non-standard, but still sent by some clients
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// NewSingleHostReverseProxy returns a new ReverseProxy that rewrites
+// URLs to the scheme, host, and base path provided in target.  If the
+// target's path is "/base" and the incoming request was for "/dir",
+// the target request will be for /base/dir.
+func NewSingleHostReverseProxy(target *URL) *ReverseProxy {
+	director := func(req *Request) {
+		req.URL.Scheme = target.Scheme
+		req.URL.Host = target.Host
+		req.URL.Path = singleJoiningSlash(target.Path, req.URL.Path)
+		if q := req.URL.RawQuery; q == "" || target.RawQuery == "" {
+			req.URL.RawQuery = target.RawQuery + q
+		} else {
+			req.URL.RawQuery = target.RawQuery + "&" + q
+		}
+	}
+	return &ReverseProxy{Director: director}
+}
+
+func singleJoiningSlash(a, b string) string {
+	aslash := strings.HasSuffix(a, "/")
+	bslash := strings.HasPrefix(b, "/")
+	switch {
+	case aslash && bslash:
+		return a + b[1:]
+	case !aslash && !bslash:
+		return a + "/" + b
+	}
+	return a + b
+}
+
+// Remove the hop-by-hop headers from h, including
+// any named by its Connection header.
+func removeHopHeaders(h map[string]string) {
+	if c, ok := h["Connection"]; ok {
+		for _, f := range strings.Split(c, ",", 0) {
+			if f = strings.TrimSpace(f); f != "" {
+				h[CanonicalHeaderKey(f)] = "", false
+			}
+		}
+	}
+	for _, k := range hopHeaders {
+		h[k] = "", false
+	}
+}
+
+func copyHeader(h map[string]string) map[string]string {
+	n := make(map[string]string)
+	for k, v := range h {
+		n[k] = v
+	}
+	return n
+}
+
+func (p *ReverseProxy) ServeHTTP(c *Conn, req *Request) {
+	outreq := new(Request)
+	*outreq = *req // shallow copy; Header and URL are replaced below
+
+	url := *req.URL
+	outreq.URL = &url
+	p.Director(outreq)
+	outreq.RawURL = ""
+	outreq.Proto = "HTTP/1.1"
+	outreq.ProtoMajor = 1
+	outreq.ProtoMinor = 1
+	outreq.Close = false
+
+	outreq.Header = copyHeader(req.Header)
+	removeHopHeaders(outreq.Header)
+
+	if clientIP := hostName(c.RemoteAddr); clientIP != "" {
+		// If we aren't the first proxy retain prior
+		// X-Forwarded-For information as a comma+space
+		// separated list and fold multiple headers into one.
+		if prior, ok := outreq.Header["X-Forwarded-For"]; ok {
+			clientIP = prior + ", " + clientIP
+		}
+		outreq.Header["X-Forwarded-For"] = clientIP
+	}
+
+	res, err := p.roundTrip(outreq)
+	if err != nil {
+		log.Stderr("http: proxy error: ", err)
+		c.SetHeader("Content-Type", "text/plain; charset=utf-8")
+		c.WriteHeader(StatusBadGateway)
+		io.WriteString(c, "502 bad gateway\n")
+		return
+	}
+	defer res.Body.Close()
+
+	removeHopHeaders(res.Header)
+	if _, ok := res.Header["Content-Type"]; !ok {
+		c.header["Content-Type"] = "", false
+	}
+	for k, v := range res.Header {
+		if k == "Content-Length" {
+			continue
+		}
+		c.SetHeader(k, v)
+	}
+	if !c.chunking && res.ContentLength >= 0 {
+		c.SetHeader("Content-Length", strconv.Itoa64(res.ContentLength))
+	}
+	// Copy the Set-Cookie lines as sent, rather than res.SetCookie,
+	// which loses attributes and lines the cookie parser rejects.
+	c.rawSetCookie = res.rawSetCookie
+	if len(res.Trailer) > 0 {
+		keys := make([]string, len(res.Trailer))
+		i := 0
+		for k, _ := range res.Trailer {
+			keys[i] = k
+			i++
+		}
+		c.SetHeader("Trailer", strings.Join(keys, ", "))
+	}
+	c.WriteHeader(res.StatusCode)
+	if req.Method != "HEAD" {
+		copyFlushing(c, res.Body)
+		// The trailer values are known once the body has been read.
+		c.trailer = res.Trailer
+	}
+}
+
+// Send req to the server named by its URL over a new connection.
+// The connection is closed when the response body is.
+func (p *ReverseProxy) roundTrip(req *Request) (*Response, os.Error) {
+	addr := req.URL.Host
+	if !hasPort(addr) {
+		addr += ":" + valueOrDefault(req.URL.Scheme, "http")
+	}
+	var conn net.Conn
+	var err os.Error
+	if req.URL.Scheme == "https" {
+		conn, err = dialTLS(addr, hostName(addr))
+	} else {
+		conn, err = net.Dial("tcp", "", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	cc := NewClientConn(conn, nil)
+	if err = cc.Write(req); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	res, err := cc.Read()
+	if err != nil && err != ErrPersistEOF {
+		conn.Close()
+		return nil, err
+	}
+	res.Body = &closeBoth{res.Body, conn}
+	return res, nil
+}
+
+// A closeBoth closes a response body and then its connection.
+type closeBoth struct {
+	io.ReadCloser
+	conn net.Conn
+}
+
+func (b *closeBoth) Close() os.Error {
+	b.ReadCloser.Close()
+	return b.conn.Close()
+}
+
+// Copy src to the reply in c, flushing after each read so that
+// streamed responses reach the client as they arrive.
+func copyFlushing(c *Conn, src io.Reader) {
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := src.Read(buf)
+		if n > 0 {
+			if _, werr := c.Write(buf[0:n]); werr != nil {
+				return
+			}
+			c.Flush()
+		}
+		if err != nil {
+			return
+		}
+	}
+}
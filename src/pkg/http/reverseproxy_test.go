@@ -0,0 +1,88 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Tests for reverseproxy.go
+
+package http
+
+import (
+	"io"
+	"io/ioutil"
+	"net"
+	"testing"
+)
+
+func backendServer(c *Conn, req *Request) {
+	if len(req.Header["X-Forwarded-For"]) == 0 {
+		c.WriteHeader(StatusBadRequest)
+		io.WriteString(c, "missing X-Forwarded-For")
+		return
+	}
+	for _, k := range []string{"Proxy-Authorization", "Proxy-Connection"} {
+		if _, ok := req.Header[k]; ok {
+			c.WriteHeader(StatusBadRequest)
+			io.WriteString(c, "hop-by-hop header "+k+" was forwarded")
+			return
+		}
+	}
+	c.SetHeader("Content-Type", "text/plain")
+	c.SetHeader("X-Backend", "yes")
+	c.SetHeader("Connection", "X-Hop")
+	c.SetHeader("X-Hop", "hop")
+	c.SetCookie(&Cookie{Name: "flavor", Value: "chips"})
+	c.SetHeader("Set-Cookie", "=odd; Priority=High")
+	c.SetHeader("Trailer", "X-Trailer")
+	c.trailer = map[string]string{"X-Trailer": "done"}
+	c.WriteHeader(StatusCreated)
+	io.WriteString(c, "backend "+req.URL.Path+"?"+req.URL.RawQuery)
+}
+
+func listenAndServe(t *testing.T, h Handler) net.Listener {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen: %v", err)
+	}
+	go Serve(ln, h)
+	return ln
+}
+
+func TestReverseProxy(t *testing.T) {
+	backend := listenAndServe(t, HandlerFunc(backendServer))
+	defer backend.Close()
+	target, _ := ParseURL("http://" + backend.Addr().String() + "/base?x=1")
+	front := listenAndServe(t, NewSingleHostReverseProxy(target))
+	defer front.Close()
+
+	req := &Request{Method: "GET", Header: map[string]string{"Proxy-Authorization": "secret", "Proxy-Connection": "keep-alive"}}
+	req.URL, _ = ParseURL("http://" + front.Addr().String() + "/dir?y=2")
+	client := new(Client)
+	r, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	b, err := ioutil.ReadAll(r.Body)
+	r.Body.Close()
+	client.CloseIdleConnections()
+	if want := "backend /base/dir?x=1&y=2"; err != nil || string(b) != want {
+		t.Errorf("body = %q, %v; want %q", b, err, want)
+	}
+	if r.StatusCode != StatusCreated {
+		t.Errorf("StatusCode = %d, want %d", r.StatusCode, StatusCreated)
+	}
+	if h := r.Header["X-Backend"]; h != "yes" {
+		t.Errorf("X-Backend = %q, want %q", h, "yes")
+	}
+	if h, ok := r.Header["X-Hop"]; ok {
+		t.Errorf("hop-by-hop header X-Hop = %q was forwarded", h)
+	}
+	if len(r.SetCookie) != 1 || r.SetCookie[0].Name != "flavor" {
+		t.Errorf("SetCookie = %v, want flavor cookie", r.SetCookie)
+	}
+	if len(r.rawSetCookie) != 2 || r.rawSetCookie[0] != "=odd; Priority=High" {
+		t.Errorf("Set-Cookie lines = %q, want flavor and =odd lines", r.rawSetCookie)
+	}
+	if tr := r.Trailer["X-Trailer"]; tr != "done" {
+		t.Errorf("trailer X-Trailer = %q, want %q", tr, "done")
+	}
+}
@@ -13,20 +13,24 @@ package http
 
 import (
 	"bufio"
+	"crypto/tls"
 	"fmt"
 	"io"
 	"log"
 	"net"
 	"os"
 	"path"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 )
 
 // Errors introduced by the HTTP server.
 var (
 	ErrWriteAfterFlush = os.NewError("Conn.Write called after Flush")
 	ErrHijacked        = os.NewError("Conn has been hijacked")
+	ErrServerShutdown  = os.NewError("Server has been shut down")
 )
 
 // Objects implementing the Handler interface can be
@@ -47,7 +51,9 @@ type Conn struct {
 	Req        *Request // current HTTP request
 
 	rwc      io.ReadWriteCloser // i/o connection
-	buf      *bufio.ReadWriter  // buffered rwc
+	lr       *headerLimitReader // limits the size of request headers read from rwc
+	buf      *bufio.ReadWriter  // buffered lr and rwc
+	srv      *Server            // server that accepted the connection
 	handler  Handler            // request handler
 	hijacked bool               // connection has been hijacked by handler
 
@@ -56,19 +62,57 @@ type Conn struct {
 	chunking        bool              // using chunked transfer encoding for reply body
 	wroteHeader     bool              // reply header has been written
 	header          map[string]string // reply header parameters
+	cookies         []*Cookie         // cookies to set in the reply header
+	rawSetCookie    []string          // Set-Cookie values to copy verbatim into the reply header
+	trailer         map[string]string // trailer to send after a chunked reply body
 	written         int64             // number of bytes written in body
 	status          int               // status code passed to WriteHeader
+	compress        *compressState    // body held back while deciding to compress it
+	zw              io.WriteCloser    // compressor for the body, if compressing
+}
+
+// A headerLimitReader returns ErrHeaderTooLong once more than
+// n bytes have been read through it.  A negative n means no limit.
+type headerLimitReader struct {
+	r io.Reader
+	n int64
+}
+
+func (l *headerLimitReader) Read(p []byte) (n int, err os.Error) {
+	if l.n < 0 {
+		return l.r.Read(p)
+	}
+	if l.n == 0 {
+		return 0, ErrHeaderTooLong
+	}
+	if int64(len(p)) > l.n {
+		p = p[0:l.n]
+	}
+	n, err = l.r.Read(p)
+	l.n -= int64(n)
+	return
 }
 
 // Create new connection from rwc.
-func newConn(rwc net.Conn, handler Handler) (c *Conn, err os.Error) {
+func newConn(rwc net.Conn, srv *Server) (c *Conn, err os.Error) {
 	c = new(Conn)
 	if a := rwc.RemoteAddr(); a != nil {
 		c.RemoteAddr = a.String()
 	}
-	c.handler = handler
+	c.srv = srv
+	c.handler = srv.Handler
+	if c.handler == nil {
+		c.handler = DefaultServeMux
+	}
+	if srv.ReadTimeout > 0 {
+		rwc.SetReadTimeout(srv.ReadTimeout)
+	}
+	if srv.WriteTimeout > 0 {
+		rwc.SetWriteTimeout(srv.WriteTimeout)
+	}
 	c.rwc = rwc
-	br := bufio.NewReader(rwc)
+	c.lr = &headerLimitReader{rwc, -1}
+	br := bufio.NewReader(c.lr)
 	bw := bufio.NewWriter(rwc)
 	c.buf = bufio.NewReadWriter(br, bw)
 	return c, nil
@@ -79,13 +123,22 @@ func (c *Conn) readRequest() (req *Request, err os.Error) {
 	if c.hijacked {
 		return nil, ErrHijacked
 	}
-	if req, err = ReadRequest(c.buf.Reader); err != nil {
+	// Limit the header to MaxHeaderBytes, beyond what is already buffered.
+	c.lr.n = int64(c.srv.maxHeaderBytes())
+	req, err = ReadRequest(c.buf.Reader)
+	c.lr.n = -1
+	if err != nil {
 		return nil, err
 	}
 
 	// Reset per-request connection state.
 	c.header = make(map[string]string)
+	c.cookies = nil
+	c.rawSetCookie = nil
+	c.trailer = nil
 	c.wroteHeader = false
+	c.compress = nil
+	c.zw = nil
 	c.Req = req
 
 	// Default output is HTML encoded in UTF-8.
@@ -122,6 +175,12 @@ func (c *Conn) readRequest() (req *Request, err os.Error) {
 // are ignored.
 func (c *Conn) SetHeader(hdr, val string) { c.header[CanonicalHeaderKey(hdr)] = val }
 
+// SetCookie adds a Set-Cookie header line for cookie to the eventual
+// reply.  Unlike SetHeader, SetCookie can be called several times to
+// set several cookies.  Calls to SetCookie after WriteHeader (or Write)
+// are ignored.
+func (c *Conn) SetCookie(cookie *Cookie) { c.cookies = appendCookie(c.cookies, cookie) }
+
 // WriteHeader sends an HTTP response header with status code.
 // If WriteHeader is not called explicitly, the first call to Write
 // will trigger an implicit WriteHeader(http.StatusOK).
@@ -142,10 +201,42 @@ func (c *Conn) WriteHeader(code int) {
 	if !c.Req.ProtoAtLeast(1, 0) {
 		return
 	}
+	if c.compress != nil && !c.compress.wants(c, code) {
+		c.compress = nil
+	}
+	if c.compress == nil {
+		c.writeHeaderLines()
+	}
+	// Otherwise the header is written once enough of the
+	// body has been seen to decide whether to compress it.
+}
+
+// bodyAllowed returns true if the reply may have a body: replies
+// to HEAD requests and some status codes must not.
+func (c *Conn) bodyAllowed() bool {
+	if c.Req.Method == "HEAD" {
+		return false
+	}
+	return c.status >= 200 && c.status != StatusNoContent && c.status != StatusNotModified
+}
+
+// Write the status line and header of the reply.
+func (c *Conn) writeHeaderLines() {
+	// A reply without a body, or whose length the handler
+	// has given, needs no chunking.
+	if _, ok := c.header["Content-Length"]; c.chunking && (ok || !c.bodyAllowed()) {
+		c.chunking = false
+		c.header["Transfer-Encoding"] = "", false
+	}
+	if !c.chunking {
+		// Only a chunked body can carry a trailer.
+		c.header["Trailer"] = "", false
+	}
 	proto := "HTTP/1.0"
 	if c.Req.ProtoAtLeast(1, 1) {
 		proto = "HTTP/1.1"
 	}
+	code := c.status
 	codestring := strconv.Itoa(code)
 	text, ok := statusText[code]
 	if !ok {
@@ -155,6 +246,10 @@ func (c *Conn) WriteHeader(code int) {
 	for k, v := range c.header {
 		io.WriteString(c.buf, k+": "+v+"\r\n")
 	}
+	writeSetCookies(c.buf, c.cookies)
+	for _, v := range c.rawSetCookie {
+		io.WriteString(c.buf, "Set-Cookie: "+v+"\r\n")
+	}
 	io.WriteString(c.buf, "\r\n")
 }
 
@@ -172,9 +267,29 @@ func (c *Conn) Write(data []byte) (n int, err os.Error) {
 	if len(data) == 0 {
 		return 0, nil
 	}
+	if !c.bodyAllowed() {
+		// Discard the body of a reply that must not have one.
+		return len(data), nil
+	}
 
 	c.written += int64(len(data)) // ignoring errors, for errorKludge
 
+	if c.compress != nil {
+		return c.compress.write(c, data)
+	}
+	if c.zw != nil {
+		return c.zw.Write(data)
+	}
+	return c.writeChunk(data)
+}
+
+// Write data to the connection as part of the reply body,
+// as a chunk if using chunked transfer encoding.
+func (c *Conn) writeChunk(data []byte) (n int, err os.Error) {
+	if len(data) == 0 {
+		return 0, nil
+	}
+
 	// TODO(rsc): if chunking happened after the buffering,
 	// then there would be fewer chunk headers.
 	// On the other hand, it would make hijacking more difficult.
@@ -214,6 +329,11 @@ func errorKludge(c *Conn, req *Request) {
 		return
 	}
 
+	// Did the handler promise a length?
+	if _, ok := c.header["Content-Length"]; ok {
+		return
+	}
+
 	// Is it a broken browser?
 	var msg string
 	switch agent := req.UserAgent; {
@@ -248,19 +368,37 @@ func (c *Conn) finishRequest() {
 		c.WriteHeader(StatusOK)
 	}
 	errorKludge(c, c.Req)
+	if c.compress != nil {
+		// Too short to be worth compressing.
+		c.startBody(false)
+	}
+	if c.zw != nil {
+		c.zw.Close()
+		c.zw = nil
+	}
 	if c.chunking {
 		io.WriteString(c.buf, "0\r\n")
 		// trailer key/value pairs, followed by blank line
+		for k, v := range c.trailer {
+			io.WriteString(c.buf, k+": "+v+"\r\n")
+		}
 		io.WriteString(c.buf, "\r\n")
 	}
 	c.buf.Flush()
 }
 
 // Flush sends any buffered data to the client.
+// If the reply might be compressed but not enough of it
+// has been written yet to decide, it is sent uncompressed.
+// Once compression has started, data held by the compressor
+// is not sent until the reply is finished.
 func (c *Conn) Flush() {
 	if !c.wroteHeader {
 		c.WriteHeader(StatusOK)
 	}
+	if c.compress != nil {
+		c.startBody(false)
+	}
 	c.buf.Flush()
 }
 
@@ -279,7 +417,11 @@ func (c *Conn) close() {
 // Serve a new connection.
 func (c *Conn) serve() {
 	for {
+		if !c.srv.setIdle(c, true) {
+			break
+		}
 		req, err := c.readRequest()
+		c.srv.setIdle(c, false)
 		if err != nil {
 			break
 		}
@@ -287,7 +429,11 @@ func (c *Conn) serve() {
 		// Until the server replies to this request, it can't read another,
 		// so we might as well run the handler in this goroutine.
 		c.handler.ServeHTTP(c, req)
+		if req.MultipartForm != nil {
+			req.MultipartForm.RemoveAll()
+		}
 		if c.hijacked {
+			c.srv.forget(c)
 			return
 		}
 		c.finishRequest()
@@ -295,6 +441,7 @@ func (c *Conn) serve() {
 			break
 		}
 	}
+	c.srv.forget(c)
 	c.close()
 }
 
@@ -414,50 +561,188 @@ func RedirectHandler(url string, code int) Handler {
 }
 
 // ServeMux is an HTTP request multiplexer.
-// It matches the URL of each incoming request against a list of registered
+// It matches each incoming request against a list of registered
 // patterns and calls the handler for the pattern that
-// most closely matches the URL.
+// most closely matches the request.
 //
-// Patterns named fixed paths, like "/favicon.ico",
-// or subtrees, like "/images/" (note the trailing slash).
-// Patterns must begin with /.
-// Longer patterns take precedence over shorter ones, so that
-// if there are handlers registered for both "/images/"
-// and "/images/thumbnails/", the latter handler will be
-// called for paths beginning "/images/thumbnails/" and the
-// former will receiver requests for any other paths in the
-// "/images/" subtree.
+// A pattern has the form "[METHOD ][HOST]/PATH".
+// PATH names a fixed path, like "/favicon.ico",
+// or a subtree, like "/images/" (note the trailing slash).
+// A path segment of the form {NAME} matches any single non-empty
+// segment of the request path; the matched values are stored in
+// the request's Vars map under NAME.  For example, "/users/{id}"
+// matches "/users/42" and sets req.Vars["id"] to "42".
 //
-// In the future, the pattern syntax may be relaxed to allow
-// an optional host-name at the beginning of the pattern,
-// so that a handler might register for the two patterns
+// A pattern beginning with a method, like "GET /users/{id}",
+// matches only requests with that method; a GET pattern also
+// matches HEAD requests.  A pattern beginning with a host name,
+// like "codesearch.google.com/", matches only requests for that
+// host, so that a handler might register for the two patterns
 // "/codesearch" and "codesearch.google.com/"
 // without taking over requests for http://www.google.com/.
 //
+// When several patterns match a request, the most specific wins:
+// host-qualified patterns take precedence over ones without a host;
+// then, comparing the patterns segment by segment, a literal segment
+// takes precedence over a {NAME} segment; then longer patterns take
+// precedence over shorter ones, so that if there are handlers
+// registered for both "/images/" and "/images/thumbnails/", the
+// latter handler will be called for paths beginning
+// "/images/thumbnails/" and the former will receive requests
+// for any other paths in the "/images/" subtree; then fixed paths
+// take precedence over subtrees and patterns with a method over
+// patterns without one.  Remaining ties go to the pattern
+// registered first.
+//
+// If the request path matches one or more patterns but none
+// of them allows the request's method, ServeMux replies with
+// ``405 Method Not Allowed'' and an Allow header listing the
+// methods that would have matched.
+//
 // ServeMux also takes care of sanitizing the URL request path,
 // redirecting any request containing . or .. elements to an
 // equivalent .- and ..-free URL.
 type ServeMux struct {
-	m map[string]Handler
+	m  map[string]*muxEntry // registered patterns, by canonical form
+	es []*muxEntry          // the same entries, in order of precedence
+}
+
+// A muxEntry is a parsed ServeMux pattern and its handler.
+type muxEntry struct {
+	method   string   // "" matches any method
+	host     string   // "" matches any host
+	segs     []string // path split at "/", without the final "" of a subtree
+	subtree  bool     // pattern ends in "/"
+	implicit bool     // redirect added for a subtree pattern
+	order    int      // registration order, to break ties
+	h        Handler
 }
 
 // NewServeMux allocates and returns a new ServeMux.
-func NewServeMux() *ServeMux { return &ServeMux{make(map[string]Handler)} }
+func NewServeMux() *ServeMux { return &ServeMux{m: make(map[string]*muxEntry)} }
 
 // DefaultServeMux is the default ServeMux used by Serve.
 var DefaultServeMux = NewServeMux()
 
-// Does path match pattern?
-func pathMatch(pattern, path string) bool {
-	if len(pattern) == 0 {
-		// should not happen
-		return false
+// Is s a {NAME} path variable?
+func isVar(s string) bool {
+	return len(s) > 2 && s[0] == '{' && s[len(s)-1] == '}'
+}
+
+// parsePattern parses a ServeMux pattern, returning nil if it is invalid.
+func parsePattern(pattern string) *muxEntry {
+	e := new(muxEntry)
+	p := pattern
+	if i := strings.Index(p, " "); i >= 0 && p[0] != '/' {
+		e.method = p[0:i]
+		p = strings.TrimSpace(p[i+1:])
+		if e.method == "" || strings.ToUpper(e.method) != e.method {
+			return nil
+		}
+	}
+	i := strings.Index(p, "/")
+	if i < 0 {
+		return nil
+	}
+	e.host = strings.ToLower(p[0:i])
+	p = p[i:]
+
+	e.segs = strings.Split(p, "/", 0)
+	if n := len(e.segs); n > 1 && e.segs[n-1] == "" {
+		e.subtree = true
+		e.segs = e.segs[0 : n-1]
+	}
+	seen := make(map[string]bool)
+	for _, s := range e.segs {
+		if isVar(s) {
+			name := s[1 : len(s)-1]
+			if seen[name] || strings.Index(name, "{") >= 0 || strings.Index(name, "}") >= 0 {
+				return nil
+			}
+			seen[name] = true
+		} else if strings.Index(s, "{") >= 0 || strings.Index(s, "}") >= 0 {
+			return nil
+		}
+	}
+	return e
+}
+
+// String returns the canonical form of the pattern.
+func (e *muxEntry) String() string {
+	s := e.host + strings.Join(e.segs, "/")
+	if e.subtree {
+		s += "/"
+	}
+	if e.method != "" {
+		s = e.method + " " + s
+	}
+	return s
+}
+
+// match reports whether the entry's host and path match
+// the request and, if so, returns the values of the path
+// variables.  It does not consider the request method.
+func (e *muxEntry) match(req *Request) (vars map[string]string, ok bool) {
+	if e.host != "" {
+		host := req.Host
+		if host == "" {
+			host = req.URL.Host
+		}
+		if strings.ToLower(hostName(host)) != e.host {
+			return nil, false
+		}
 	}
-	n := len(pattern)
-	if pattern[n-1] != '/' {
-		return pattern == path
+	elems := strings.Split(req.URL.Path, "/", 0)
+	if e.subtree && len(elems) <= len(e.segs) || !e.subtree && len(elems) != len(e.segs) {
+		return nil, false
 	}
-	return len(path) >= n && path[0:n] == pattern
+	for i, s := range e.segs {
+		if !isVar(s) {
+			if s != elems[i] {
+				return nil, false
+			}
+			continue
+		}
+		if elems[i] == "" {
+			return nil, false
+		}
+		if vars == nil {
+			vars = make(map[string]string)
+		}
+		vars[s[1:len(s)-1]] = elems[i]
+	}
+	return vars, true
+}
+
+// Does the entry accept requests with the given method?
+func (e *muxEntry) allows(method string) bool {
+	return e.method == "" || e.method == method || e.method == "GET" && method == "HEAD"
+}
+
+// before reports whether e takes precedence over f
+// when both match a request.
+func (e *muxEntry) before(f *muxEntry) bool {
+	if (e.host != "") != (f.host != "") {
+		return e.host != ""
+	}
+	for i := 0; i < len(e.segs) && i < len(f.segs); i++ {
+		if ev, fv := isVar(e.segs[i]), isVar(f.segs[i]); ev != fv {
+			return fv
+		}
+	}
+	if len(e.segs) != len(f.segs) {
+		return len(e.segs) > len(f.segs)
+	}
+	if e.subtree != f.subtree {
+		return f.subtree
+	}
+	if e.implicit != f.implicit {
+		return f.implicit
+	}
+	if (e.method != "") != (f.method != "") {
+		return e.method != ""
+	}
+	return e.order < f.order
 }
 
 // Return the canonical path for p, eliminating . and .. elements.
@@ -477,8 +762,62 @@ func cleanPath(p string) string {
 	return np
 }
 
+// handler returns the handler to use for req, which must have a
+// clean path, and the values of the path variables in its pattern.
+// If no pattern matches, it returns a handler that replies
+// with 404 or, if only the method failed to match, 405.
+// The redirect added for a subtree pattern is not used if a
+// pattern for the same path matched but rejected the method.
+func (mux *ServeMux) handler(req *Request) (h Handler, vars map[string]string) {
+	var allow []string
+	for _, e := range mux.es {
+		v, ok := e.match(req)
+		if !ok || e.implicit && allow != nil {
+			continue
+		}
+		if !e.allows(req.Method) {
+			allow = addMethod(allow, e.method)
+			if e.method == "GET" {
+				allow = addMethod(allow, "HEAD")
+			}
+			continue
+		}
+		return e.h, v
+	}
+	if len(allow) > 0 {
+		sort.SortStrings(allow)
+		return methodNotAllowedHandler(strings.Join(allow, ", ")), nil
+	}
+	return NotFoundHandler(), nil
+}
+
+// Add method to the list ms if it is not already present.
+func addMethod(ms []string, method string) []string {
+	for _, m := range ms {
+		if m == method {
+			return ms
+		}
+	}
+	n := make([]string, len(ms)+1)
+	copy(n, ms)
+	n[len(ms)] = method
+	return n
+}
+
+// methodNotAllowedHandler returns a handler that replies to each
+// request with a ``405 method not allowed'' reply and the given
+// Allow header.
+func methodNotAllowedHandler(allow string) Handler {
+	return HandlerFunc(func(c *Conn, req *Request) {
+		c.SetHeader("Allow", allow)
+		c.SetHeader("Content-Type", "text/plain; charset=utf-8")
+		c.WriteHeader(StatusMethodNotAllowed)
+		io.WriteString(c, "405 method not allowed\n")
+	})
+}
+
 // ServeHTTP dispatches the request to the handler whose
-// pattern most closely matches the request URL.
+// pattern most closely matches the request.
 func (mux *ServeMux) ServeHTTP(c *Conn, req *Request) {
 	// Clean path to canonical form and redirect.
 	if p := cleanPath(req.URL.Path); p != req.URL.Path {
@@ -487,37 +826,77 @@ func (mux *ServeMux) ServeHTTP(c *Conn, req *Request) {
 		return
 	}
 
-	// Most-specific (longest) pattern wins.
-	var h Handler
-	var n = 0
-	for k, v := range mux.m {
-		if !pathMatch(k, req.URL.Path) {
-			continue
-		}
-		if h == nil || len(k) > n {
-			n = len(k)
-			h = v
-		}
-	}
-	if h == nil {
-		h = NotFoundHandler()
-	}
+	h, vars := mux.handler(req)
+	req.Vars = vars
 	h.ServeHTTP(c, req)
 }
 
 // Handle registers the handler for the given pattern.
+// If a handler already exists for pattern, Handle replaces it.
+// Registering a subtree such as "/tree/" also redirects
+// requests for "/tree" to "/tree/", unless a pattern
+// for "/tree" is registered too.
 func (mux *ServeMux) Handle(pattern string, handler Handler) {
-	if pattern == "" || pattern[0] != '/' {
+	e := parsePattern(pattern)
+	if e == nil {
 		panicln("http: invalid pattern", pattern)
 	}
-
-	mux.m[pattern] = handler
+	e.h = handler
+	mux.add(e)
 
 	// Helpful behavior:
 	// If pattern is /tree/, insert permanent redirect for /tree.
-	n := len(pattern)
-	if n > 0 && pattern[n-1] == '/' {
-		mux.m[pattern[0:n-1]] = RedirectHandler(pattern, StatusMovedPermanently)
+	if e.subtree && len(e.segs) > 1 {
+		r := &muxEntry{method: e.method, host: e.host, segs: e.segs, implicit: true}
+		r.h = HandlerFunc(func(c *Conn, req *Request) {
+			Redirect(c, req.URL.Path+"/", StatusMovedPermanently)
+		})
+		mux.add(r)
+	}
+}
+
+// Add e to mux, replacing any entry for the same pattern
+// unless e is an implicit redirect.
+func (mux *ServeMux) add(e *muxEntry) {
+	key := e.String()
+	e.order = len(mux.es)
+	if old, ok := mux.m[key]; ok {
+		if e.implicit {
+			return
+		}
+		if !old.implicit {
+			old.h = e.h
+			return
+		}
+		// An explicit pattern replaces the redirect and
+		// takes its place in the precedence order.
+		e.order = old.order
+		mux.remove(old)
+	}
+	mux.m[key] = e
+
+	// Insert e into the precedence order.
+	i := 0
+	for i < len(mux.es) && mux.es[i].before(e) {
+		i++
+	}
+	es := make([]*muxEntry, len(mux.es)+1)
+	copy(es, mux.es[0:i])
+	es[i] = e
+	copy(es[i+1:], mux.es[i:])
+	mux.es = es
+}
+
+// Remove e from the precedence order.
+func (mux *ServeMux) remove(e *muxEntry) {
+	for i, x := range mux.es {
+		if x == e {
+			es := make([]*muxEntry, len(mux.es)-1)
+			copy(es, mux.es[0:i])
+			copy(es[i:], mux.es[i+1:])
+			mux.es = es
+			return
+		}
 	}
 }
 
@@ -525,28 +904,195 @@ func (mux *ServeMux) Handle(pattern string, handler Handler) {
 // in the DefaultServeMux.
 func Handle(pattern string, handler Handler) { DefaultServeMux.Handle(pattern, handler) }
 
+// A Server defines parameters for running an HTTP server.
+// The zero value for each field is a usable default.
+type Server struct {
+	Addr    string  // TCP address to listen on, ":http" if empty
+	Handler Handler // handler to invoke, DefaultServeMux if nil
+
+	// ReadTimeout and WriteTimeout, if non-zero, are the read and
+	// write timeouts in nanoseconds set on each accepted connection
+	// (see net.Conn's SetReadTimeout and SetWriteTimeout).
+	ReadTimeout  int64
+	WriteTimeout int64
+
+	// MaxHeaderBytes controls the maximum number of bytes the server
+	// will read parsing the request header, not counting data already
+	// buffered from the connection.  If zero, DefaultMaxHeaderBytes
+	// is used.
+	MaxHeaderBytes int
+
+	// MaxConns, if non-zero, is the maximum number of connections
+	// served at once.  When it is reached, the server stops accepting
+	// until one of the connections is closed.
+	MaxConns int
+
+	lk       sync.Mutex
+	listener net.Listener
+	shutdown bool
+	conns    map[*Conn]bool // active connections; true if idle
+	slots    chan bool      // one value for each connection, if MaxConns > 0
+	drained  chan bool      // signaled when the last connection closes after Shutdown
+}
+
+// DefaultMaxHeaderBytes is the maximum permitted size of the headers
+// in an HTTP request, unless overridden by Server.MaxHeaderBytes.
+const DefaultMaxHeaderBytes = 1 << 20 // 1 MB
+
+func (srv *Server) maxHeaderBytes() int {
+	if srv.MaxHeaderBytes > 0 {
+		return srv.MaxHeaderBytes
+	}
+	return DefaultMaxHeaderBytes
+}
+
+// Record whether c is waiting for a request (idle) or handling one.
+// It returns false if the server is shutting down and c should not
+// wait for another request.
+func (srv *Server) setIdle(c *Conn, idle bool) bool {
+	srv.lk.Lock()
+	defer srv.lk.Unlock()
+	if idle && srv.shutdown {
+		return false
+	}
+	srv.conns[c] = idle
+	return true
+}
+
+// Stop tracking c, which has been closed or hijacked.
+func (srv *Server) forget(c *Conn) {
+	srv.lk.Lock()
+	defer srv.lk.Unlock()
+	if _, ok := srv.conns[c]; !ok {
+		return
+	}
+	srv.conns[c] = false, false
+	if srv.slots != nil {
+		<-srv.slots
+	}
+	if srv.shutdown && len(srv.conns) == 0 {
+		_ = srv.drained <- true
+	}
+}
+
 // Serve accepts incoming HTTP connections on the listener l,
 // creating a new service thread for each.  The service threads
-// read requests and then call handler to reply to them.
-// Handler is typically nil, in which case the DefaultServeMux is used.
-func Serve(l net.Listener, handler Handler) os.Error {
-	if handler == nil {
-		handler = DefaultServeMux
+// read requests and then call srv.Handler to reply to them.
+// Serve returns nil after Shutdown is called.
+func (srv *Server) Serve(l net.Listener) os.Error {
+	srv.lk.Lock()
+	if srv.shutdown {
+		srv.lk.Unlock()
+		return ErrServerShutdown
 	}
+	srv.listener = l
+	srv.conns = make(map[*Conn]bool)
+	srv.drained = make(chan bool, 1)
+	if srv.MaxConns > 0 {
+		srv.slots = make(chan bool, srv.MaxConns)
+	}
+	slots := srv.slots
+	srv.lk.Unlock()
+
 	for {
+		if slots != nil {
+			slots <- true
+		}
 		rw, e := l.Accept()
 		if e != nil {
+			if slots != nil {
+				<-slots
+			}
+			srv.lk.Lock()
+			shutdown := srv.shutdown
+			srv.lk.Unlock()
+			if shutdown {
+				return nil
+			}
 			return e
 		}
-		c, err := newConn(rw, handler)
+		c, err := newConn(rw, srv)
 		if err != nil {
+			if slots != nil {
+				<-slots
+			}
 			continue
 		}
+		srv.lk.Lock()
+		srv.conns[c] = false
+		srv.lk.Unlock()
 		go c.serve()
 	}
 	panic("not reached")
 }
 
+// Shutdown stops the server: it closes the listener, so that no new
+// connections are accepted, closes the connections that are idle,
+// and then waits for the requests in progress to finish.  Connections
+// that were hijacked are no longer the server's concern and are not
+// waited for.
+func (srv *Server) Shutdown() os.Error {
+	srv.lk.Lock()
+	if srv.shutdown {
+		srv.lk.Unlock()
+		return ErrServerShutdown
+	}
+	srv.shutdown = true
+	l := srv.listener
+	wait := len(srv.conns) > 0
+	for c, idle := range srv.conns {
+		if idle {
+			c.rwc.Close()
+		}
+	}
+	srv.lk.Unlock()
+
+	var err os.Error
+	if l != nil {
+		err = l.Close()
+	}
+	if wait {
+		<-srv.drained
+	}
+	return err
+}
+
+// ListenAndServe listens on the TCP network address srv.Addr and then
+// calls Serve to handle requests on incoming connections.  If
+// srv.Addr is blank, ":http" is used.
+func (srv *Server) ListenAndServe() os.Error {
+	addr := srv.Addr
+	if addr == "" {
+		addr = ":http"
+	}
+	l, e := net.Listen("tcp", addr)
+	if e != nil {
+		return e
+	}
+	return srv.serveListener(l)
+}
+
+// Serve l and close it when done.
+func (srv *Server) serveListener(l net.Listener) os.Error {
+	e := srv.Serve(l)
+	srv.lk.Lock()
+	shutdown := srv.shutdown
+	srv.lk.Unlock()
+	if !shutdown {
+		l.Close()
+	}
+	return e
+}
+
+// Serve accepts incoming HTTP connections on the listener l,
+// creating a new service thread for each.  The service threads
+// read requests and then call handler to reply to them.
+// Handler is typically nil, in which case the DefaultServeMux is used.
+func Serve(l net.Listener, handler Handler) os.Error {
+	srv := &Server{Handler: handler}
+	return srv.Serve(l)
+}
+
 // ListenAndServe listens on the TCP network address addr
 // and then calls Serve with handler to handle requests
 // on incoming connections.  Handler is typically nil,
@@ -574,11 +1120,78 @@ func Serve(l net.Listener, handler Handler) os.Error {
 //		}
 //	}
 func ListenAndServe(addr string, handler Handler) os.Error {
-	l, e := net.Listen("tcp", addr)
-	if e != nil {
-		return e
+	srv := &Server{Addr: addr, Handler: handler}
+	return srv.ListenAndServe()
+}
+
+// A tlsListener wraps each connection accepted from the underlying
+// listener in a TLS server connection.
+type tlsListener struct {
+	net.Listener
+	config *tls.Config
+}
+
+func (l *tlsListener) Accept() (c net.Conn, err os.Error) {
+	raw, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
 	}
-	e = Serve(l, handler)
-	l.Close()
-	return e
+	return &tlsConn{tls.Server(raw, l.config), raw}, nil
+}
+
+// ListenAndServeTLS acts identically to ListenAndServe, except that it
+// expects HTTPS connections.  Additionally, files containing a certificate
+// and matching private key for the server must be provided, both in PEM
+// format.  If the certificate is signed by a certificate authority, the
+// certFile should be the concatenation of the server's certificate followed
+// by the CA's certificate.
+//
+// A trivial example server is:
+//
+//	import (
+//		"http"
+//		"log"
+//	)
+//
+//	func handler(c *http.Conn, req *http.Request) {
+//		c.SetHeader("Content-Type", "text/plain")
+//		c.Write([]byte("This is an example server.\n"))
+//	}
+//
+//	func main() {
+//		http.Handle("/", http.HandlerFunc(handler))
+//		log.Stdoutf("About to listen on 10443. Go to https://127.0.0.1:10443/")
+//		err := http.ListenAndServeTLS(":10443", "cert.pem", "key.pem", nil)
+//		if err != nil {
+//			log.Exit(err)
+//		}
+//	}
+func ListenAndServeTLS(addr string, certFile string, keyFile string, handler Handler) os.Error {
+	srv := &Server{Addr: addr, Handler: handler}
+	return srv.ListenAndServeTLS(certFile, keyFile)
+}
+
+// ListenAndServeTLS acts identically to ListenAndServe, except that it
+// expects HTTPS connections.  If srv.Addr is blank, ":https" is used.
+// The certFile and keyFile are as for the package-level ListenAndServeTLS.
+func (srv *Server) ListenAndServeTLS(certFile string, keyFile string) os.Error {
+	addr := srv.Addr
+	if addr == "" {
+		addr = ":https"
+	}
+	config, err := tlsConfig()
+	if err != nil {
+		return err
+	}
+	config.Certificates = make([]tls.Certificate, 1)
+	config.Certificates[0], err = tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return err
+	}
+
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return srv.serveListener(&tlsListener{l, config})
 }
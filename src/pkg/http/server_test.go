@@ -0,0 +1,302 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Tests for Server and ServeMux in server.go
+
+package http
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServerShutdown(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen: %v", err)
+	}
+	started := make(chan bool)
+	release := make(chan bool)
+	srv := &Server{Handler: HandlerFunc(func(c *Conn, req *Request) {
+		started <- true
+		<-release
+		io.WriteString(c, "done")
+	})}
+	served := make(chan bool)
+	go func() {
+		if err := srv.Serve(ln); err != nil {
+			t.Errorf("Serve: %v", err)
+		}
+		served <- true
+	}()
+
+	body := make(chan string)
+	go func() {
+		r, _, err := Get("http://" + ln.Addr().String() + "/")
+		if err != nil {
+			t.Errorf("Get: %v", err)
+			body <- ""
+			return
+		}
+		b, _ := ioutil.ReadAll(r.Body)
+		r.Body.Close()
+		body <- string(b)
+	}()
+	<-started
+
+	shutdown := make(chan bool)
+	go func() {
+		if err := srv.Shutdown(); err != nil {
+			t.Errorf("Shutdown: %v", err)
+		}
+		shutdown <- true
+	}()
+	<-served
+
+	// The request in progress must be allowed to finish.
+	release <- true
+	if s := <-body; s != "done" {
+		t.Errorf("body = %q, want %q", s, "done")
+	}
+	<-shutdown
+
+	if _, err := net.Dial("tcp", "", ln.Addr().String()); err == nil {
+		t.Errorf("Dial succeeded after Shutdown")
+	}
+	if err := srv.Serve(ln); err != ErrServerShutdown {
+		t.Errorf("Serve after Shutdown = %v, want ErrServerShutdown", err)
+	}
+}
+
+// Send a request with n extra header lines of about 70 bytes each
+// and return the status line of the reply.
+func sendHeaderLines(t *testing.T, addr string, n int) (string, os.Error) {
+	c, err := net.Dial("tcp", "", addr)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	defer c.Close()
+	c.SetReadTimeout(5e9)
+	req := "GET / HTTP/1.1\r\nHost: example.com\r\n"
+	for i := 0; i < n; i++ {
+		req += fmt.Sprintf("X-H%d: %s\r\n", i, strings.Repeat("x", 60))
+	}
+	io.WriteString(c, req+"\r\n")
+	return bufio.NewReader(c).ReadString('\n')
+}
+
+func TestServerMaxHeaderBytes(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen: %v", err)
+	}
+	srv := &Server{Handler: HandlerFunc(helloServer), MaxHeaderBytes: 1024}
+	go srv.Serve(ln)
+	defer srv.Shutdown()
+
+	// Every line is well under maxLineLength;
+	// only the total size of the header counts.
+	addr := ln.Addr().String()
+	if line, err := sendHeaderLines(t, addr, 5); err != nil || !strings.HasPrefix(line, "HTTP/1.1 200") {
+		t.Errorf("short header: reply %q, %v; want 200", line, err)
+	}
+	if line, err := sendHeaderLines(t, addr, 40); err == nil {
+		t.Errorf("server replied %q to request with oversized header", line)
+	}
+}
+
+func TestServerMaxConns(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen: %v", err)
+	}
+	srv := &Server{Handler: HandlerFunc(helloServer), MaxConns: 1}
+	go srv.Serve(ln)
+	defer srv.Shutdown()
+
+	const req = "GET / HTTP/1.1\r\nHost: example.com\r\n\r\n"
+	c1, err := net.Dial("tcp", "", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	c1.SetReadTimeout(5e9)
+	io.WriteString(c1, req)
+	if line, err := bufio.NewReader(c1).ReadString('\n'); err != nil {
+		t.Fatalf("first connection: %q, %v", line, err)
+	}
+
+	// c1 stays open, so the server must not serve c2 yet.
+	c2, err := net.Dial("tcp", "", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	defer c2.Close()
+	c2.SetReadTimeout(2e8)
+	io.WriteString(c2, req)
+	if line, err := bufio.NewReader(c2).ReadString('\n'); err == nil {
+		t.Fatalf("second connection served while first open: %q", line)
+	}
+
+	c1.Close()
+	c2.SetReadTimeout(5e9)
+	if line, err := bufio.NewReader(c2).ReadString('\n'); err != nil || !strings.HasPrefix(line, "HTTP/1.1 200") {
+		t.Errorf("second connection after first closed: %q, %v; want 200", line, err)
+	}
+}
+
+func TestServerReadTimeout(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen: %v", err)
+	}
+	srv := &Server{Handler: HandlerFunc(helloServer), ReadTimeout: 1e8}
+	go srv.Serve(ln)
+	defer srv.Shutdown()
+
+	c, err := net.Dial("tcp", "", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	defer c.Close()
+	// Send nothing; the server should give up and hang up.
+	c.SetReadTimeout(5e9)
+	t0 := time.Nanoseconds()
+	_, err = c.Read(make([]byte, 1))
+	if t1 := time.Nanoseconds(); err != os.EOF || t1-t0 > 2e9 {
+		t.Errorf("Read = %v after %f seconds; want os.EOF after 0.1", err, float64(t1-t0)/1e9)
+	}
+}
+
+// A patternHandler replies with the pattern it was registered for.
+type patternHandler string
+
+func (p patternHandler) ServeHTTP(c *Conn, req *Request) {
+	io.WriteString(c, string(p))
+}
+
+var muxPatterns = []string{
+	"/",
+	"/images/",
+	"/images/thumbnails/",
+	"/favicon.ico",
+	"GET /users/{id}",
+	"DELETE /users/{id}",
+	"/users/new",
+	"/users/{id}/",
+	"PUT /users/{id}/photos/{photo}",
+	"codesearch.google.com/",
+	"GET codesearch.google.com/search",
+}
+
+type muxTest struct {
+	method, host, path string
+	pattern            string // "" if no pattern matches
+	vars               string
+}
+
+var muxTests = []muxTest{
+	muxTest{"GET", "", "/", "/", ""},
+	muxTest{"GET", "", "/favicon.ico", "/favicon.ico", ""},
+	muxTest{"GET", "", "/images/a.png", "/images/", ""},
+	muxTest{"GET", "", "/images/thumbnails/a.png", "/images/thumbnails/", ""},
+	muxTest{"GET", "", "/users/42", "GET /users/{id}", "id=42"},
+	muxTest{"HEAD", "", "/users/42", "GET /users/{id}", "id=42"},
+	muxTest{"DELETE", "", "/users/42", "DELETE /users/{id}", "id=42"},
+	muxTest{"POST", "", "/users/42", "/", ""},
+	muxTest{"POST", "", "/users/new", "/users/new", ""},
+	muxTest{"GET", "", "/users/42/", "/users/{id}/", "id=42"},
+	muxTest{"GET", "", "/users/42/photos/7", "/users/{id}/", "id=42"},
+	muxTest{"PUT", "", "/users/42/photos/7", "PUT /users/{id}/photos/{photo}", "id=42 photo=7"},
+	muxTest{"GET", "codesearch.google.com", "/favicon.ico", "codesearch.google.com/", ""},
+	muxTest{"GET", "CodeSearch.Google.com:80", "/search", "GET codesearch.google.com/search", ""},
+	muxTest{"POST", "codesearch.google.com", "/search", "codesearch.google.com/", ""},
+	muxTest{"GET", "www.google.com", "/search", "/", ""},
+}
+
+func newTestMux() *ServeMux {
+	mux := NewServeMux()
+	for _, p := range muxPatterns {
+		mux.Handle(p, patternHandler(p))
+	}
+	return mux
+}
+
+func TestServeMuxHandler(t *testing.T) {
+	mux := newTestMux()
+	for _, tt := range muxTests {
+		req := &Request{Method: tt.method, Host: tt.host, URL: &URL{Path: tt.path}}
+		h, vars := mux.handler(req)
+		pattern, _ := h.(patternHandler)
+		kv := make([]string, len(vars))
+		i := 0
+		for k, v := range vars {
+			kv[i] = k + "=" + v
+			i++
+		}
+		sort.SortStrings(kv)
+		if string(pattern) != tt.pattern || strings.Join(kv, " ") != tt.vars {
+			t.Errorf("%s %s%s matched %q with vars %v, want %q with vars %q",
+				tt.method, tt.host, tt.path, pattern, kv, tt.pattern, tt.vars)
+		}
+	}
+}
+
+var invalidPatterns = []string{
+	"",
+	"users",
+	"get /users",
+	"/users/{}",
+	"/users/{id}/{id}",
+	"/users/x{id}",
+}
+
+func TestServeMuxInvalidPattern(t *testing.T) {
+	for _, p := range invalidPatterns {
+		if e := parsePattern(p); e != nil {
+			t.Errorf("parsePattern(%q) = %q, want nil", p, e.String())
+		}
+	}
+}
+
+func TestServeMuxReplies(t *testing.T) {
+	mux := NewServeMux()
+	for _, p := range []string{"/images/", "/images/thumbnails/", "GET /users/{id}", "DELETE /users/{id}", "/users/{id}/"} {
+		mux.Handle(p, patternHandler(p))
+	}
+	ln := listenAndServe(t, mux)
+	defer ln.Close()
+	url := "http://" + ln.Addr().String()
+
+	// A subtree pattern redirects the path without its trailing slash.
+	r, final, err := Get(url + "/images/thumbnails")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	b, _ := ioutil.ReadAll(r.Body)
+	r.Body.Close()
+	if want := url + "/images/thumbnails/"; final != want || string(b) != "/images/thumbnails/" {
+		t.Errorf("Get /images/thumbnails: final URL %q, body %q; want %q", final, b, want)
+	}
+
+	// A path matched only by patterns for other methods gets a 405.
+	r, err = Post(url+"/users/42", "text/plain", strings.NewReader("x"))
+	if err != nil {
+		t.Fatalf("Post: %v", err)
+	}
+	r.Body.Close()
+	if r.StatusCode != StatusMethodNotAllowed {
+		t.Errorf("POST /users/42: status %d, want %d", r.StatusCode, StatusMethodNotAllowed)
+	}
+	if allow, want := r.Header["Allow"], "DELETE, GET, HEAD"; allow != want {
+		t.Errorf("POST /users/42: Allow %q, want %q", allow, want)
+	}
+}
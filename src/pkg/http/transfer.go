@@ -221,7 +221,9 @@ func readTransfer(msg interface{}, r *bufio.Reader) (err os.Error) {
 	// or close connection when finished, since multipart is not supported yet
 	switch {
 	case chunked(t.TransferEncoding):
-		t.Body = &body{Reader: newChunkedReader(r), hdr: msg, r: r, closing: t.Close}
+		cr := newChunkedReader(r)
+		cr.trailer = t.Trailer
+		t.Body = &body{Reader: cr, hdr: msg, r: r, closing: t.Close}
 	case t.ContentLength >= 0:
 		// TODO: limit the Content-Length. This is an easy DoS vector.
 		t.Body = &body{Reader: io.LimitReader(r, t.ContentLength), closing: t.Close}
@@ -427,7 +429,6 @@ func (b *body) Close() os.Error {
 		return nil
 	}
 
-	// TODO(petar): Put trailer reader code here
-
+	// The chunked reader has filled in the trailer.
 	return nil
 }
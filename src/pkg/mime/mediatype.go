@@ -0,0 +1,106 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mime
+
+import (
+	"bytes"
+	"strings"
+)
+
+// isTSpecial returns true if c is a tspecial as defined by RFC 2045
+// and RFC 2616, section 2.2.
+func isTSpecial(c byte) bool {
+	return strings.Index(`()<>@,;:\"/[]?=`, string(c)) >= 0
+}
+
+// isTokenChar returns true if c may appear in a token as defined by
+// RFC 2045: any CHAR except SPACE, CTLs, or tspecials.
+func isTokenChar(c byte) bool {
+	return c > 0x20 && c < 0x7f && !isTSpecial(c)
+}
+
+// consumeToken consumes a token from the beginning of v and returns
+// the token and the rest of v.  If v does not begin with a token,
+// token is empty and rest is v.
+func consumeToken(v string) (token, rest string) {
+	i := 0
+	for i < len(v) && isTokenChar(v[i]) {
+		i++
+	}
+	return v[0:i], v[i:]
+}
+
+// consumeValue consumes a token or a quoted-string from the beginning
+// of v and returns the unquoted value and the rest of v.  If v does
+// not begin with a valid value, value is empty and rest is v.
+func consumeValue(v string) (value, rest string) {
+	if v == "" || v[0] != '"' {
+		return consumeToken(v)
+	}
+	var b bytes.Buffer
+	for i := 1; i < len(v); i++ {
+		switch c := v[i]; {
+		case c == '"':
+			return b.String(), v[i+1:]
+		case c == '\\' && i+1 < len(v):
+			i++
+			b.WriteByte(v[i])
+		case c == '\r' || c == '\n':
+			return "", v
+		default:
+			b.WriteByte(c)
+		}
+	}
+	// Missing closing quote.
+	return "", v
+}
+
+// ParseMediaType parses the value of a Content-Type or
+// Content-Disposition header as defined by RFC 2045 and RFC 2183.
+// It returns the media type, converted to lower case, and a map
+// of its parameters, keyed by lower case attribute name.  If v
+// cannot be parsed, ParseMediaType returns "" and nil.
+func ParseMediaType(v string) (mediatype string, params map[string]string) {
+	i := strings.Index(v, ";")
+	if i < 0 {
+		i = len(v)
+	}
+	mediatype = strings.ToLower(strings.TrimSpace(v[0:i]))
+	if mediatype == "" {
+		return "", nil
+	}
+	params = make(map[string]string)
+	v = v[i:]
+	for {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			break
+		}
+		if v[0] != ';' {
+			return "", nil
+		}
+		v = strings.TrimSpace(v[1:])
+		if v == "" {
+			// Tolerate a trailing semicolon.
+			break
+		}
+		var key, value string
+		key, v = consumeToken(v)
+		if key == "" {
+			return "", nil
+		}
+		v = strings.TrimSpace(v)
+		if v == "" || v[0] != '=' {
+			return "", nil
+		}
+		value, v = consumeValue(strings.TrimSpace(v[1:]))
+		key = strings.ToLower(key)
+		if _, dup := params[key]; dup {
+			return "", nil
+		}
+		params[key] = value
+	}
+	return mediatype, params
+}
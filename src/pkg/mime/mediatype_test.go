@@ -0,0 +1,46 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Tests for mediatype.go
+
+package mime
+
+import (
+	"reflect"
+	"testing"
+)
+
+type mediaTypeTest struct {
+	in     string
+	mt     string
+	params map[string]string
+}
+
+var mediaTypeTests = []mediaTypeTest{
+	mediaTypeTest{"text/html", "text/html", map[string]string{}},
+	mediaTypeTest{"Text/HTML; Charset=UTF-8", "text/html", map[string]string{"charset": "UTF-8"}},
+	mediaTypeTest{
+		`multipart/form-data; boundary="--a;b\"c"`,
+		"multipart/form-data",
+		map[string]string{"boundary": `--a;b"c`},
+	},
+	mediaTypeTest{
+		`form-data; name="file"; filename="a b.txt";`,
+		"form-data",
+		map[string]string{"name": "file", "filename": "a b.txt"},
+	},
+	mediaTypeTest{"", "", nil},
+	mediaTypeTest{"text/html; charset", "", nil},
+	mediaTypeTest{`text/html; charset="utf-8`, "", nil},
+	mediaTypeTest{"text/html; a=1; A=2", "", nil},
+}
+
+func TestParseMediaType(t *testing.T) {
+	for _, tt := range mediaTypeTests {
+		mt, params := ParseMediaType(tt.in)
+		if mt != tt.mt || !reflect.DeepEqual(params, tt.params) {
+			t.Errorf("ParseMediaType(%q) = %q, %v; want %q, %v", tt.in, mt, params, tt.mt, tt.params)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package multipart
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// ErrMessageTooLarge is returned by ReadForm if the non-file parts
+// of the message are too large to be held in memory.
+var ErrMessageTooLarge = os.NewError("multipart: message too large")
+
+// The non-file parts of a form may use this many bytes
+// beyond the maxMemory passed to ReadForm.
+const maxValueBytes = 10 << 20
+
+// ReadForm parses an entire multipart message whose parts have
+// a Content-Disposition of "form-data".
+// It stores up to maxMemory bytes of the file parts in memory
+// and the remainder on disk in temporary files.
+func (r *Reader) ReadForm(maxMemory int64) (f *Form, err os.Error) {
+	form := &Form{make(map[string][]string), make(map[string][]*FileHeader)}
+	defer func() {
+		if err != nil {
+			form.RemoveAll()
+		}
+	}()
+
+	maxValue := maxMemory + maxValueBytes
+	for {
+		p, err := r.NextPart()
+		if err == os.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		name := p.FormName()
+		if name == "" {
+			continue
+		}
+		filename := p.FileName()
+
+		var b bytes.Buffer
+
+		if filename == "" {
+			// value, store as string in memory
+			n, err := io.Copyn(&b, p, maxValue+1)
+			if err != nil && err != os.EOF {
+				return nil, err
+			}
+			maxValue -= n
+			if maxValue < 0 {
+				return nil, ErrMessageTooLarge
+			}
+			form.Value[name] = appendString(form.Value[name], b.String())
+			continue
+		}
+
+		// file, store in memory or on disk
+		fh := &FileHeader{
+			Filename: filename,
+			Header:   p.Header,
+		}
+		n, err := io.Copyn(&b, p, maxMemory+1)
+		if err != nil && err != os.EOF {
+			return nil, err
+		}
+		if n > maxMemory {
+			// too big, write to disk and flush buffer
+			file, err := tempFile()
+			if err != nil {
+				return nil, err
+			}
+			_, err = file.Write(b.Bytes())
+			if err == nil {
+				_, err = io.Copy(file, p)
+			}
+			if cerr := file.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				os.Remove(file.Name())
+				return nil, err
+			}
+			fh.tmpfile = file.Name()
+		} else {
+			fh.content = b.Bytes()
+			maxMemory -= n
+		}
+		form.File[name] = appendFileHeader(form.File[name], fh)
+	}
+
+	return form, nil
+}
+
+func appendString(a []string, s string) []string {
+	n := make([]string, len(a)+1)
+	copy(n, a)
+	n[len(a)] = s
+	return n
+}
+
+func appendFileHeader(a []*FileHeader, fh *FileHeader) []*FileHeader {
+	n := make([]*FileHeader, len(a)+1)
+	copy(n, a)
+	n[len(a)] = fh
+	return n
+}
+
+var (
+	tempLock  sync.Mutex
+	tempCount int
+)
+
+// tempFile creates a new file for a large form part in the
+// directory named by $TMPDIR, or /tmp if it is not set.
+func tempFile() (*os.File, os.Error) {
+	dir := os.Getenv("TMPDIR")
+	if dir == "" {
+		dir = "/tmp"
+	}
+	for {
+		tempLock.Lock()
+		tempCount++
+		name := fmt.Sprintf("%s/multipart.%d.%d", dir, os.Getpid(), tempCount)
+		tempLock.Unlock()
+
+		f, err := os.Open(name, os.O_RDWR|os.O_CREAT|os.O_EXCL, 0600)
+		if err == nil {
+			return f, nil
+		}
+		if pe, ok := err.(*os.PathError); !ok || pe.Error != os.EEXIST {
+			return nil, err
+		}
+	}
+	panic("not reached")
+}
+
+// Form is a parsed multipart form.
+// Its File parts are stored either in memory or on disk,
+// and are accessible via the *FileHeader's Open method.
+// Its Value parts are stored as strings.
+// Both are keyed by field name.
+type Form struct {
+	Value map[string][]string
+	File  map[string][]*FileHeader
+}
+
+// RemoveAll removes any temporary files associated with a Form.
+func (f *Form) RemoveAll() os.Error {
+	var err os.Error
+	for _, fhs := range f.File {
+		for _, fh := range fhs {
+			if fh.tmpfile != "" {
+				e := os.Remove(fh.tmpfile)
+				if e != nil && err == nil {
+					err = e
+				}
+			}
+		}
+	}
+	return err
+}
+
+// A FileHeader describes a file part of a multipart request.
+type FileHeader struct {
+	Filename string
+	Header   map[string]string
+
+	content []byte
+	tmpfile string
+}
+
+// Open opens and returns the FileHeader's associated File.
+func (fh *FileHeader) Open() (File, os.Error) {
+	if fh.tmpfile == "" {
+		b := fh.content
+		r := io.NewSectionReader(sliceReaderAt(b), 0, int64(len(b)))
+		return sectionReadCloser{r}, nil
+	}
+	return os.Open(fh.tmpfile, os.O_RDONLY, 0)
+}
+
+// File is an interface to access the file part of a multipart message.
+// Its contents may be either stored in memory or on disk.
+// If stored on disk, the File's underlying concrete type will be an *os.File.
+type File interface {
+	io.Reader
+	io.ReaderAt
+	io.Seeker
+	io.Closer
+}
+
+// helper types to turn a []byte into a File
+
+type sectionReadCloser struct {
+	*io.SectionReader
+}
+
+func (rc sectionReadCloser) Close() os.Error {
+	return nil
+}
+
+type sliceReaderAt []byte
+
+func (r sliceReaderAt) ReadAt(b []byte, off int64) (int, os.Error) {
+	if int(off) >= len(r) || off < 0 {
+		return 0, os.EINVAL
+	}
+	n := copy(b, r[int(off):])
+	if n < len(b) {
+		return n, os.EOF
+	}
+	return n, nil
+}
@@ -0,0 +1,287 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+/*
+The multipart package implements MIME multipart parsing and generation,
+as defined in RFC 2046.  The implementation is sufficient for HTTP
+(RFC 2388) and the multipart bodies generated by popular browsers.
+*/
+package multipart
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"mime"
+	"os"
+	"strings"
+)
+
+// A Reader is an iterator over the parts of a MIME multipart body.
+// Its underlying parser consumes its input as needed.  Seeking
+// isn't supported.
+type Reader struct {
+	buf          *bufio.Reader
+	dashBoundary []byte // "--boundary"
+	currentPart  *Part
+	partsRead    int
+	done         bool // read the final "--boundary--" line
+}
+
+// A Part represents a single part in a multipart body.
+type Part struct {
+	// The headers of the body, if any, with the keys canonicalized
+	// in the same fashion as the http package's request headers.
+	// Repeated headers are joined with commas.
+	Header map[string]string
+
+	mr         *Reader
+	buffer     bytes.Buffer // data of the part that has not been read yet
+	pending    []byte       // trailing newline, unless followed by a boundary
+	lineStart  bool         // next byte from mr.buf starts a line
+	err        os.Error     // os.EOF at the end of the part
+	disp       string       // parsed Content-Disposition type
+	dispParams map[string]string
+}
+
+// NewReader creates a new multipart Reader reading from r using the
+// given MIME boundary.
+func NewReader(r io.Reader, boundary string) *Reader {
+	return &Reader{
+		buf:          bufio.NewReader(r),
+		dashBoundary: []byte("--" + boundary),
+	}
+}
+
+// NextPart returns the next part in the multipart, or os.EOF
+// when there are no more parts.  Any unread data in the previous
+// part is discarded.
+func (r *Reader) NextPart() (*Part, os.Error) {
+	if r.currentPart != nil {
+		if err := r.currentPart.discard(); err != nil {
+			return nil, err
+		}
+		r.currentPart = nil
+	}
+	if r.done {
+		return nil, os.EOF
+	}
+
+	if r.partsRead == 0 {
+		// Skip the preamble, up to the first boundary.
+		lineStart := true
+		for {
+			line, partial, err := r.readLine()
+			if err != nil {
+				return nil, err
+			}
+			if lineStart && !partial {
+				if final, ok := r.isBoundary(line); ok {
+					if final {
+						r.done = true
+						return nil, os.EOF
+					}
+					break
+				}
+			}
+			lineStart = !partial
+		}
+	}
+
+	header, err := readHeader(r.buf)
+	if err != nil {
+		return nil, err
+	}
+	r.partsRead++
+	r.currentPart = &Part{Header: header, mr: r, lineStart: true}
+	return r.currentPart, nil
+}
+
+// readLine reads the next line from the underlying reader.  If the
+// line does not fit in the buffer, readLine returns as much of it as
+// is buffered, with partial set.  Running out of input is an error,
+// except after a boundary line, whose newline is optional at the end
+// of the body (RFC 2046, section 5.1.1).
+func (r *Reader) readLine() (line []byte, partial bool, err os.Error) {
+	line, err = r.buf.ReadSlice('\n')
+	switch err {
+	case nil:
+		return line, false, nil
+	case bufio.ErrBufferFull:
+		line = make([]byte, r.buf.Buffered())
+		if _, err = io.ReadFull(r.buf, line); err != nil {
+			return nil, false, err
+		}
+		return line, true, nil
+	case os.EOF:
+		if _, ok := r.isBoundary(line); ok {
+			return line, false, nil
+		}
+		err = io.ErrUnexpectedEOF
+	}
+	return nil, false, err
+}
+
+// isBoundary reports whether line is a boundary delimiter line,
+// and whether it is the final one, which closes the body.
+func (r *Reader) isBoundary(line []byte) (final, ok bool) {
+	if !bytes.HasPrefix(line, r.dashBoundary) {
+		return false, false
+	}
+	rest := line[len(r.dashBoundary):]
+	if bytes.HasPrefix(rest, []byte("--")) {
+		final = true
+		rest = rest[2:]
+	}
+	// Only linear white space may follow the boundary.
+	for _, c := range rest {
+		if c != ' ' && c != '\t' && c != '\r' && c != '\n' {
+			return false, false
+		}
+	}
+	return final, true
+}
+
+// readHeader reads the MIME headers of a part, up to and including
+// the blank line that separates them from the body.
+func readHeader(b *bufio.Reader) (map[string]string, os.Error) {
+	header := make(map[string]string)
+	var key string
+	for {
+		line, err := b.ReadString('\n')
+		if err != nil {
+			if err == os.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		continuation := line[0] == ' ' || line[0] == '\t'
+		line = strings.TrimSpace(line)
+		if line == "" {
+			break
+		}
+		if continuation && key != "" {
+			// Continuation of the previous header's value.
+			header[key] += " " + line
+			continue
+		}
+		i := strings.Index(line, ":")
+		if i <= 0 {
+			return nil, os.NewError("multipart: malformed header line: " + line)
+		}
+		key = canonicalHeaderKey(strings.TrimSpace(line[0:i]))
+		value := strings.TrimSpace(line[i+1:])
+		if old, present := header[key]; present {
+			value = old + "," + value
+		}
+		header[key] = value
+	}
+	return header, nil
+}
+
+// canonicalHeaderKey returns the canonical format of the header key s,
+// as http.CanonicalHeaderKey does: the first letter and any letter
+// following a hyphen are upper case, the rest lower case.
+func canonicalHeaderKey(s string) string {
+	a := []byte(s)
+	upper := true
+	for i, v := range a {
+		if upper && 'a' <= v && v <= 'z' {
+			a[i] = v + 'A' - 'a'
+		}
+		if !upper && 'A' <= v && v <= 'Z' {
+			a[i] = v + 'a' - 'A'
+		}
+		upper = v == '-'
+	}
+	return string(a)
+}
+
+func (p *Part) parseContentDisposition() {
+	if p.dispParams != nil {
+		return
+	}
+	p.disp, p.dispParams = mime.ParseMediaType(p.Header["Content-Disposition"])
+	if p.dispParams == nil {
+		p.dispParams = make(map[string]string)
+	}
+}
+
+// FormName returns the name parameter if p has a Content-Disposition
+// of type "form-data".  Otherwise it returns the empty string.
+func (p *Part) FormName() string {
+	p.parseContentDisposition()
+	if p.disp != "form-data" {
+		return ""
+	}
+	return p.dispParams["name"]
+}
+
+// FileName returns the filename parameter of the Part's
+// Content-Disposition header, if any.
+func (p *Part) FileName() string {
+	p.parseContentDisposition()
+	return p.dispParams["filename"]
+}
+
+// Read reads the body of a part, after its headers and before the
+// next part (if any) begins.
+func (p *Part) Read(d []byte) (n int, err os.Error) {
+	for p.buffer.Len() < len(d) && p.err == nil {
+		p.err = p.readLine()
+	}
+	if p.buffer.Len() > 0 {
+		return p.buffer.Read(d)
+	}
+	return 0, p.err
+}
+
+// readLine moves the next line of the body into p.buffer.  The
+// newline ending the line is held back in p.pending: if the next
+// line is a boundary, the newline belongs to the boundary instead.
+// At the end of the part, readLine returns os.EOF.
+func (p *Part) readLine() os.Error {
+	line, partial, err := p.mr.readLine()
+	if err != nil {
+		return err
+	}
+	if p.lineStart && !partial {
+		if final, ok := p.mr.isBoundary(line); ok {
+			p.mr.done = final
+			p.pending = nil
+			return os.EOF
+		}
+	}
+	data := line
+	if len(p.pending) > 0 {
+		data = bytes.Add(p.pending, line)
+	}
+	// Hold back the newline, or a carriage return that may
+	// be the start of one when the line continues.
+	k := 0
+	switch {
+	case !partial && bytes.HasSuffix(data, []byte("\r\n")):
+		k = 2
+	case !partial, data[len(data)-1] == '\r':
+		k = 1
+	}
+	p.buffer.Write(data[0 : len(data)-k])
+	p.pending = make([]byte, k)
+	copy(p.pending, data[len(data)-k:])
+	p.lineStart = !partial
+	return nil
+}
+
+// discard reads the rest of the part's body.
+func (p *Part) discard() os.Error {
+	for p.err == nil {
+		p.err = p.readLine()
+		p.buffer.Reset()
+	}
+	p.buffer.Reset()
+	if p.err != os.EOF {
+		return p.err
+	}
+	return nil
+}
@@ -0,0 +1,213 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package multipart
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+const testBody = `This is the preamble.
+--MyBoundary
+Content-Disposition: form-data; name="foo"
+
+bar
+--MyBoundary
+content-disposition: form-data; name="file"; filename="a.txt"
+Content-Type: text/plain
+X-Folded: one
+ two
+
+line one
+line two
+
+--MyBoundary
+Content-Disposition: form-data; name="empty"
+
+--MyBoundary--
+This is the epilogue.
+`
+
+type partTest struct {
+	FormName string
+	FileName string
+	Body     string
+}
+
+var partTests = []partTest{
+	partTest{"foo", "", "bar"},
+	partTest{"file", "a.txt", "line one\r\nline two\r\n"},
+	partTest{"empty", "", ""},
+}
+
+func crlf(s string) string { return strings.Join(strings.Split(s, "\n", 0), "\r\n") }
+
+func TestReader(t *testing.T) {
+	r := NewReader(strings.NewReader(crlf(testBody)), "MyBoundary")
+	for i, tt := range partTests {
+		p, err := r.NextPart()
+		if err != nil {
+			t.Fatalf("part %d: NextPart: %v", i, err)
+		}
+		if name := p.FormName(); name != tt.FormName {
+			t.Errorf("part %d: FormName = %q, want %q", i, name, tt.FormName)
+		}
+		if name := p.FileName(); name != tt.FileName {
+			t.Errorf("part %d: FileName = %q, want %q", i, name, tt.FileName)
+		}
+		b, err := ioutil.ReadAll(p)
+		if err != nil {
+			t.Errorf("part %d: ReadAll: %v", i, err)
+		}
+		if string(b) != tt.Body {
+			t.Errorf("part %d: body = %q, want %q", i, b, tt.Body)
+		}
+		if i == 1 {
+			if h := p.Header["Content-Type"]; h != "text/plain" {
+				t.Errorf("Content-Type = %q, want %q", h, "text/plain")
+			}
+			if h := p.Header["X-Folded"]; h != "one two" {
+				t.Errorf("X-Folded = %q, want %q", h, "one two")
+			}
+		}
+	}
+	if p, err := r.NextPart(); p != nil || err != os.EOF {
+		t.Errorf("NextPart at end = %v, %v; want nil, os.EOF", p, err)
+	}
+}
+
+func TestReaderSkipsUnreadParts(t *testing.T) {
+	r := NewReader(strings.NewReader(crlf(testBody)), "MyBoundary")
+	n := 0
+	for {
+		_, err := r.NextPart()
+		if err == os.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("NextPart: %v", err)
+		}
+		n++
+	}
+	if n != len(partTests) {
+		t.Errorf("read %d parts, want %d", n, len(partTests))
+	}
+}
+
+func TestReaderLongLines(t *testing.T) {
+	// Lines longer than the reader's buffer, one ending in a
+	// carriage return split from its newline.
+	long := strings.Repeat("x", 10000)
+	body := long + "\r" + long + "\r\n" + long
+	msg := "--b\r\n\r\n" + body + "\r\n--b--\r\n"
+	r := NewReader(strings.NewReader(msg), "b")
+	p, err := r.NextPart()
+	if err != nil {
+		t.Fatalf("NextPart: %v", err)
+	}
+	b, err := ioutil.ReadAll(p)
+	if err != nil || string(b) != body {
+		t.Errorf("body has %d bytes, %v; want %d bytes", len(b), err, len(body))
+	}
+}
+
+func TestReaderNoFinalNewline(t *testing.T) {
+	r := NewReader(strings.NewReader("--b\r\n\r\nbody\r\n--b--"), "b")
+	p, err := r.NextPart()
+	if err != nil {
+		t.Fatalf("NextPart: %v", err)
+	}
+	if b, err := ioutil.ReadAll(p); err != nil || string(b) != "body" {
+		t.Errorf("ReadAll = %q, %v; want %q", b, err, "body")
+	}
+	if p, err := r.NextPart(); err != os.EOF {
+		t.Errorf("NextPart = %v, %v; want os.EOF", p, err)
+	}
+}
+
+func TestReaderUnexpectedEOF(t *testing.T) {
+	r := NewReader(strings.NewReader("--b\r\n\r\nunterminated"), "b")
+	p, err := r.NextPart()
+	if err != nil {
+		t.Fatalf("NextPart: %v", err)
+	}
+	if _, err := ioutil.ReadAll(p); err != io.ErrUnexpectedEOF {
+		t.Errorf("ReadAll = %v, want io.ErrUnexpectedEOF", err)
+	}
+}
+
+func TestWriter(t *testing.T) {
+	var b bytes.Buffer
+	w := NewWriter(&b)
+	if err := w.WriteField("key", "val"); err != nil {
+		t.Fatalf("WriteField: %v", err)
+	}
+	f, err := w.CreateFormFile("file", `my "file".txt`)
+	if err != nil {
+		t.Fatalf("CreateFormFile: %v", err)
+	}
+	io.WriteString(f, "contents\r\n--not a boundary\r\n")
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	r := NewReader(&b, w.Boundary())
+	p, err := r.NextPart()
+	if err != nil {
+		t.Fatalf("NextPart: %v", err)
+	}
+	if body, _ := ioutil.ReadAll(p); p.FormName() != "key" || string(body) != "val" {
+		t.Errorf("part 0: %q = %q, want %q = %q", p.FormName(), body, "key", "val")
+	}
+	p, err = r.NextPart()
+	if err != nil {
+		t.Fatalf("NextPart: %v", err)
+	}
+	if name := p.FileName(); name != `my "file".txt` {
+		t.Errorf("FileName = %q", name)
+	}
+	if body, _ := ioutil.ReadAll(p); string(body) != "contents\r\n--not a boundary\r\n" {
+		t.Errorf("part 1 body = %q", body)
+	}
+	if _, err := r.NextPart(); err != os.EOF {
+		t.Errorf("NextPart at end = %v, want os.EOF", err)
+	}
+}
+
+func TestReadForm(t *testing.T) {
+	for _, maxMemory := range []int64{1 << 20, 4} {
+		r := NewReader(strings.NewReader(crlf(testBody)), "MyBoundary")
+		f, err := r.ReadForm(maxMemory)
+		if err != nil {
+			t.Fatalf("ReadForm(%d): %v", maxMemory, err)
+		}
+		if v := f.Value["foo"]; len(v) != 1 || v[0] != "bar" {
+			t.Errorf("Value[foo] = %v", v)
+		}
+		fhs := f.File["file"]
+		if len(fhs) != 1 || fhs[0].Filename != "a.txt" {
+			t.Fatalf("File[file] = %v", fhs)
+		}
+		if maxMemory == 4 && fhs[0].tmpfile == "" {
+			t.Errorf("ReadForm(%d) kept large file in memory", maxMemory)
+		}
+		fd, err := fhs[0].Open()
+		if err != nil {
+			t.Fatalf("Open: %v", err)
+		}
+		b, err := ioutil.ReadAll(fd)
+		fd.Close()
+		if err != nil || string(b) != "line one\r\nline two\r\n" {
+			t.Errorf("file contents = %q, %v", b, err)
+		}
+		if err := f.RemoveAll(); err != nil {
+			t.Errorf("RemoveAll: %v", err)
+		}
+	}
+}
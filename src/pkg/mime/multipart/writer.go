@@ -0,0 +1,152 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package multipart
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"rand"
+	"sort"
+	"time"
+)
+
+// A Writer generates multipart messages.
+type Writer struct {
+	w        io.Writer
+	boundary string
+	lastpart *part
+}
+
+// NewWriter returns a new multipart Writer with a random boundary,
+// writing to w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{
+		w:        w,
+		boundary: randomBoundary(),
+	}
+}
+
+// Boundary returns the Writer's randomly selected boundary string.
+func (w *Writer) Boundary() string {
+	return w.boundary
+}
+
+// FormDataContentType returns the Content-Type for an HTTP
+// multipart/form-data with this Writer's Boundary.
+func (w *Writer) FormDataContentType() string {
+	return "multipart/form-data; boundary=" + w.boundary
+}
+
+func randomBoundary() string {
+	r := rand.New(rand.NewSource(time.Nanoseconds()))
+	var b bytes.Buffer
+	for i := 0; i < 4; i++ {
+		fmt.Fprintf(&b, "%015x", r.Int63()&(1<<60-1))
+	}
+	return b.String()
+}
+
+// CreatePart creates a new multipart section with the provided
+// header.  The previous part, if any, is finished.  The body of
+// the part should be written to the returned io.Writer.
+func (w *Writer) CreatePart(header map[string]string) (io.Writer, os.Error) {
+	if w.lastpart != nil {
+		w.lastpart.close()
+	}
+	var b bytes.Buffer
+	if w.lastpart != nil {
+		fmt.Fprintf(&b, "\r\n--%s\r\n", w.boundary)
+	} else {
+		fmt.Fprintf(&b, "--%s\r\n", w.boundary)
+	}
+	// Write the header in a deterministic order.
+	keys := make([]string, len(header))
+	i := 0
+	for k := range header {
+		keys[i] = k
+		i++
+	}
+	sort.SortStrings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(&b, "%s: %s\r\n", k, header[k])
+	}
+	b.WriteString("\r\n")
+	if _, err := io.Copy(w.w, &b); err != nil {
+		return nil, err
+	}
+	p := &part{mw: w}
+	w.lastpart = p
+	return p, nil
+}
+
+// escapeQuotes escapes the backslashes and double quotes in s
+// so that it can appear in a quoted-string.
+func escapeQuotes(s string) string {
+	var b bytes.Buffer
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' || s[i] == '"' {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// CreateFormFile is a convenience wrapper around CreatePart.  It creates
+// a new form-data header with the provided field name and file name.
+func (w *Writer) CreateFormFile(fieldname, filename string) (io.Writer, os.Error) {
+	h := make(map[string]string)
+	h["Content-Disposition"] = fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+		escapeQuotes(fieldname), escapeQuotes(filename))
+	h["Content-Type"] = "application/octet-stream"
+	return w.CreatePart(h)
+}
+
+// CreateFormField is a convenience wrapper around CreatePart.  It creates
+// a new form-data header with the provided field name.
+func (w *Writer) CreateFormField(fieldname string) (io.Writer, os.Error) {
+	h := make(map[string]string)
+	h["Content-Disposition"] = fmt.Sprintf(`form-data; name="%s"`, escapeQuotes(fieldname))
+	return w.CreatePart(h)
+}
+
+// WriteField is a convenience wrapper around CreateFormField.  It creates
+// and writes a part with the provided name and value.
+func (w *Writer) WriteField(fieldname, value string) os.Error {
+	p, err := w.CreateFormField(fieldname)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(p, value)
+	return err
+}
+
+// Close finishes the multipart message and writes the trailing
+// boundary end line to the output.
+func (w *Writer) Close() os.Error {
+	if w.lastpart != nil {
+		w.lastpart.close()
+		w.lastpart = nil
+	}
+	_, err := io.WriteString(w.w, "\r\n--"+w.boundary+"--\r\n")
+	return err
+}
+
+// A part is the io.Writer returned by CreatePart.
+type part struct {
+	mw     *Writer
+	closed bool
+}
+
+func (p *part) close() { p.closed = true }
+
+func (p *part) Write(d []byte) (n int, err os.Error) {
+	if p.closed {
+		return 0, os.NewError("multipart: can't write to finished part")
+	}
+	return p.mw.w.Write(d)
+}
@@ -15,9 +15,12 @@
 package net
 
 import (
+	"io"
 	"once"
 	"os"
 	"rand"
+	"sort"
+	"sync"
 	"time"
 )
 
@@ -41,20 +44,27 @@ const noSuchHost = "no such host"
 
 // Send a request on the connection and hope for a reply.
 // Up to cfg.attempts attempts.
-func _Exchange(cfg *_DNS_Config, c Conn, name string) (m *_DNS_Msg, err os.Error) {
+// On a TCP connection, each message is preceded by its
+// length as a 2-byte integer.
+func _Exchange(cfg *_DNS_Config, c Conn, name string, qtype uint16) (m *_DNS_Msg, err os.Error) {
 	if len(name) >= 256 {
 		return nil, &DNSError{"name too long", name, ""}
 	}
 	out := new(_DNS_Msg)
 	out.id = uint16(rand.Int()) ^ uint16(time.Nanoseconds())
 	out.question = []_DNS_Question{
-		_DNS_Question{name, _DNS_TypeA, _DNS_ClassINET},
+		_DNS_Question{name, qtype, _DNS_ClassINET},
 	}
 	out.recursion_desired = true
 	msg, ok := out.Pack()
 	if !ok {
 		return nil, &DNSError{"internal error - cannot pack message", name, ""}
 	}
+	_, stream := c.(*TCPConn)
+	if stream {
+		n := len(msg)
+		msg = bytesAdd([]byte{byte(n >> 8), byte(n)}, msg)
+	}
 
 	for attempt := 0; attempt < cfg.attempts; attempt++ {
 		n, err := c.Write(msg)
@@ -64,8 +74,14 @@ func _Exchange(cfg *_DNS_Config, c Conn, name string) (m *_DNS_Msg, err os.Error
 
 		c.SetReadTimeout(int64(cfg.timeout) * 1e9) // nanoseconds
 
-		buf := make([]byte, 2000) // More than enough.
-		n, err = c.Read(buf)
+		var buf []byte
+		if stream {
+			buf, err = readStreamMsg(c)
+		} else {
+			buf = make([]byte, 2000) // More than enough.
+			n, err = c.Read(buf)
+			buf = buf[0:n]
+		}
 		if isEAGAIN(err) {
 			err = nil
 			continue
@@ -73,7 +89,6 @@ func _Exchange(cfg *_DNS_Config, c Conn, name string) (m *_DNS_Msg, err os.Error
 		if err != nil {
 			return nil, err
 		}
-		buf = buf[0:n]
 		in := new(_DNS_Msg)
 		if !in.Unpack(buf) || in.id != out.id {
 			continue
@@ -87,21 +102,42 @@ func _Exchange(cfg *_DNS_Config, c Conn, name string) (m *_DNS_Msg, err os.Error
 	return nil, &DNSError{"no answer from server", name, server}
 }
 
+// Read a length-prefixed DNS message from a TCP connection.
+func readStreamMsg(c Conn) ([]byte, os.Error) {
+	var l [2]byte
+	if _, err := io.ReadFull(c, l[0:]); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, int(l[0])<<8|int(l[1]))
+	if _, err := io.ReadFull(c, buf); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// Return a new slice holding the contents of a followed by b.
+func bytesAdd(a, b []byte) []byte {
+	n := make([]byte, len(a)+len(b))
+	copy(n, a)
+	copy(n[len(a):], b)
+	return n
+}
+
 
 // Find answer for name in dns message.
 // On return, if err == nil, addrs != nil.
-func answer(name, server string, dns *_DNS_Msg) (addrs []string, err *DNSError) {
-	addrs = make([]string, 0, len(dns.answer))
+func answer(name, server string, dns *_DNS_Msg, qtype uint16) (cname string, addrs []_DNS_RR, err *DNSError) {
+	addrs = make([]_DNS_RR, 0, len(dns.answer))
 
 	if dns.rcode == _DNS_RcodeNameError && dns.recursion_available {
-		return nil, &DNSError{noSuchHost, name, ""}
+		return "", nil, &DNSError{noSuchHost, name, ""}
 	}
 	if dns.rcode != _DNS_RcodeSuccess {
 		// None of the error codes make sense
 		// for the query we sent.  If we didn't get
 		// a name error and we didn't get success,
 		// the server is behaving incorrectly.
-		return nil, &DNSError{"server misbehaving", name, server}
+		return "", nil, &DNSError{"server misbehaving", name, server}
 	}
 
 	// Look for the name.
@@ -114,14 +150,17 @@ Cname:
 		addrs = addrs[0:0]
 		for i := 0; i < len(dns.answer); i++ {
 			rr := dns.answer[i]
+			if _, ok := rr.(*_DNS_RR_Header); ok {
+				// unknown or malformed record
+				continue
+			}
 			h := rr.Header()
 			if h.Class == _DNS_ClassINET && h.Name == name {
 				switch h.Rrtype {
-				case _DNS_TypeA:
+				case qtype:
 					n := len(addrs)
-					a := rr.(*_DNS_RR_A).A
 					addrs = addrs[0 : n+1]
-					addrs[n] = IPv4(byte(a>>24), byte(a>>16), byte(a>>8), byte(a)).String()
+					addrs[n] = rr
 				case _DNS_TypeCNAME:
 					// redirect to cname
 					name = rr.(*_DNS_RR_CNAME).Cname
@@ -130,41 +169,63 @@ Cname:
 			}
 		}
 		if len(addrs) == 0 {
-			return nil, &DNSError{noSuchHost, name, server}
+			return "", nil, &DNSError{noSuchHost, name, server}
 		}
-		return addrs, nil
+		return name, addrs, nil
 	}
 
-	return nil, &DNSError{"too many redirects", name, server}
+	return "", nil, &DNSError{"too many redirects", name, server}
+}
+
+// Send a query for name to server over the given network,
+// "udp" or "tcp".
+func exchange(cfg *_DNS_Config, network, server, name string, qtype uint16) (*_DNS_Msg, os.Error) {
+	// Calling Dial here is scary -- we have to be sure
+	// not to dial a name that will require a DNS lookup,
+	// or Dial will call back here to translate it.
+	// The DNS config parser has already checked that
+	// all the cfg.servers[i] are IP addresses, which
+	// Dial will use without a DNS lookup.
+	c, err := Dial(network, "", server)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+	return _Exchange(cfg, c, name, qtype)
+}
+
+// Number of queries sent, for rotating among servers.
+var queries struct {
+	sync.Mutex
+	n int
 }
 
 // Do a lookup for a single name, which must be rooted
 // (otherwise answer will not find the answers).
-func tryOneName(cfg *_DNS_Config, name string) (addrs []string, err os.Error) {
+func tryOneName(cfg *_DNS_Config, name string, qtype uint16) (cname string, addrs []_DNS_RR, err os.Error) {
 	if len(cfg.servers) == 0 {
-		return nil, &DNSError{"no DNS servers", name, ""}
+		return "", nil, &DNSError{"no DNS servers", name, ""}
+	}
+	start := 0
+	if cfg.rotate {
+		queries.Lock()
+		start = queries.n
+		queries.n++
+		queries.Unlock()
 	}
 	for i := 0; i < len(cfg.servers); i++ {
-		// Calling Dial here is scary -- we have to be sure
-		// not to dial a name that will require a DNS lookup,
-		// or Dial will call back here to translate it.
-		// The DNS config parser has already checked that
-		// all the cfg.servers[i] are IP addresses, which
-		// Dial will use without a DNS lookup.
-		server := cfg.servers[i] + ":53"
-		c, cerr := Dial("udp", "", server)
-		if cerr != nil {
-			err = cerr
-			continue
+		server := cfg.servers[(start+i)%len(cfg.servers)] + ":53"
+		msg, merr := exchange(cfg, "udp", server, name, qtype)
+		if merr == nil && msg.truncated {
+			// The answer did not fit in a UDP packet.
+			msg, merr = exchange(cfg, "tcp", server, name, qtype)
 		}
-		msg, merr := _Exchange(cfg, c, name)
-		c.Close()
 		if merr != nil {
 			err = merr
 			continue
 		}
 		var dnserr *DNSError
-		addrs, dnserr = answer(name, server, msg)
+		cname, addrs, dnserr = answer(name, server, msg, qtype)
 		if dnserr != nil {
 			err = dnserr
 		} else {
@@ -186,6 +247,7 @@ func isDomainName(s string) bool {
 	// Requirements on DNS name:
 	//	* must not be empty.
 	//	* must be alphanumeric plus - and .
+	//	  (and _, which appears in SRV names like _sip._udp)
 	//	* each of the dot-separated elements must begin
 	//	  and end with a letter or digit.
 	//	  RFC 1035 required the element to begin with a letter,
@@ -205,7 +267,7 @@ func isDomainName(s string) bool {
 		switch {
 		default:
 			return false
-		case 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z':
+		case 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || c == '_':
 			ok = true
 		case '0' <= c && c <= '9':
 			// fine
@@ -226,24 +288,15 @@ func isDomainName(s string) bool {
 	return ok
 }
 
-// LookupHost looks for name using the local hosts file and DNS resolver.
-// It returns the canonical name for the host and an array of that
-// host's addresses.
-func LookupHost(name string) (cname string, addrs []string, err os.Error) {
-	if !isDomainName(name) {
-		return name, nil, &DNSError{"invalid domain name", name, ""}
-	}
+// lookup looks for records of type qtype for name, which must be
+// a valid domain name, using the DNS resolver and its search list.
+// It returns the canonical name and the records found.
+func lookup(name string, qtype uint16) (cname string, addrs []_DNS_RR, err os.Error) {
 	once.Do(loadConfig)
 	if dnserr != nil || cfg == nil {
 		err = dnserr
 		return
 	}
-	// Use entries from /etc/hosts if they match.
-	addrs = lookupStaticHost(name)
-	if len(addrs) > 0 {
-		cname = name
-		return
-	}
 	// If name is rooted (trailing dot) or has enough dots,
 	// try it by itself first.
 	rooted := len(name) > 0 && name[len(name)-1] == '.'
@@ -253,9 +306,8 @@ func LookupHost(name string) (cname string, addrs []string, err os.Error) {
 			rname += "."
 		}
 		// Can try as ordinary name.
-		addrs, err = tryOneName(cfg, rname)
+		cname, addrs, err = tryOneName(cfg, rname, qtype)
 		if err == nil {
-			cname = rname
 			return
 		}
 	}
@@ -269,9 +321,8 @@ func LookupHost(name string) (cname string, addrs []string, err os.Error) {
 		if rname[len(rname)-1] != '.' {
 			rname += "."
 		}
-		addrs, err = tryOneName(cfg, rname)
+		cname, addrs, err = tryOneName(cfg, rname, qtype)
 		if err == nil {
-			cname = rname
 			return
 		}
 	}
@@ -281,10 +332,225 @@ func LookupHost(name string) (cname string, addrs []string, err os.Error) {
 	if !rooted {
 		rname += "."
 	}
-	addrs, err = tryOneName(cfg, rname)
-	if err == nil {
-		cname = rname
+	cname, addrs, err = tryOneName(cfg, rname, qtype)
+	return
+}
+
+// The result of a lookup run in its own goroutine.
+type lookupResult struct {
+	cname string
+	rr    []_DNS_RR
+	err   os.Error
+}
+
+// LookupHost looks for name using the local hosts file and DNS resolver.
+// It returns the canonical name for the host and an array of that
+// host's addresses, IPv4 addresses first.
+func LookupHost(name string) (cname string, addrs []string, err os.Error) {
+	if !isDomainName(name) {
+		return name, nil, &DNSError{"invalid domain name", name, ""}
+	}
+	// Use entries from /etc/hosts if they match.
+	addrs = lookupStaticHost(name)
+	if len(addrs) > 0 {
+		cname = name
 		return
 	}
+	// Send the A and AAAA queries at once, so that a server slow
+	// to answer one does not delay the other.
+	c := make(chan *lookupResult, 1)
+	go func() {
+		r := new(lookupResult)
+		r.cname, r.rr, r.err = lookup(name, _DNS_TypeAAAA)
+		c <- r
+	}()
+	cname, rr, err := lookup(name, _DNS_TypeA)
+	r6 := <-c
+	rr6 := r6.rr
+	if err != nil {
+		if r6.err != nil {
+			return
+		}
+		cname, err = r6.cname, nil
+	}
+	addrs = make([]string, len(rr)+len(rr6))
+	for i, r := range rr {
+		a := r.(*_DNS_RR_A).A
+		addrs[i] = IPv4(byte(a>>24), byte(a>>16), byte(a>>8), byte(a)).String()
+	}
+	for i, r := range rr6 {
+		a := r.(*_DNS_RR_AAAA).AAAA
+		addrs[len(rr)+i] = IP(a[0:]).String()
+	}
+	return
+}
+
+// An MX represents a single DNS MX record.
+type MX struct {
+	Host string
+	Pref uint16
+}
+
+type byPref []*MX
+
+func (s byPref) Len() int           { return len(s) }
+func (s byPref) Less(i, j int) bool { return s[i].Pref < s[j].Pref }
+func (s byPref) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// LookupMX returns the DNS MX records for the given domain name,
+// sorted by preference.
+func LookupMX(name string) (mx []*MX, err os.Error) {
+	if !isDomainName(name) {
+		return nil, &DNSError{"invalid domain name", name, ""}
+	}
+	_, rr, err := lookup(name, _DNS_TypeMX)
+	if err != nil {
+		return
+	}
+	mx = make([]*MX, len(rr))
+	for i, r := range rr {
+		r := r.(*_DNS_RR_MX)
+		mx[i] = &MX{r.Mx, r.Pref}
+	}
+	sort.Sort(byPref(mx))
+	return
+}
+
+// An SRV represents a single DNS SRV record.
+type SRV struct {
+	Target   string
+	Port     uint16
+	Priority uint16
+	Weight   uint16
+}
+
+type byPriorityWeight []*SRV
+
+func (s byPriorityWeight) Len() int { return len(s) }
+func (s byPriorityWeight) Less(i, j int) bool {
+	return s[i].Priority < s[j].Priority ||
+		s[i].Priority == s[j].Priority && s[i].Weight < s[j].Weight
+}
+func (s byPriorityWeight) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+
+// shuffleByWeight shuffles SRV records of equal priority
+// by weight, as described in RFC 2782.
+func shuffleByWeight(addrs []*SRV) {
+	sum := 0
+	for _, addr := range addrs {
+		sum += int(addr.Weight)
+	}
+	for sum > 0 && len(addrs) > 1 {
+		s := 0
+		n := rand.Intn(sum)
+		for i := range addrs {
+			s += int(addrs[i].Weight)
+			if s > n {
+				if i > 0 {
+					t := addrs[i]
+					copy(addrs[1:i+1], addrs[0:i])
+					addrs[0] = t
+				}
+				break
+			}
+		}
+		sum -= int(addrs[0].Weight)
+		addrs = addrs[1:]
+	}
+}
+
+// sortSRV sorts SRV records by priority, ordering records
+// of equal priority by weighted random selection.
+func sortSRV(addrs []*SRV) {
+	sort.Sort(byPriorityWeight(addrs))
+	i := 0
+	for j := 1; j <= len(addrs); j++ {
+		if j == len(addrs) || addrs[i].Priority != addrs[j].Priority {
+			shuffleByWeight(addrs[i:j])
+			i = j
+		}
+	}
+}
+
+// LookupSRV tries to resolve an SRV query of the given service,
+// protocol, and domain name, as specified in RFC 2782.  In most cases
+// the proto argument can be the same as the corresponding
+// Addr.Network().  The returned records are sorted by priority
+// and randomized by weight within a priority.
+func LookupSRV(service, proto, name string) (cname string, addrs []*SRV, err os.Error) {
+	target := "_" + service + "._" + proto + "." + name
+	if !isDomainName(target) {
+		return "", nil, &DNSError{"invalid domain name", target, ""}
+	}
+	cname, rr, err := lookup(target, _DNS_TypeSRV)
+	if err != nil {
+		return
+	}
+	addrs = make([]*SRV, len(rr))
+	for i, r := range rr {
+		r := r.(*_DNS_RR_SRV)
+		addrs[i] = &SRV{r.Target, r.Port, r.Priority, r.Weight}
+	}
+	sortSRV(addrs)
+	return
+}
+
+// LookupTXT returns the DNS TXT records for the given domain name.
+func LookupTXT(name string) (txt []string, err os.Error) {
+	if !isDomainName(name) {
+		return nil, &DNSError{"invalid domain name", name, ""}
+	}
+	_, rr, err := lookup(name, _DNS_TypeTXT)
+	if err != nil {
+		return
+	}
+	txt = make([]string, len(rr))
+	for i, r := range rr {
+		txt[i] = r.(*_DNS_RR_TXT).Txt
+	}
+	return
+}
+
+// reverseaddr returns the in-addr.arpa. or ip6.arpa. hostname of the IP
+// address addr suitable for rDNS (PTR) record lookup or an error if it fails
+// to parse the IP address.
+func reverseaddr(addr string) (arpa string, err os.Error) {
+	ip := ParseIP(addr)
+	if ip == nil {
+		return "", &DNSError{"unrecognized address", addr, ""}
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return itod(uint(ip4[3])) + "." + itod(uint(ip4[2])) + "." +
+			itod(uint(ip4[1])) + "." + itod(uint(ip4[0])) + ".in-addr.arpa.", nil
+	}
+	// Must be IPv6
+	const hex = "0123456789abcdef"
+	buf := make([]byte, 0, len(ip)*4+len("ip6.arpa."))
+	for i := len(ip) - 1; i >= 0; i-- {
+		n := len(buf)
+		buf = buf[0 : n+4]
+		buf[n] = hex[ip[i]&0xF]
+		buf[n+1] = '.'
+		buf[n+2] = hex[ip[i]>>4]
+		buf[n+3] = '.'
+	}
+	return string(buf) + "ip6.arpa.", nil
+}
+
+// LookupAddr performs a reverse lookup for the given address, returning
+// a list of names mapping to that address.
+func LookupAddr(addr string) (name []string, err os.Error) {
+	arpa, err := reverseaddr(addr)
+	if err != nil {
+		return
+	}
+	_, rr, err := lookup(arpa, _DNS_TypePTR)
+	if err != nil {
+		return
+	}
+	name = make([]string, len(rr))
+	for i, r := range rr {
+		name[i] = r.(*_DNS_RR_PTR).Ptr
+	}
 	return
 }
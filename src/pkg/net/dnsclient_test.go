@@ -0,0 +1,165 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package net
+
+import (
+	"testing"
+)
+
+type revAddrTest struct {
+	addr      string
+	reverse   string
+	errPrefix string
+}
+
+var revAddrTests = []revAddrTest{
+	revAddrTest{"1.2.3.4", "4.3.2.1.in-addr.arpa.", ""},
+	revAddrTest{"245.110.36.114", "114.36.110.245.in-addr.arpa.", ""},
+	revAddrTest{"::ffff:12.34.56.78", "78.56.34.12.in-addr.arpa.", ""},
+	revAddrTest{"::1", "1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.ip6.arpa.", ""},
+	revAddrTest{"1::", "0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.1.0.0.0.ip6.arpa.", ""},
+	revAddrTest{"1234:567::89a:bcde", "e.d.c.b.a.9.8.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.7.6.5.0.4.3.2.1.ip6.arpa.", ""},
+	revAddrTest{"1.2.3", "", "unrecognized address"},
+	revAddrTest{"1.2.3.4.5", "", "unrecognized address"},
+}
+
+func TestReverseAddress(t *testing.T) {
+	for i, tt := range revAddrTests {
+		a, err := reverseaddr(tt.addr)
+		if len(tt.errPrefix) > 0 && err == nil {
+			t.Errorf("#%d: expected %q, got <nil> (error)", i, tt.errPrefix)
+			continue
+		}
+		if len(tt.errPrefix) == 0 && err != nil {
+			t.Errorf("#%d: expected <nil>, got %q (error)", i, err)
+		}
+		if err != nil && err.(*DNSError).Error != tt.errPrefix {
+			t.Errorf("#%d: expected %q, got %q (mismatched error)", i, tt.errPrefix, err.(*DNSError).Error)
+		}
+		if a != tt.reverse {
+			t.Errorf("#%d: expected %q, got %q (reverse address)", i, tt.reverse, a)
+		}
+	}
+}
+
+// A reply to a query for "example.com." with one record of each kind.
+func testReply() *_DNS_Msg {
+	h := func(rrtype uint16) _DNS_RR_Header {
+		return _DNS_RR_Header{Name: "example.com.", Rrtype: rrtype, Class: _DNS_ClassINET, Ttl: 300}
+	}
+	m := new(_DNS_Msg)
+	m.id = 42
+	m.response = true
+	m.recursion_available = true
+	m.question = []_DNS_Question{_DNS_Question{"example.com.", _DNS_TypeMX, _DNS_ClassINET}}
+	m.answer = []_DNS_RR{
+		&_DNS_RR_A{h(_DNS_TypeA), 0x7f000001},
+		&_DNS_RR_AAAA{h(_DNS_TypeAAAA), [16]byte{0x20, 0x01, 0x0d, 0xb8, 15: 1}},
+		&_DNS_RR_MX{h(_DNS_TypeMX), 10, "mail.example.com."},
+		&_DNS_RR_SRV{h(_DNS_TypeSRV), 1, 2, 5269, "xmpp.example.com."},
+		&_DNS_RR_TXT{h(_DNS_TypeTXT), "v=spf1 -all"},
+	}
+	return m
+}
+
+func TestDNSMsgPackUnpack(t *testing.T) {
+	b, ok := testReply().Pack()
+	if !ok {
+		t.Fatalf("Pack failed")
+	}
+	m := new(_DNS_Msg)
+	if !m.Unpack(b) {
+		t.Fatalf("Unpack failed")
+	}
+	if len(m.answer) != 5 {
+		t.Fatalf("Unpack: %d answers, want 5", len(m.answer))
+	}
+	if a := m.answer[0].(*_DNS_RR_A); a.A != 0x7f000001 {
+		t.Errorf("A record %#x, want 0x7f000001", a.A)
+	}
+	if a := m.answer[1].(*_DNS_RR_AAAA); IP(a.AAAA[0:]).String() != "2001:db8::1" {
+		t.Errorf("AAAA record %v, want 2001:db8::1", IP(a.AAAA[0:]))
+	}
+	if mx := m.answer[2].(*_DNS_RR_MX); mx.Pref != 10 || mx.Mx != "mail.example.com." {
+		t.Errorf("MX record %d %q, want 10 mail.example.com.", mx.Pref, mx.Mx)
+	}
+	srv := m.answer[3].(*_DNS_RR_SRV)
+	if srv.Priority != 1 || srv.Weight != 2 || srv.Port != 5269 || srv.Target != "xmpp.example.com." {
+		t.Errorf("SRV record %d %d %d %q, want 1 2 5269 xmpp.example.com.",
+			srv.Priority, srv.Weight, srv.Port, srv.Target)
+	}
+	if txt := m.answer[4].(*_DNS_RR_TXT); txt.Txt != "v=spf1 -all" {
+		t.Errorf("TXT record %q, want %q", txt.Txt, "v=spf1 -all")
+	}
+
+	_, rr, err := answer("example.com.", "", m, _DNS_TypeMX)
+	if err != nil || len(rr) != 1 || rr[0] != m.answer[2] {
+		t.Errorf("answer for MX = %v, %v; want the MX record", rr, err)
+	}
+}
+
+func TestSortSRV(t *testing.T) {
+	addrs := []*SRV{
+		&SRV{"c", 1, 20, 0},
+		&SRV{"a", 1, 10, 5},
+		&SRV{"b", 1, 10, 0},
+		&SRV{"d", 1, 30, 1},
+	}
+	sortSRV(addrs)
+	var got string
+	for _, a := range addrs {
+		got += a.Target
+	}
+	if got != "abcd" {
+		t.Errorf("sortSRV order %q, want %q", got, "abcd")
+	}
+}
+
+// Serve one DNS query over TCP, replying with testReply.
+func serveTCPQuery(t *testing.T, l Listener) {
+	c, err := l.Accept()
+	if err != nil {
+		t.Errorf("Accept: %v", err)
+		return
+	}
+	defer c.Close()
+	q, err := readStreamMsg(c)
+	if err != nil {
+		t.Errorf("readStreamMsg: %v", err)
+		return
+	}
+	in := new(_DNS_Msg)
+	if !in.Unpack(q) {
+		t.Errorf("cannot unpack query")
+		return
+	}
+	out := testReply()
+	out.id = in.id
+	b, _ := out.Pack()
+	c.Write(bytesAdd([]byte{byte(len(b) >> 8), byte(len(b))}, b))
+}
+
+func TestExchangeTCP(t *testing.T) {
+	l, err := Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	defer l.Close()
+	go serveTCPQuery(t, l)
+
+	c, err := Dial("tcp", "", l.Addr().String())
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	defer c.Close()
+	cfg := &_DNS_Config{timeout: 5, attempts: 1}
+	m, err := _Exchange(cfg, c, "example.com.", _DNS_TypeMX)
+	if err != nil {
+		t.Fatalf("_Exchange: %v", err)
+	}
+	if len(m.answer) != 5 {
+		t.Errorf("_Exchange: %d answers, want 5", len(m.answer))
+	}
+}
@@ -90,7 +90,7 @@ func _DNS_ReadConfig() (*_DNS_Config, os.Error) {
 						n = 1
 					}
 					conf.timeout = n
-				case len(s) >= 8 && s[0:9] == "attempts:":
+				case len(s) >= 9 && s[0:9] == "attempts:":
 					n, _, _ := dtoi(s, 9)
 					if n < 1 {
 						n = 1
@@ -50,6 +50,8 @@ const (
 	_DNS_TypeMINFO = 14
 	_DNS_TypeMX    = 15
 	_DNS_TypeTXT   = 16
+	_DNS_TypeAAAA  = 28
+	_DNS_TypeSRV   = 33
 
 	// valid _DNS_Question.qtype only
 	_DNS_TypeAXFR  = 252
@@ -226,6 +228,18 @@ func (rr *_DNS_RR_TXT) Header() *_DNS_RR_Header {
 	return &rr.Hdr
 }
 
+type _DNS_RR_SRV struct {
+	Hdr      _DNS_RR_Header
+	Priority uint16
+	Weight   uint16
+	Port     uint16
+	Target   string "domain-name"
+}
+
+func (rr *_DNS_RR_SRV) Header() *_DNS_RR_Header {
+	return &rr.Hdr
+}
+
 type _DNS_RR_A struct {
 	Hdr _DNS_RR_Header
 	A   uint32 "ipv4"
@@ -233,6 +247,13 @@ type _DNS_RR_A struct {
 
 func (rr *_DNS_RR_A) Header() *_DNS_RR_Header { return &rr.Hdr }
 
+type _DNS_RR_AAAA struct {
+	Hdr  _DNS_RR_Header
+	AAAA [16]byte "ipv6"
+}
+
+func (rr *_DNS_RR_AAAA) Header() *_DNS_RR_Header { return &rr.Hdr }
+
 
 // Packing and unpacking.
 //
@@ -254,8 +275,10 @@ var rr_mk = map[int]func() _DNS_RR{
 	_DNS_TypeNS:    func() _DNS_RR { return new(_DNS_RR_NS) },
 	_DNS_TypePTR:   func() _DNS_RR { return new(_DNS_RR_PTR) },
 	_DNS_TypeSOA:   func() _DNS_RR { return new(_DNS_RR_SOA) },
+	_DNS_TypeSRV:   func() _DNS_RR { return new(_DNS_RR_SRV) },
 	_DNS_TypeTXT:   func() _DNS_RR { return new(_DNS_RR_TXT) },
 	_DNS_TypeA:     func() _DNS_RR { return new(_DNS_RR_A) },
+	_DNS_TypeAAAA:  func() _DNS_RR { return new(_DNS_RR_AAAA) },
 }
 
 // Pack a domain name s into msg[off:].
@@ -363,7 +386,7 @@ Loop:
 
 // TODO(rsc): Move into generic library?
 // Pack a reflect.StructValue into msg.  Struct members can only be uint16, uint32, string,
-// and other (often anonymous) structs.
+// byte arrays, and other (often anonymous) structs.
 func packStructValue(val *reflect.StructValue, msg []byte, off int) (off1 int, ok bool) {
 	for i := 0; i < val.NumField(); i++ {
 		f := val.Type().(*reflect.StructType).Field(i)
@@ -389,8 +412,21 @@ func packStructValue(val *reflect.StructValue, msg []byte, off int) (off1 int, o
 			msg[off] = byte(i >> 24)
 			msg[off+1] = byte(i >> 16)
 			msg[off+2] = byte(i >> 8)
-			msg[off+4] = byte(i)
+			msg[off+3] = byte(i)
 			off += 4
+		case *reflect.ArrayValue:
+			if _, ok := fv.Type().(*reflect.ArrayType).Elem().(*reflect.Uint8Type); !ok {
+				fmt.Fprintf(os.Stderr, "net: dns: unknown packing type %v", f.Type)
+				return len(msg), false
+			}
+			n := fv.Len()
+			if off+n > len(msg) {
+				return len(msg), false
+			}
+			for j := 0; j < n; j++ {
+				msg[off+j] = fv.Elem(j).(*reflect.Uint8Value).Get()
+			}
+			off += n
 		case *reflect.StringValue:
 			// There are multiple string encodings.
 			// The tag distinguishes ordinary strings from domain names.
@@ -456,6 +492,19 @@ func unpackStructValue(val *reflect.StructValue, msg []byte, off int) (off1 int,
 			i := uint32(msg[off])<<24 | uint32(msg[off+1])<<16 | uint32(msg[off+2])<<8 | uint32(msg[off+3])
 			fv.Set(i)
 			off += 4
+		case *reflect.ArrayValue:
+			if _, ok := fv.Type().(*reflect.ArrayType).Elem().(*reflect.Uint8Type); !ok {
+				fmt.Fprintf(os.Stderr, "net: dns: unknown packing type %v", f.Type)
+				return len(msg), false
+			}
+			n := fv.Len()
+			if off+n > len(msg) {
+				return len(msg), false
+			}
+			for j := 0; j < n; j++ {
+				fv.Elem(j).(*reflect.Uint8Value).Set(msg[off+j])
+			}
+			off += n
 		case *reflect.StringValue:
 			var s string
 			switch f.Tag {
@@ -493,8 +542,8 @@ func unpackStruct(any interface{}, msg []byte, off int) (off1 int, ok bool) {
 
 // Generic struct printer.
 // Doesn't care about the string tag "domain-name",
-// but does look for an "ipv4" tag on uint32 variables,
-// printing them as IP addresses.
+// but does look for an "ipv4" tag on uint32 variables
+// and an "ipv6" tag on byte arrays, printing them as IP addresses.
 func printStructValue(val *reflect.StructValue) string {
 	s := "{"
 	for i := 0; i < val.NumField(); i++ {
@@ -511,6 +560,12 @@ func printStructValue(val *reflect.StructValue) string {
 		} else if fv, ok := fval.(*reflect.Uint32Value); ok && f.Tag == "ipv4" {
 			i := fv.Get()
 			s += IPv4(byte(i>>24), byte(i>>16), byte(i>>8), byte(i)).String()
+		} else if fv, ok := fval.(*reflect.ArrayValue); ok && f.Tag == "ipv6" {
+			ip := make(IP, fv.Len())
+			for j := range ip {
+				ip[j] = fv.Elem(j).(*reflect.Uint8Value).Get()
+			}
+			s += ip.String()
 		} else {
 			s += fmt.Sprint(fval.Interface())
 		}
@@ -558,6 +613,18 @@ func unpackRR(msg []byte, off int) (rr _DNS_RR, off1 int, ok bool) {
 	}
 	rr = mk()
 	off, ok = unpackStruct(rr, msg, off0)
+	if txt, isTxt := rr.(*_DNS_RR_TXT); isTxt {
+		// The data of a TXT record may hold several
+		// counted strings; join them.
+		for ok && off < end {
+			n := int(msg[off])
+			if off+1+n > end {
+				break
+			}
+			txt.Txt += string(msg[off+1 : off+1+n])
+			off += 1 + n
+		}
+	}
 	if off != end {
 		return &h, end, true
 	}
@@ -633,13 +700,13 @@ func (dns *_DNS_Msg) Pack() (msg []byte, ok bool) {
 		off, ok = packStruct(&question[i], msg, off)
 	}
 	for i := 0; i < len(answer); i++ {
-		off, ok = packStruct(answer[i], msg, off)
+		off, ok = packRR(answer[i], msg, off)
 	}
 	for i := 0; i < len(ns); i++ {
-		off, ok = packStruct(ns[i], msg, off)
+		off, ok = packRR(ns[i], msg, off)
 	}
 	for i := 0; i < len(extra); i++ {
-		off, ok = packStruct(extra[i], msg, off)
+		off, ok = packRR(extra[i], msg, off)
 	}
 	if !ok {
 		return nil, false
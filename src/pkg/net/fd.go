@@ -19,6 +19,7 @@ type netFD struct {
 	sysmu   sync.Mutex
 	sysref  int
 	closing bool
+	shared  bool // sysfd's file description is shared with an os.File
 
 	// immutable until Close
 	sysfd   int
@@ -295,19 +296,28 @@ func startServer() {
 	pollserver = p
 }
 
-func newFD(fd, family, proto int, net string, laddr, raddr Addr) (f *netFD, err os.Error) {
+// newFD returns a netFD for the socket fd, which it puts into
+// non-blocking mode.  The netFD cannot be used for I/O until
+// its addresses are set with setAddr.
+func newFD(fd, family, proto int, net string) (f *netFD, err os.Error) {
 	once.Do(startServer)
 	if e := syscall.SetNonblock(fd, true); e != 0 {
-		return nil, &OpError{"setnonblock", net, laddr, os.Errno(e)}
+		return nil, &OpError{"setnonblock", net, nil, os.Errno(e)}
 	}
 	f = &netFD{
 		sysfd:  fd,
 		family: family,
 		proto:  proto,
 		net:    net,
-		laddr:  laddr,
-		raddr:  raddr,
 	}
+	f.cr = make(chan *netFD, 1)
+	f.cw = make(chan *netFD, 1)
+	return f, nil
+}
+
+func (fd *netFD) setAddr(laddr, raddr Addr) {
+	fd.laddr = laddr
+	fd.raddr = raddr
 	var ls, rs string
 	if laddr != nil {
 		ls = laddr.String()
@@ -315,10 +325,36 @@ func newFD(fd, family, proto int, net string, laddr, raddr Addr) (f *netFD, err
 	if raddr != nil {
 		rs = raddr.String()
 	}
-	f.sysfile = os.NewFile(fd, net+":"+ls+"->"+rs)
-	f.cr = make(chan *netFD, 1)
-	f.cw = make(chan *netFD, 1)
-	return f, nil
+	fd.sysfile = os.NewFile(fd.sysfd, fd.net+":"+ls+"->"+rs)
+}
+
+// connect connects the socket to ra, waiting for the connection
+// to complete until deadline (nsec since 1970), if it is non-zero.
+func (fd *netFD) connect(ra syscall.Sockaddr, deadline int64) (err os.Error) {
+	e := syscall.Connect(fd.sysfd, ra)
+	if e == syscall.EAGAIN {
+		// A Unix domain socket whose listener's queue is full.
+		// There is nothing to poll for; wait in blocking mode.
+		syscall.SetNonblock(fd.sysfd, false)
+		e = syscall.Connect(fd.sysfd, ra)
+		syscall.SetNonblock(fd.sysfd, true)
+	}
+	if e == syscall.EINPROGRESS {
+		fd.wdeadline = deadline
+		pollserver.WaitWrite(fd)
+		if fd.wdeadline < 0 {
+			return os.Errno(syscall.ETIMEDOUT)
+		}
+		var errno int
+		e, errno = syscall.GetsockoptInt(fd.sysfd, syscall.SOL_SOCKET, syscall.SO_ERROR)
+		if errno != 0 {
+			return os.NewSyscallError("getsockopt", errno)
+		}
+	}
+	if e != 0 {
+		return os.Errno(e)
+	}
+	return nil
 }
 
 // Add a reference to this fd.
@@ -338,7 +374,11 @@ func (fd *netFD) decref() {
 		// the close blocks.  As long as this doesn't happen often, we
 		// can handle the extra OS processes.  Otherwise we'll need to
 		// use the pollserver for Close too.  Sigh.
-		syscall.SetNonblock(fd.sysfd, false)
+		// The mode is left alone if another descriptor, perhaps
+		// in use by another netFD, shares it.
+		if !fd.shared {
+			syscall.SetNonblock(fd.sysfd, false)
+		}
 		fd.sysfile.Close()
 		fd.sysfile = nil
 		fd.sysfd = -1
@@ -489,6 +529,93 @@ func (fd *netFD) WriteTo(p []byte, sa syscall.Sockaddr) (n int, err os.Error) {
 	return
 }
 
+func (fd *netFD) ReadMsg(p []byte, oob []byte) (n, oobn, flags int, sa syscall.Sockaddr, err os.Error) {
+	if fd == nil || fd.sysfile == nil {
+		return 0, 0, 0, nil, os.EINVAL
+	}
+	fd.rio.Lock()
+	defer fd.rio.Unlock()
+	fd.incref()
+	defer fd.decref()
+	if fd.rdeadline_delta > 0 {
+		fd.rdeadline = pollserver.Now() + fd.rdeadline_delta
+	} else {
+		fd.rdeadline = 0
+	}
+	for {
+		var errno int
+		n, oobn, flags, sa, errno = syscall.Recvmsg(fd.sysfd, p, oob, 0)
+		if errno == syscall.EAGAIN && fd.rdeadline >= 0 {
+			pollserver.WaitRead(fd)
+			continue
+		}
+		if errno != 0 {
+			err = &os.PathError{"recvmsg", fd.sysfile.Name(), os.Errno(errno)}
+		}
+		break
+	}
+	return
+}
+
+func (fd *netFD) WriteMsg(p []byte, oob []byte, sa syscall.Sockaddr) (n int, oobn int, err os.Error) {
+	if fd == nil || fd.sysfile == nil {
+		return 0, 0, os.EINVAL
+	}
+	fd.wio.Lock()
+	defer fd.wio.Unlock()
+	fd.incref()
+	defer fd.decref()
+	if fd.wdeadline_delta > 0 {
+		fd.wdeadline = pollserver.Now() + fd.wdeadline_delta
+	} else {
+		fd.wdeadline = 0
+	}
+	err = nil
+	for {
+		errno := syscall.Sendmsg(fd.sysfd, p, oob, sa, 0)
+		if errno == syscall.EAGAIN && fd.wdeadline >= 0 {
+			pollserver.WaitWrite(fd)
+			continue
+		}
+		if errno != 0 {
+			err = &os.PathError{"sendmsg", fd.sysfile.Name(), os.Errno(errno)}
+		}
+		break
+	}
+	if err == nil {
+		n = len(p)
+		oobn = len(oob)
+	}
+	return
+}
+
+func (fd *netFD) dup() (f *os.File, err os.Error) {
+	if fd == nil || fd.sysfile == nil {
+		return nil, os.EINVAL
+	}
+	fd.incref()
+	defer fd.decref()
+	fd.sysmu.Lock()
+	fd.shared = true
+	fd.sysmu.Unlock()
+
+	// See ../syscall/exec.go for description of ForkLock.
+	syscall.ForkLock.RLock()
+	ns, e := syscall.Dup(fd.sysfd)
+	if e != 0 {
+		syscall.ForkLock.RUnlock()
+		return nil, &OpError{"dup", fd.net, fd.laddr, os.Errno(e)}
+	}
+	syscall.CloseOnExec(ns)
+	syscall.ForkLock.RUnlock()
+
+	// The copy shares the open file description, and with it the
+	// O_NONBLOCK flag, with fd.  Making it blocking would leave fd's
+	// own reads and writes blocked outside the pollserver, deaf to
+	// deadlines and Close, so the copy stays non-blocking.
+	return os.NewFile(ns, fd.sysfile.Name()), nil
+}
+
 func (fd *netFD) accept(toAddr func(syscall.Sockaddr) Addr) (nfd *netFD, err os.Error) {
 	if fd == nil || fd.sysfile == nil {
 		return nil, os.EINVAL
@@ -519,9 +646,10 @@ func (fd *netFD) accept(toAddr func(syscall.Sockaddr) Addr) (nfd *netFD, err os.
 	syscall.CloseOnExec(s)
 	syscall.ForkLock.RUnlock()
 
-	if nfd, err = newFD(s, fd.family, fd.proto, fd.net, fd.laddr, toAddr(sa)); err != nil {
+	if nfd, err = newFD(s, fd.family, fd.proto, fd.net); err != nil {
 		syscall.Close(s)
 		return nil, err
 	}
+	nfd.setAddr(fd.laddr, toAddr(sa))
 	return nfd, nil
 }
@@ -0,0 +1,151 @@
+// Copyright 2010 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Conversion between os.File and network connections
+
+package net
+
+import (
+	"os"
+	"syscall"
+)
+
+// newFileFD returns a new netFD for a duplicate of the
+// socket open on f.  The socket type and addresses are
+// discovered from the descriptor itself.  The duplicate shares
+// f's file description, so f too is put into non-blocking mode.
+func newFileFD(f *os.File) (nfd *netFD, err os.Error) {
+	if f == nil {
+		return nil, os.EINVAL
+	}
+	// See ../syscall/exec.go for description of ForkLock.
+	syscall.ForkLock.RLock()
+	fd, errno := syscall.Dup(f.Fd())
+	if errno != 0 {
+		syscall.ForkLock.RUnlock()
+		return nil, os.NewSyscallError("dup", errno)
+	}
+	syscall.CloseOnExec(fd)
+	syscall.ForkLock.RUnlock()
+
+	proto, errno := syscall.GetsockoptInt(fd, syscall.SOL_SOCKET, syscall.SO_TYPE)
+	if errno != 0 {
+		syscall.Close(fd)
+		return nil, os.NewSyscallError("getsockopt", errno)
+	}
+
+	var family int
+	var net string
+	var toAddr func(syscall.Sockaddr) Addr
+	sa, _ := syscall.Getsockname(fd)
+	switch sa.(type) {
+	default:
+		syscall.Close(fd)
+		return nil, os.EINVAL
+	case *syscall.SockaddrInet4, *syscall.SockaddrInet6:
+		family = syscall.AF_INET
+		if _, ok := sa.(*syscall.SockaddrInet6); ok {
+			family = syscall.AF_INET6
+		}
+		switch proto {
+		case syscall.SOCK_STREAM:
+			net, toAddr = "tcp", sockaddrToTCP
+		case syscall.SOCK_DGRAM:
+			net, toAddr = "udp", sockaddrToUDP
+		case syscall.SOCK_RAW:
+			net, toAddr = "ip", sockaddrToIP
+		default:
+			syscall.Close(fd)
+			return nil, os.EINVAL
+		}
+	case *syscall.SockaddrUnix:
+		family = syscall.AF_UNIX
+		switch proto {
+		case syscall.SOCK_STREAM:
+			net, toAddr = "unix", sockaddrToUnix
+		case syscall.SOCK_DGRAM:
+			net, toAddr = "unixgram", sockaddrToUnixgram
+		default:
+			syscall.Close(fd)
+			return nil, os.EINVAL
+		}
+	}
+	laddr := toAddr(sa)
+	sa, _ = syscall.Getpeername(fd)
+	raddr := toAddr(sa)
+
+	if nfd, err = newFD(fd, family, proto, net); err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+	nfd.shared = true
+	nfd.setAddr(laddr, raddr)
+	return nfd, nil
+}
+
+// FileConn returns a copy of the network connection corresponding to
+// the open file f.  It is the caller's responsibility to close f when
+// finished.  Closing c does not affect f, and closing f does not
+// affect c.  Like c, f is left in non-blocking mode.
+func FileConn(f *os.File) (c Conn, err os.Error) {
+	fd, err := newFileFD(f)
+	if err != nil {
+		return nil, err
+	}
+	switch fd.net {
+	case "tcp":
+		return newTCPConn(fd), nil
+	case "udp":
+		return newUDPConn(fd), nil
+	case "ip":
+		return newIPConn(fd), nil
+	case "unix", "unixgram":
+		return newUnixConn(fd), nil
+	}
+	fd.Close()
+	return nil, os.EINVAL
+}
+
+// FileListener returns a copy of the network listener corresponding
+// to the open file f.  It is the caller's responsibility to close l
+// when finished.  Closing l does not affect f, and closing f does not
+// affect l.  Unlike a listener created by ListenUnix, closing a Unix
+// domain listener obtained this way does not remove its socket file.
+// Like l, f is left in non-blocking mode.
+func FileListener(f *os.File) (l Listener, err os.Error) {
+	fd, err := newFileFD(f)
+	if err != nil {
+		return nil, err
+	}
+	switch fd.net {
+	case "tcp":
+		return &TCPListener{fd}, nil
+	case "unix":
+		return &UnixListener{fd, ""}, nil
+	}
+	fd.Close()
+	return nil, os.EINVAL
+}
+
+// FilePacketConn returns a copy of the packet network connection
+// corresponding to the open file f.  It is the caller's
+// responsibility to close f when finished.  Closing c does not affect
+// f, and closing f does not affect c.  Like c, f is left in
+// non-blocking mode.
+func FilePacketConn(f *os.File) (c PacketConn, err os.Error) {
+	fd, err := newFileFD(f)
+	if err != nil {
+		return nil, err
+	}
+	switch fd.net {
+	case "udp":
+		return newUDPConn(fd), nil
+	case "ip":
+		return newIPConn(fd), nil
+	case "unixgram":
+		return newUnixConn(fd), nil
+	}
+	fd.Close()
+	return nil, os.EINVAL
+}
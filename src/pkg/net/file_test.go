@@ -0,0 +1,176 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package net
+
+import (
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+type listenerFile interface {
+	Listener
+	File() (f *os.File, err os.Error)
+}
+
+type packetConnFile interface {
+	PacketConn
+	File() (f *os.File, err os.Error)
+}
+
+type connFile interface {
+	Conn
+	File() (f *os.File, err os.Error)
+}
+
+func testFileListener(t *testing.T, net, laddr string) {
+	l, err := Listen(net, laddr)
+	if err != nil {
+		t.Fatalf("Listen(%q, %q): %v", net, laddr, err)
+	}
+	defer l.Close()
+	f, err := l.(listenerFile).File()
+	if err != nil {
+		t.Fatalf("File failed: %v", err)
+	}
+	l1, err := FileListener(f)
+	f.Close()
+	if err != nil {
+		t.Fatalf("FileListener failed: %v", err)
+	}
+	defer l1.Close()
+	if l1.Addr().String() != l.Addr().String() {
+		t.Errorf("FileListener address = %v, want %v", l1.Addr(), l.Addr())
+	}
+
+	// Connections queued on the original socket
+	// are accepted through the copy.
+	go func() {
+		if c, err := Dial(net, "", l.Addr().String()); err == nil {
+			c.Write([]byte("hello"))
+			c.Close()
+		}
+	}()
+	c, err := l1.Accept()
+	if err != nil {
+		t.Fatalf("Accept failed: %v", err)
+	}
+	defer c.Close()
+	cf, err := c.(connFile).File()
+	if err != nil {
+		t.Fatalf("File failed: %v", err)
+	}
+	c1, err := FileConn(cf)
+	cf.Close()
+	if err != nil {
+		t.Fatalf("FileConn failed: %v", err)
+	}
+	defer c1.Close()
+	var b [16]byte
+	n, err := c1.Read(&b)
+	if err != nil || string(b[0:n]) != "hello" {
+		t.Errorf("Read = %q, %v; want %q", b[0:n], err, "hello")
+	}
+}
+
+func TestFileListener(t *testing.T) {
+	if syscall.OS == "nacl" {
+		return
+	}
+	testFileListener(t, "tcp", "127.0.0.1:0")
+	os.Remove("/tmp/gotest.net.file")
+	testFileListener(t, "unix", "/tmp/gotest.net.file")
+}
+
+func TestFilePacketConn(t *testing.T) {
+	if syscall.OS == "nacl" {
+		return
+	}
+	c, err := ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket: %v", err)
+	}
+	defer c.Close()
+	f, err := c.(packetConnFile).File()
+	if err != nil {
+		t.Fatalf("File failed: %v", err)
+	}
+	c1, err := FilePacketConn(f)
+	f.Close()
+	if err != nil {
+		t.Fatalf("FilePacketConn failed: %v", err)
+	}
+	defer c1.Close()
+	if c1.LocalAddr().String() != c.LocalAddr().String() {
+		t.Errorf("FilePacketConn address = %v, want %v", c1.LocalAddr(), c.LocalAddr())
+	}
+	if _, err := c.WriteTo([]byte("hello"), c.LocalAddr()); err != nil {
+		t.Fatalf("WriteTo failed: %v", err)
+	}
+	var b [16]byte
+	n, _, err := c1.ReadFrom(&b)
+	if err != nil || string(b[0:n]) != "hello" {
+		t.Errorf("ReadFrom = %q, %v; want %q", b[0:n], err, "hello")
+	}
+}
+
+// readTimeout checks that a read on c with a short deadline
+// times out rather than blocking.
+func readTimeout(t *testing.T, what string, c Conn) {
+	c.SetReadTimeout(1e8) // 100ms
+	t0 := time.Nanoseconds()
+	var b [16]byte
+	n, err := c.Read(&b)
+	if t1 := time.Nanoseconds(); n != 0 || !isEAGAIN(err) || t1-t0 > 1e9 {
+		t.Errorf("%s: Read = %d, %v after %.1fs; want 0, EAGAIN after 0.1s", what, n, err, float64(t1-t0)/1e9)
+	}
+	c.SetReadTimeout(0)
+}
+
+func TestConnAfterFile(t *testing.T) {
+	if syscall.OS == "nacl" {
+		return
+	}
+	l, err := Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	defer l.Close()
+	c, err := Dial("tcp", "", l.Addr().String())
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	s, err := l.Accept()
+	if err != nil {
+		t.Fatalf("Accept: %v", err)
+	}
+	defer s.Close()
+
+	f, err := c.(connFile).File()
+	if err != nil {
+		t.Fatalf("File failed: %v", err)
+	}
+	c1, err := FileConn(f)
+	f.Close()
+	if err != nil {
+		t.Fatalf("FileConn failed: %v", err)
+	}
+	defer c1.Close()
+
+	// The original connection still honors deadlines
+	// and still reads what is sent to it.
+	readTimeout(t, "original", c)
+	s.Write([]byte("hello"))
+	var b [16]byte
+	n, err := c.Read(&b)
+	if err != nil || string(b[0:n]) != "hello" {
+		t.Errorf("Read = %q, %v; want %q", b[0:n], err, "hello")
+	}
+
+	// Closing the original does not leave the copy blocking.
+	c.Close()
+	readTimeout(t, "copy", c1)
+}
@@ -0,0 +1,96 @@
+// Copyright 2010 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// ICMP echo messages, for use over "ip4:icmp" and
+// "ip6:ipv6-icmp" connections.
+
+package net
+
+import "os"
+
+// ICMP message types of echo requests and replies.
+const (
+	ICMPv4EchoReply   = 0
+	ICMPv4EchoRequest = 8
+	ICMPv6EchoRequest = 128
+	ICMPv6EchoReply   = 129
+)
+
+const icmpEchoHeaderLen = 8
+
+var (
+	errShortICMP    = os.ErrorString("short ICMP message")
+	errNotICMPEcho  = os.ErrorString("not an ICMP echo message")
+	errICMPChecksum = os.ErrorString("invalid ICMP checksum")
+)
+
+// ICMPEcho represents an ICMP echo request or reply message,
+// as described in RFC 792 and RFC 4443.
+type ICMPEcho struct {
+	Type int    // ICMPv4EchoRequest, ICMPv6EchoReply, ...
+	Code int    // always 0 for echo messages
+	ID   int    // identifier, 16 bits
+	Seq  int    // sequence number, 16 bits
+	Data []byte // payload
+}
+
+// Marshal returns the wire format of the message m.  The
+// checksum of ICMPv4 messages is filled in; that of ICMPv6
+// messages depends on the IPv6 pseudo-header and is left for
+// the kernel to compute.
+func (m *ICMPEcho) Marshal() []byte {
+	b := make([]byte, icmpEchoHeaderLen+len(m.Data))
+	b[0] = byte(m.Type)
+	b[1] = byte(m.Code)
+	b[4] = byte(m.ID >> 8)
+	b[5] = byte(m.ID)
+	b[6] = byte(m.Seq >> 8)
+	b[7] = byte(m.Seq)
+	copy(b[icmpEchoHeaderLen:], m.Data)
+	if m.Type < ICMPv6EchoRequest {
+		s := icmpChecksum(b)
+		b[2] = byte(s >> 8)
+		b[3] = byte(s)
+	}
+	return b
+}
+
+// ParseICMPEcho parses b as an ICMP echo request or reply message,
+// verifying the checksum of ICMPv4 messages.
+func ParseICMPEcho(b []byte) (*ICMPEcho, os.Error) {
+	if len(b) < icmpEchoHeaderLen {
+		return nil, errShortICMP
+	}
+	m := new(ICMPEcho)
+	m.Type = int(b[0])
+	m.Code = int(b[1])
+	switch m.Type {
+	case ICMPv4EchoReply, ICMPv4EchoRequest:
+		if icmpChecksum(b) != 0 {
+			return nil, errICMPChecksum
+		}
+	case ICMPv6EchoRequest, ICMPv6EchoReply:
+	default:
+		return nil, errNotICMPEcho
+	}
+	m.ID = int(b[4])<<8 | int(b[5])
+	m.Seq = int(b[6])<<8 | int(b[7])
+	m.Data = make([]byte, len(b)-icmpEchoHeaderLen)
+	copy(m.Data, b[icmpEchoHeaderLen:])
+	return m, nil
+}
+
+// The Internet checksum of RFC 1071.
+func icmpChecksum(b []byte) uint16 {
+	var s uint32
+	for i := 0; i+1 < len(b); i += 2 {
+		s += uint32(b[i])<<8 | uint32(b[i+1])
+	}
+	if len(b)&1 == 1 {
+		s += uint32(b[len(b)-1]) << 8
+	}
+	s = s>>16 + s&0xffff
+	s += s >> 16
+	return ^uint16(s)
+}
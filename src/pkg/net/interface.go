@@ -0,0 +1,133 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Network interface identification
+
+package net
+
+import "os"
+
+var (
+	errInvalidInterface      = os.ErrorString("invalid network interface")
+	errInvalidInterfaceIndex = os.ErrorString("invalid network interface index")
+	errInvalidInterfaceName  = os.ErrorString("invalid network interface name")
+	errNoSuchInterface       = os.ErrorString("no such network interface")
+)
+
+// A HardwareAddr represents a physical hardware address.
+type HardwareAddr []byte
+
+func (a HardwareAddr) String() string {
+	if len(a) == 0 {
+		return ""
+	}
+	buf := make([]byte, 3*len(a)-1)
+	for i, b := range a {
+		if i > 0 {
+			buf[3*i-1] = ':'
+		}
+		buf[3*i] = "0123456789abcdef"[b>>4]
+		buf[3*i+1] = "0123456789abcdef"[b&0xF]
+	}
+	return string(buf)
+}
+
+// An Interface represents a mapping between network interface
+// name and index.  It also represents network interface facility
+// information.
+type Interface struct {
+	Index        int          // positive integer that starts at one, zero is never used
+	MTU          int          // maximum transmission unit
+	Name         string       // e.g., "eth0", "lo"
+	HardwareAddr HardwareAddr // IEEE MAC-48, EUI-48 and EUI-64 form
+	Flags        Flags        // e.g., FlagUp, FlagLoopback, FlagMulticast
+}
+
+type Flags uint
+
+const (
+	FlagUp           Flags = 1 << iota // interface is up
+	FlagBroadcast                      // interface supports broadcast access capability
+	FlagLoopback                       // interface is a loopback interface
+	FlagPointToPoint                   // interface belongs to a point-to-point link
+	FlagMulticast                      // interface supports multicast access capability
+)
+
+var flagNames = []string{
+	"up",
+	"broadcast",
+	"loopback",
+	"pointtopoint",
+	"multicast",
+}
+
+func (f Flags) String() string {
+	s := ""
+	for i, name := range flagNames {
+		if f&(1<<uint(i)) != 0 {
+			if s != "" {
+				s += "|"
+			}
+			s += name
+		}
+	}
+	if s == "" {
+		s = "0"
+	}
+	return s
+}
+
+// Addrs returns the addresses of the interface ifi,
+// each an *IPNet giving the address and its prefix length.
+func (ifi *Interface) Addrs() ([]Addr, os.Error) {
+	if ifi == nil {
+		return nil, errInvalidInterface
+	}
+	return interfaceAddrTable(ifi.Index)
+}
+
+// Interfaces returns a list of the system's network interfaces.
+func Interfaces() ([]Interface, os.Error) {
+	return interfaceTable(0)
+}
+
+// InterfaceAddrs returns a list of the system's network
+// interface addresses, each an *IPNet.
+func InterfaceAddrs() ([]Addr, os.Error) {
+	return interfaceAddrTable(0)
+}
+
+// InterfaceByIndex returns the interface specified by index.
+func InterfaceByIndex(index int) (*Interface, os.Error) {
+	if index <= 0 {
+		return nil, errInvalidInterfaceIndex
+	}
+	ift, err := interfaceTable(index)
+	if err != nil {
+		return nil, err
+	}
+	for i := range ift {
+		if ift[i].Index == index {
+			return &ift[i], nil
+		}
+	}
+	return nil, errNoSuchInterface
+}
+
+// InterfaceByName returns the interface specified by name.
+func InterfaceByName(name string) (*Interface, os.Error) {
+	if name == "" {
+		return nil, errInvalidInterfaceName
+	}
+	ift, err := interfaceTable(0)
+	if err != nil {
+		return nil, err
+	}
+	for i := range ift {
+		if ift[i].Name == name {
+			return &ift[i], nil
+		}
+	}
+	return nil, errNoSuchInterface
+}
@@ -0,0 +1,18 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Network interface identification for Darwin.
+// Not implemented.
+
+package net
+
+import "os"
+
+func interfaceTable(ifindex int) ([]Interface, os.Error) {
+	return nil, os.EINVAL
+}
+
+func interfaceAddrTable(ifindex int) ([]Addr, os.Error) {
+	return nil, os.EINVAL
+}
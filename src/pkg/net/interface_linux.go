@@ -0,0 +1,148 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Network interface identification for Linux,
+// using the kernel's netlink routing socket.
+
+package net
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// If the ifindex is zero, interfaceTable returns mappings of all
+// network interfaces.  Otherwise it returns a mapping of a specific
+// interface.
+func interfaceTable(ifindex int) ([]Interface, os.Error) {
+	tab, e := syscall.NetlinkRIB(syscall.RTM_GETLINK, syscall.AF_UNSPEC)
+	if e != 0 {
+		return nil, os.NewSyscallError("netlink rib", e)
+	}
+	msgs, e := syscall.ParseNetlinkMessage(tab)
+	if e != 0 {
+		return nil, os.NewSyscallError("netlink message", e)
+	}
+
+	var ift []Interface
+	for _, m := range msgs {
+		if m.Header.Type != syscall.RTM_NEWLINK || len(m.Data) < syscall.SizeofIfInfomsg {
+			continue
+		}
+		ifim := (*syscall.IfInfomsg)(unsafe.Pointer(&m.Data[0]))
+		if ifindex != 0 && ifindex != int(ifim.Index) {
+			continue
+		}
+		attrs, e := syscall.ParseNetlinkRouteAttr(&m)
+		if e != 0 {
+			return nil, os.NewSyscallError("netlink routeattr", e)
+		}
+		n := make([]Interface, len(ift)+1)
+		copy(n, ift)
+		n[len(ift)] = newLink(ifim, attrs)
+		ift = n
+	}
+	return ift, nil
+}
+
+func newLink(ifim *syscall.IfInfomsg, attrs []syscall.NetlinkRouteAttr) Interface {
+	ifi := Interface{Index: int(ifim.Index), Flags: linkFlags(ifim.Flags)}
+	for _, a := range attrs {
+		switch a.Attr.Type {
+		case syscall.IFLA_ADDRESS:
+			// Links without one, such as loopback
+			// and tunnels, report all zeros.
+			if !isZeros(IP(a.Value)) {
+				ifi.HardwareAddr = HardwareAddr(a.Value)
+			}
+		case syscall.IFLA_IFNAME:
+			if n := len(a.Value); n > 0 && a.Value[n-1] == 0 {
+				ifi.Name = string(a.Value[0 : n-1])
+			}
+		case syscall.IFLA_MTU:
+			if len(a.Value) >= 4 {
+				ifi.MTU = int(*(*uint32)(unsafe.Pointer(&a.Value[0])))
+			}
+		}
+	}
+	return ifi
+}
+
+func linkFlags(rawFlags uint32) Flags {
+	var f Flags
+	if rawFlags&syscall.IFF_UP != 0 {
+		f |= FlagUp
+	}
+	if rawFlags&syscall.IFF_BROADCAST != 0 {
+		f |= FlagBroadcast
+	}
+	if rawFlags&syscall.IFF_LOOPBACK != 0 {
+		f |= FlagLoopback
+	}
+	if rawFlags&syscall.IFF_POINTOPOINT != 0 {
+		f |= FlagPointToPoint
+	}
+	if rawFlags&syscall.IFF_MULTICAST != 0 {
+		f |= FlagMulticast
+	}
+	return f
+}
+
+// If the ifindex is zero, interfaceAddrTable returns addresses
+// for all network interfaces.  Otherwise it returns addresses
+// for a specific interface.
+func interfaceAddrTable(ifindex int) ([]Addr, os.Error) {
+	tab, e := syscall.NetlinkRIB(syscall.RTM_GETADDR, syscall.AF_UNSPEC)
+	if e != 0 {
+		return nil, os.NewSyscallError("netlink rib", e)
+	}
+	msgs, e := syscall.ParseNetlinkMessage(tab)
+	if e != 0 {
+		return nil, os.NewSyscallError("netlink message", e)
+	}
+
+	var ifat []Addr
+	for _, m := range msgs {
+		if m.Header.Type != syscall.RTM_NEWADDR || len(m.Data) < syscall.SizeofIfAddrmsg {
+			continue
+		}
+		ifam := (*syscall.IfAddrmsg)(unsafe.Pointer(&m.Data[0]))
+		if ifindex != 0 && ifindex != int(ifam.Index) {
+			continue
+		}
+		attrs, e := syscall.ParseNetlinkRouteAttr(&m)
+		if e != 0 {
+			return nil, os.NewSyscallError("netlink routeattr", e)
+		}
+		if ifa := newAddr(ifam, attrs); ifa != nil {
+			n := make([]Addr, len(ifat)+1)
+			copy(n, ifat)
+			n[len(ifat)] = ifa
+			ifat = n
+		}
+	}
+	return ifat, nil
+}
+
+func newAddr(ifam *syscall.IfAddrmsg, attrs []syscall.NetlinkRouteAttr) Addr {
+	// On point-to-point links IFA_ADDRESS is the peer's
+	// address and IFA_LOCAL our own; otherwise they agree
+	// and IFA_LOCAL may be missing.
+	var ip IP
+	for _, a := range attrs {
+		if a.Attr.Type == syscall.IFA_LOCAL || a.Attr.Type == syscall.IFA_ADDRESS && ip == nil {
+			ip = IP(a.Value)
+		}
+	}
+	switch {
+	case ifam.Family == syscall.AF_INET && len(ip) == IPv4len:
+		return &IPNet{IPv4(ip[0], ip[1], ip[2], ip[3]), CIDRMask(int(ifam.Prefixlen), 8*IPv4len)}
+	case ifam.Family == syscall.AF_INET6 && len(ip) == IPv6len:
+		ip6 := make(IP, IPv6len)
+		copy(ip6, ip)
+		return &IPNet{ip6, CIDRMask(int(ifam.Prefixlen), 8*IPv6len)}
+	}
+	return nil
+}
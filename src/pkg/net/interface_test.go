@@ -0,0 +1,90 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package net
+
+import (
+	"syscall"
+	"testing"
+)
+
+func TestInterfaces(t *testing.T) {
+	if syscall.OS != "linux" {
+		return
+	}
+	ift, err := Interfaces()
+	if err != nil {
+		t.Fatalf("Interfaces: %v", err)
+	}
+	haveLoopback := false
+	for _, ifi := range ift {
+		ifxi, err := InterfaceByIndex(ifi.Index)
+		if err != nil || ifxi.Name != ifi.Name {
+			t.Errorf("InterfaceByIndex(%d) = %v, %v; want %s", ifi.Index, ifxi, err, ifi.Name)
+		}
+		ifxn, err := InterfaceByName(ifi.Name)
+		if err != nil || ifxn.Index != ifi.Index {
+			t.Errorf("InterfaceByName(%q) = %v, %v; want index %d", ifi.Name, ifxn, err, ifi.Index)
+		}
+		ifat, err := ifi.Addrs()
+		if err != nil {
+			t.Errorf("%s.Addrs: %v", ifi.Name, err)
+		}
+		if ifi.Flags&FlagLoopback == 0 {
+			continue
+		}
+		haveLoopback = true
+		for _, a := range ifat {
+			if !a.(*IPNet).IP.IsLoopback() {
+				t.Errorf("loopback interface %s has address %v", ifi.Name, a)
+			}
+		}
+	}
+	if !haveLoopback {
+		t.Errorf("Interfaces: no loopback interface in %v", ift)
+	}
+
+	ifat, err := InterfaceAddrs()
+	if err != nil {
+		t.Fatalf("InterfaceAddrs: %v", err)
+	}
+	haveLoopback = false
+	for _, a := range ifat {
+		if a.(*IPNet).IP.Equal(IPv4(127, 0, 0, 1)) {
+			haveLoopback = true
+		}
+	}
+	if !haveLoopback {
+		t.Errorf("InterfaceAddrs = %v, want 127.0.0.1 among them", ifat)
+	}
+
+	if _, err := InterfaceByIndex(0); err == nil {
+		t.Errorf("InterfaceByIndex(0) succeeded")
+	}
+	if _, err := InterfaceByName("no-such-interface"); err == nil {
+		t.Errorf("InterfaceByName(no-such-interface) succeeded")
+	}
+}
+
+type hwAddrTest struct {
+	in  HardwareAddr
+	out string
+}
+
+var hwaddrtests = []hwAddrTest{
+	hwAddrTest{HardwareAddr{}, ""},
+	hwAddrTest{HardwareAddr{0x00, 0x1b, 0x21, 0x0a, 0xbc, 0xde}, "00:1b:21:0a:bc:de"},
+	hwAddrTest{HardwareAddr{0x02, 0x00, 0x5e, 0x10, 0x00, 0x00, 0x00, 0x01}, "02:00:5e:10:00:00:00:01"},
+}
+
+func TestHardwareAddrString(t *testing.T) {
+	for _, tt := range hwaddrtests {
+		if s := tt.in.String(); s != tt.out {
+			t.Errorf("HardwareAddr(%v).String() = %q, want %q", []byte(tt.in), s, tt.out)
+		}
+	}
+	if s := (FlagUp | FlagMulticast).String(); s != "up|multicast" {
+		t.Errorf("Flags.String() = %q, want %q", s, "up|multicast")
+	}
+}
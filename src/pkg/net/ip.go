@@ -12,6 +12,8 @@
 
 package net
 
+import "os"
+
 // IP address lengths (bytes).
 const (
 	IPv4len = 4
@@ -61,7 +63,8 @@ var (
 
 // Well-known IPv6 addresses
 var (
-	IPzero = make(IP, IPv6len) // all zeros
+	IPzero       = make(IP, IPv6len) // all zeros
+	IPv6loopback = IP{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1}
 )
 
 // Is p all zeros?
@@ -139,6 +142,40 @@ func (ip IP) Mask(mask IPMask) IP {
 	return out
 }
 
+// Equal returns true if ip and x are the same IP address.
+// An IPv4 address and the same address in 16-byte form
+// are considered to be equal.
+func (ip IP) Equal(x IP) bool {
+	a, b := ip.To16(), x.To16()
+	if a == nil || b == nil {
+		return false
+	}
+	for i := 0; i < IPv6len; i++ {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// IsLoopback returns true if ip is a loopback address:
+// 127.0.0.0/8 for IPv4 or ::1 for IPv6.
+func (ip IP) IsLoopback() bool {
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4[0] == 127
+	}
+	return ip.Equal(IPv6loopback)
+}
+
+// IsMulticast returns true if ip is a multicast address:
+// 224.0.0.0/4 for IPv4 or ff00::/8 for IPv6.
+func (ip IP) IsMulticast() bool {
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4[0]&0xf0 == 0xe0
+	}
+	return len(ip) == IPv6len && ip[0] == 0xff
+}
+
 // Convert i to decimal string.
 func itod(i uint) string {
 	if i == 0 {
@@ -236,6 +273,9 @@ func simpleMaskLength(mask IPMask) int {
 		}
 	}
 	n := 8 * i
+	if i == len(mask) {
+		return n
+	}
 	v := mask[i]
 	for v&0x80 != 0 {
 		n++
@@ -273,6 +313,119 @@ func (mask IPMask) String() string {
 	return IP(mask).String()
 }
 
+// IPv4Mask returns the IP mask (in 16-byte form) of the
+// IPv4 mask a.b.c.d.
+func IPv4Mask(a, b, c, d byte) IPMask {
+	p := make(IPMask, IPv6len)
+	for i := 0; i < 12; i++ {
+		p[i] = 0xff
+	}
+	p[12] = a
+	p[13] = b
+	p[14] = c
+	p[15] = d
+	return p
+}
+
+// CIDRMask returns an IP mask (in 16-byte form) consisting of
+// ones 1 bits followed by 0s up to a total length of bits bits.
+// The bits argument must be 32 for an IPv4 mask or 128 for an
+// IPv6 mask; an IPv4 mask is embedded in the same way as an
+// IPv4 address.  CIDRMask returns nil if its arguments are invalid.
+func CIDRMask(ones, bits int) IPMask {
+	if bits != 8*IPv4len && bits != 8*IPv6len || ones < 0 || ones > bits {
+		return nil
+	}
+	if bits == 8*IPv4len {
+		ones += 8 * (IPv6len - IPv4len)
+	}
+	p := make(IPMask, IPv6len)
+	for i := 0; i < IPv6len; i++ {
+		switch {
+		case ones >= 8:
+			p[i] = 0xff
+			ones -= 8
+		case ones > 0:
+			p[i] = ^byte(0xff >> uint(ones))
+			ones = 0
+		}
+	}
+	return p
+}
+
+// Size returns the number of leading ones and the total
+// number of bits in the mask.  If the mask is not in the
+// canonical form--ones followed by zeros--Size returns 0, 0.
+func (mask IPMask) Size() (ones, bits int) {
+	if len(mask) != IPv4len && len(mask) != IPv6len {
+		return 0, 0
+	}
+	ones = simpleMaskLength(mask)
+	if ones < 0 {
+		return 0, 0
+	}
+	return ones, 8 * len(mask)
+}
+
+// An IPNet represents an IP network: an address
+// and a mask selecting its network bits.
+type IPNet struct {
+	IP   IP     // network number
+	Mask IPMask // network mask
+}
+
+// Contains returns true if the network n includes ip.
+// IPv4 addresses never belong to IPv6 networks or vice versa.
+func (n *IPNet) Contains(ip IP) bool {
+	nip, mask := n.IP.To4(), n.Mask
+	if nip != nil {
+		if ip = ip.To4(); ip == nil {
+			return false
+		}
+		if len(mask) == IPv6len {
+			mask = mask[12:16]
+		}
+	} else {
+		nip = n.IP
+		if len(ip) != IPv6len || ip.To4() != nil || len(nip) != IPv6len {
+			return false
+		}
+	}
+	if len(mask) != len(nip) {
+		return false
+	}
+	for i := 0; i < len(nip); i++ {
+		if nip[i]&mask[i] != ip[i]&mask[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Network returns the address's network name, "ip+net".
+func (n *IPNet) Network() string { return "ip+net" }
+
+// String returns the CIDR notation of n, such as "10.0.0.0/8"
+// or "2001:db8::/32".  If the mask is not in the canonical form,
+// it is formatted as an IP address, as in "10.0.0.0/255.0.255.0".
+func (n *IPNet) String() string {
+	ones, bits := n.Mask.Size()
+	if n.IP.To4() != nil && bits == 8*IPv6len {
+		if ones < 8*(IPv6len-IPv4len) {
+			bits = 0
+		}
+		ones -= 8 * (IPv6len - IPv4len)
+	}
+	if bits == 0 {
+		mask := IP(n.Mask)
+		if n.IP.To4() != nil && len(mask) == IPv6len {
+			mask = mask[12:16]
+		}
+		return n.IP.String() + "/" + mask.String()
+	}
+	return n.IP.String() + "/" + itod(uint(ones))
+}
+
 // Parse IPv4 address (d.d.d.d).
 func parseIPv4(s string) IP {
 	var p [IPv4len]byte
@@ -421,3 +574,27 @@ func ParseIP(s string) IP {
 	}
 	return parseIPv6(s)
 }
+
+// ParseCIDR parses s as a CIDR notation IP address and mask,
+// like "192.168.100.1/24" or "2001:db8::/32", as defined in
+// RFC 4632 and RFC 4291.  It returns the IP address and the
+// network it implies: for "192.168.100.1/24", the address
+// 192.168.100.1 and the network 192.168.100.0/24.
+func ParseCIDR(s string) (ip IP, n *IPNet, err os.Error) {
+	i := byteIndex(s, '/')
+	if i < 0 {
+		return nil, nil, &AddrError{"invalid CIDR address", s}
+	}
+	addr, mask := s[0:i], s[i+1:]
+	bits := 8 * IPv4len
+	if ip = parseIPv4(addr); ip == nil {
+		bits = 8 * IPv6len
+		ip = parseIPv6(addr)
+	}
+	ones, i, ok := dtoi(mask, 0)
+	if ip == nil || !ok || i != len(mask) || ones > bits {
+		return nil, nil, &AddrError{"invalid CIDR address", s}
+	}
+	m := CIDRMask(ones, bits)
+	return ip, &IPNet{ip.Mask(m), m}, nil
+}
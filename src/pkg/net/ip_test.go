@@ -50,3 +50,156 @@ func TestParseIP(t *testing.T) {
 		}
 	}
 }
+
+type parseCIDRTest struct {
+	in  string
+	ip  IP
+	net *IPNet
+	ok  bool
+}
+
+var parsecidrtests = []parseCIDRTest{
+	parseCIDRTest{"135.104.0.0/32", IPv4(135, 104, 0, 0), &IPNet{IPv4(135, 104, 0, 0), IPv4Mask(255, 255, 255, 255)}, true},
+	parseCIDRTest{"0.0.0.0/24", IPv4(0, 0, 0, 0), &IPNet{IPv4(0, 0, 0, 0), IPv4Mask(255, 255, 255, 0)}, true},
+	parseCIDRTest{"135.104.0.1/24", IPv4(135, 104, 0, 1), &IPNet{IPv4(135, 104, 0, 0), IPv4Mask(255, 255, 255, 0)}, true},
+	parseCIDRTest{"10.1.2.3/8", IPv4(10, 1, 2, 3), &IPNet{IPv4(10, 0, 0, 0), IPv4Mask(255, 0, 0, 0)}, true},
+	parseCIDRTest{"::1/128", ParseIP("::1"), &IPNet{ParseIP("::1"), CIDRMask(128, 128)}, true},
+	parseCIDRTest{"abcd:2345::/127", ParseIP("abcd:2345::"), &IPNet{ParseIP("abcd:2345::"), CIDRMask(127, 128)}, true},
+	parseCIDRTest{"abcd:2345::/65", ParseIP("abcd:2345::"), &IPNet{ParseIP("abcd:2345::"), CIDRMask(65, 128)}, true},
+	parseCIDRTest{"abcd:2345:6789::1/32", ParseIP("abcd:2345:6789::1"), &IPNet{ParseIP("abcd:2345::"), CIDRMask(32, 128)}, true},
+	parseCIDRTest{"192.168.1.1/255.255.255.0", nil, nil, false},
+	parseCIDRTest{"192.168.1.1/35", nil, nil, false},
+	parseCIDRTest{"2001:db8::1/-1", nil, nil, false},
+	parseCIDRTest{"2001:db8::1/129", nil, nil, false},
+	parseCIDRTest{"192.168.1.1", nil, nil, false},
+	parseCIDRTest{"", nil, nil, false},
+}
+
+func TestParseCIDR(t *testing.T) {
+	for _, tt := range parsecidrtests {
+		ip, net, err := ParseCIDR(tt.in)
+		if (err == nil) != tt.ok {
+			t.Errorf("ParseCIDR(%q) error = %v, want ok=%v", tt.in, err, tt.ok)
+			continue
+		}
+		if !tt.ok {
+			continue
+		}
+		if !isEqual(ip, tt.ip) || !isEqual(net.IP, tt.net.IP) || !isEqual(IP(net.Mask), IP(tt.net.Mask)) {
+			t.Errorf("ParseCIDR(%q) = %v, {%v, %v}; want %v, {%v, %v}",
+				tt.in, ip, net.IP, net.Mask, tt.ip, tt.net.IP, tt.net.Mask)
+		}
+	}
+}
+
+type ipNetTest struct {
+	net *IPNet
+	str string
+	in  []string
+	out []string
+}
+
+var ipnettests = []ipNetTest{
+	ipNetTest{
+		&IPNet{IPv4(172, 16, 0, 0), CIDRMask(12, 32)}, "172.16.0.0/12",
+		[]string{"172.16.0.1", "172.31.255.255", "::ffff:172.20.1.1"},
+		[]string{"172.32.0.0", "10.0.0.1", "::ac10:1"},
+	},
+	ipNetTest{
+		&IPNet{IP{192, 168, 0, 0}, IPMask{255, 255, 0, 0}}, "192.168.0.0/16",
+		[]string{"192.168.3.4"},
+		[]string{"192.169.0.0"},
+	},
+	ipNetTest{
+		&IPNet{IPv4(10, 0, 0, 0), IPv4Mask(255, 0, 255, 0)}, "10.0.0.0/255.0.255.0",
+		[]string{"10.1.0.7"},
+		[]string{"10.0.1.0"},
+	},
+	ipNetTest{
+		&IPNet{ParseIP("2001:db8::"), CIDRMask(32, 128)}, "2001:db8::/32",
+		[]string{"2001:db8::1", "2001:db8:ffff::"},
+		[]string{"2001:db9::", "1.2.3.4"},
+	},
+	ipNetTest{
+		&IPNet{IPzero, CIDRMask(0, 128)}, "::/0",
+		[]string{"::1", "ff02::1"},
+		[]string{"127.0.0.1"},
+	},
+}
+
+func TestIPNet(t *testing.T) {
+	for _, tt := range ipnettests {
+		if s := tt.net.String(); s != tt.str {
+			t.Errorf("IPNet.String() = %q, want %q", s, tt.str)
+		}
+		for _, a := range tt.in {
+			if !tt.net.Contains(ParseIP(a)) {
+				t.Errorf("%s.Contains(%s) = false, want true", tt.str, a)
+			}
+		}
+		for _, a := range tt.out {
+			if tt.net.Contains(ParseIP(a)) {
+				t.Errorf("%s.Contains(%s) = true, want false", tt.str, a)
+			}
+		}
+	}
+}
+
+type maskSizeTest struct {
+	mask       IPMask
+	ones, bits int
+}
+
+var masksizetests = []maskSizeTest{
+	maskSizeTest{CIDRMask(0, 32), 96, 128},
+	maskSizeTest{CIDRMask(24, 32), 120, 128},
+	maskSizeTest{CIDRMask(128, 128), 128, 128},
+	maskSizeTest{CIDRMask(65, 128), 65, 128},
+	maskSizeTest{IPMask{255, 255, 240, 0}, 20, 32},
+	maskSizeTest{IPMask{255, 0, 255, 0}, 0, 0},
+	maskSizeTest{IPv4Mask(255, 255, 0, 255), 0, 0},
+}
+
+func TestIPMaskSize(t *testing.T) {
+	for _, tt := range masksizetests {
+		if ones, bits := tt.mask.Size(); ones != tt.ones || bits != tt.bits {
+			t.Errorf("%v.Size() = %d, %d, want %d, %d", tt.mask, ones, bits, tt.ones, tt.bits)
+		}
+	}
+	if m := CIDRMask(33, 32); m != nil {
+		t.Errorf("CIDRMask(33, 32) = %v, want nil", m)
+	}
+}
+
+type ipPredicateTest struct {
+	ip                  string
+	loopback, multicast bool
+}
+
+var ippredicatetests = []ipPredicateTest{
+	ipPredicateTest{"127.0.0.1", true, false},
+	ipPredicateTest{"127.255.0.3", true, false},
+	ipPredicateTest{"::1", true, false},
+	ipPredicateTest{"::ffff:127.0.0.1", true, false},
+	ipPredicateTest{"224.0.0.1", false, true},
+	ipPredicateTest{"239.255.255.250", false, true},
+	ipPredicateTest{"ff02::1", false, true},
+	ipPredicateTest{"10.0.0.1", false, false},
+	ipPredicateTest{"::", false, false},
+	ipPredicateTest{"2001:db8::1", false, false},
+}
+
+func TestIPPredicates(t *testing.T) {
+	for _, tt := range ippredicatetests {
+		ip := ParseIP(tt.ip)
+		if ip.IsLoopback() != tt.loopback {
+			t.Errorf("%s.IsLoopback() = %v, want %v", tt.ip, !tt.loopback, tt.loopback)
+		}
+		if ip.IsMulticast() != tt.multicast {
+			t.Errorf("%s.IsMulticast() = %v, want %v", tt.ip, !tt.multicast, tt.multicast)
+		}
+	}
+	if !IPv4(1, 2, 3, 4).Equal(IP{1, 2, 3, 4}) || IPv4(1, 2, 3, 4).Equal(ParseIP("::102:304")) {
+		t.Errorf("IP.Equal does not treat IPv4 forms alike")
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package net
+
+import (
+	"bytes"
+	"os"
+	"syscall"
+	"testing"
+)
+
+type splitNetProtoTest struct {
+	in    string
+	net   string
+	proto int
+	ok    bool
+}
+
+var splitNetProtoTests = []splitNetProtoTest{
+	splitNetProtoTest{"ip:icmp", "ip", 1, true},
+	splitNetProtoTest{"ip4:1", "ip4", 1, true},
+	splitNetProtoTest{"ip6:ipv6-icmp", "ip6", 58, true},
+	splitNetProtoTest{"ip4", "", 0, false},
+	splitNetProtoTest{"tcp:icmp", "", 0, false},
+	splitNetProtoTest{"ip:nosuchprotocol", "", 0, false},
+}
+
+func TestSplitNetProto(t *testing.T) {
+	for _, tt := range splitNetProtoTests {
+		net, proto, err := splitNetProto(tt.in)
+		if (err == nil) != tt.ok || net != tt.net || proto != tt.proto {
+			t.Errorf("splitNetProto(%q) = %q, %d, %v", tt.in, net, proto, err)
+		}
+	}
+}
+
+func TestICMPEcho(t *testing.T) {
+	m := &ICMPEcho{Type: ICMPv4EchoRequest, ID: 0x1234, Seq: 7, Data: []byte("odd")}
+	b := m.Marshal()
+	if icmpChecksum(b) != 0 {
+		t.Errorf("Marshal(%v) has bad checksum: % x", m, b)
+	}
+	m1, err := ParseICMPEcho(b)
+	if err != nil {
+		t.Fatalf("ParseICMPEcho: %v", err)
+	}
+	if m1.Type != m.Type || m1.ID != m.ID || m1.Seq != m.Seq || !bytes.Equal(m1.Data, m.Data) {
+		t.Errorf("ParseICMPEcho(Marshal(%v)) = %v", m, m1)
+	}
+
+	b[len(b)-1] ^= 1
+	if _, err := ParseICMPEcho(b); err == nil {
+		t.Errorf("ParseICMPEcho accepted a corrupted message")
+	}
+	if _, err := ParseICMPEcho(b[0:4]); err == nil {
+		t.Errorf("ParseICMPEcho accepted a short message")
+	}
+}
+
+func TestICMP(t *testing.T) {
+	if syscall.OS == "nacl" || os.Getuid() != 0 {
+		// Raw sockets need privilege.
+		return
+	}
+	c, err := Dial("ip4:icmp", "", "127.0.0.1")
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	defer c.Close()
+	c.SetReadTimeout(1e9)
+
+	id := os.Getpid() & 0xffff
+	req := &ICMPEcho{Type: ICMPv4EchoRequest, ID: id, Seq: 1, Data: []byte("go ping")}
+	if _, err := c.Write(req.Marshal()); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	// The socket sees our own request too; wait for the reply.
+	b := make([]byte, 512)
+	for {
+		n, err := c.Read(b)
+		if err != nil {
+			t.Fatalf("Read: %v", err)
+		}
+		m, err := ParseICMPEcho(b[0:n])
+		if err != nil || m.Type != ICMPv4EchoReply || m.ID != id {
+			continue
+		}
+		if m.Seq != req.Seq || string(m.Data) != string(req.Data) {
+			t.Errorf("reply = %v, want echo of %v", m, req)
+		}
+		break
+	}
+}
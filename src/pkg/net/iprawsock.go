@@ -0,0 +1,372 @@
+// Copyright 2010 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Raw IP sockets
+
+package net
+
+import (
+	"once"
+	"os"
+	"syscall"
+)
+
+func sockaddrToIP(sa syscall.Sockaddr) Addr {
+	switch sa := sa.(type) {
+	case *syscall.SockaddrInet4:
+		return &IPAddr{&sa.Addr}
+	case *syscall.SockaddrInet6:
+		return &IPAddr{&sa.Addr}
+	}
+	return nil
+}
+
+// IPAddr represents the address of an IP end point.
+type IPAddr struct {
+	IP IP
+}
+
+// Network returns the address's network name, "ip".
+func (a *IPAddr) Network() string { return "ip" }
+
+func (a *IPAddr) String() string { return a.IP.String() }
+
+func (a *IPAddr) family() int {
+	if a == nil || len(a.IP) <= 4 {
+		return syscall.AF_INET
+	}
+	if ip := a.IP.To4(); ip != nil {
+		return syscall.AF_INET
+	}
+	return syscall.AF_INET6
+}
+
+func (a *IPAddr) sockaddr(family int) (syscall.Sockaddr, os.Error) {
+	return ipToSockaddr(family, a.IP, 0)
+}
+
+func (a *IPAddr) toAddr() sockaddr {
+	if a == nil { // nil *IPAddr
+		return nil // nil interface
+	}
+	return a
+}
+
+// ResolveIPAddr parses addr as an IP address and resolves a
+// domain name to a numeric address.
+func ResolveIPAddr(addr string) (*IPAddr, os.Error) {
+	ip := ParseIP(addr)
+	if ip == nil {
+		// Not an IP address.  Try as a DNS name.
+		_, addrs, err := LookupHost(addr)
+		if err != nil {
+			return nil, err
+		}
+		if ip = ParseIP(addrs[0]); ip == nil {
+			// should not happen
+			return nil, &AddrError{"LookupHost returned invalid address", addrs[0]}
+		}
+	}
+	return &IPAddr{ip}, nil
+}
+
+// IPConn is the implementation of the Conn and PacketConn
+// interfaces for raw IP network connections.
+type IPConn struct {
+	fd *netFD
+}
+
+func newIPConn(fd *netFD) *IPConn { return &IPConn{fd} }
+
+func (c *IPConn) ok() bool { return c != nil && c.fd != nil }
+
+// Implementation of the Conn interface - see Conn for documentation.
+
+// Read reads data from a single IP packet on the connection.
+// Any IPv4 header is removed, leaving only the payload.
+//
+// Read can be made to time out and return err == os.EAGAIN
+// after a fixed time limit; see SetTimeout and SetReadTimeout.
+func (c *IPConn) Read(b []byte) (n int, err os.Error) {
+	n, _, err = c.ReadFrom(b)
+	return
+}
+
+// Write writes data to the connection as a single IP packet.
+//
+// Write can be made to time out and return err == os.EAGAIN
+// after a fixed time limit; see SetTimeout and SetReadTimeout.
+func (c *IPConn) Write(b []byte) (n int, err os.Error) {
+	if !c.ok() {
+		return 0, os.EINVAL
+	}
+	return c.fd.Write(b)
+}
+
+// Close closes the IP connection.
+func (c *IPConn) Close() os.Error {
+	if !c.ok() {
+		return os.EINVAL
+	}
+	err := c.fd.Close()
+	c.fd = nil
+	return err
+}
+
+// LocalAddr returns the local network address.
+func (c *IPConn) LocalAddr() Addr {
+	if !c.ok() {
+		return nil
+	}
+	return c.fd.laddr
+}
+
+// RemoteAddr returns the remote network address, a *IPAddr.
+func (c *IPConn) RemoteAddr() Addr {
+	if !c.ok() {
+		return nil
+	}
+	return c.fd.raddr
+}
+
+// SetTimeout sets the read and write deadlines associated
+// with the connection.
+func (c *IPConn) SetTimeout(nsec int64) os.Error {
+	if !c.ok() {
+		return os.EINVAL
+	}
+	return setTimeout(c.fd, nsec)
+}
+
+// SetReadTimeout sets the time (in nanoseconds) that
+// Read will wait for data before returning os.EAGAIN.
+// Setting nsec == 0 (the default) disables the deadline.
+func (c *IPConn) SetReadTimeout(nsec int64) os.Error {
+	if !c.ok() {
+		return os.EINVAL
+	}
+	return setReadTimeout(c.fd, nsec)
+}
+
+// SetWriteTimeout sets the time (in nanoseconds) that
+// Write will wait to send its data before returning os.EAGAIN.
+// Setting nsec == 0 (the default) disables the deadline.
+// Even if write times out, it may return n > 0, indicating that
+// some of the data was successfully written.
+func (c *IPConn) SetWriteTimeout(nsec int64) os.Error {
+	if !c.ok() {
+		return os.EINVAL
+	}
+	return setWriteTimeout(c.fd, nsec)
+}
+
+// SetReadBuffer sets the size of the operating system's
+// receive buffer associated with the connection.
+func (c *IPConn) SetReadBuffer(bytes int) os.Error {
+	if !c.ok() {
+		return os.EINVAL
+	}
+	return setReadBuffer(c.fd, bytes)
+}
+
+// SetWriteBuffer sets the size of the operating system's
+// transmit buffer associated with the connection.
+func (c *IPConn) SetWriteBuffer(bytes int) os.Error {
+	if !c.ok() {
+		return os.EINVAL
+	}
+	return setWriteBuffer(c.fd, bytes)
+}
+
+// File returns a copy of the underlying os.File.  The copy is in
+// non-blocking mode, which it shares with c: reads and writes on f
+// that cannot complete at once fail with os.EAGAIN.
+// It is the caller's responsibility to close f when finished.
+// Closing c does not affect f, and closing f does not affect c.
+func (c *IPConn) File() (f *os.File, err os.Error) {
+	if !c.ok() {
+		return nil, os.EINVAL
+	}
+	return c.fd.dup()
+}
+
+// IP-specific methods.
+
+// ReadFromIP reads an IP packet from c, copying the payload into b.
+// It returns the number of bytes copied into b and the return address
+// that was on the packet.  The kernel delivers IPv4 packets with
+// their header, which ReadFromIP removes.
+//
+// ReadFromIP can be made to time out and return err == os.EAGAIN
+// after a fixed time limit; see SetTimeout and SetReadTimeout.
+func (c *IPConn) ReadFromIP(b []byte) (n int, addr *IPAddr, err os.Error) {
+	if !c.ok() {
+		return 0, nil, os.EINVAL
+	}
+	n, sa, err := c.fd.ReadFrom(b)
+	switch sa := sa.(type) {
+	case *syscall.SockaddrInet4:
+		addr = &IPAddr{&sa.Addr}
+		if n > 0 {
+			hl := int(b[0]&0x0f) << 2
+			if hl > n {
+				hl = n
+			}
+			copy(b, b[hl:n])
+			n -= hl
+		}
+	case *syscall.SockaddrInet6:
+		addr = &IPAddr{&sa.Addr}
+	}
+	return
+}
+
+// ReadFrom reads an IP packet from c, copying the payload into b.
+// It returns the number of bytes copied into b and the return address
+// that was on the packet.
+//
+// ReadFrom can be made to time out and return err == os.EAGAIN
+// after a fixed time limit; see SetTimeout and SetReadTimeout.
+func (c *IPConn) ReadFrom(b []byte) (n int, addr Addr, err os.Error) {
+	if !c.ok() {
+		return 0, nil, os.EINVAL
+	}
+	n, uaddr, err := c.ReadFromIP(b)
+	return n, uaddr.toAddr(), err
+}
+
+// WriteToIP writes an IP packet to addr via c, copying the payload from b.
+//
+// WriteToIP can be made to time out and return err == os.EAGAIN
+// after a fixed time limit; see SetTimeout and SetWriteTimeout.
+// On packet-oriented connections such as IP, write timeouts are rare.
+func (c *IPConn) WriteToIP(b []byte, addr *IPAddr) (n int, err os.Error) {
+	if !c.ok() {
+		return 0, os.EINVAL
+	}
+	sa, err := addr.sockaddr(c.fd.family)
+	if err != nil {
+		return 0, err
+	}
+	return c.fd.WriteTo(b, sa)
+}
+
+// WriteTo writes an IP packet with payload b to addr via c.
+//
+// WriteTo can be made to time out and return err == os.EAGAIN
+// after a fixed time limit; see SetTimeout and SetWriteTimeout.
+// On packet-oriented connections such as IP, write timeouts are rare.
+func (c *IPConn) WriteTo(b []byte, addr Addr) (n int, err os.Error) {
+	if !c.ok() {
+		return 0, os.EINVAL
+	}
+	a, ok := addr.(*IPAddr)
+	if !ok {
+		return 0, &OpError{"writeto", "ip", addr, os.EINVAL}
+	}
+	return c.WriteToIP(b, a)
+}
+
+var protocols map[string]int
+
+func readProtocols() {
+	protocols = make(map[string]int)
+	// Fallbacks for systems without /etc/protocols.
+	protocols["icmp"] = 1
+	protocols["igmp"] = 2
+	protocols["tcp"] = 6
+	protocols["udp"] = 17
+	protocols["ipv6-icmp"] = 58
+	file, err := open("/etc/protocols")
+	if err != nil {
+		return
+	}
+	for line, ok := file.readLine(); ok; line, ok = file.readLine() {
+		// "icmp 1 ICMP # internet control message protocol"
+		if i := byteIndex(line, '#'); i >= 0 {
+			line = line[0:i]
+		}
+		f := getFields(line)
+		if len(f) < 2 {
+			continue
+		}
+		proto, j, ok := dtoi(f[1], 0)
+		if !ok || j != len(f[1]) {
+			continue
+		}
+		protocols[f[0]] = proto
+		for _, alias := range f[2:] {
+			protocols[alias] = proto
+		}
+	}
+	file.close()
+}
+
+// splitNetProto splits a network of the form "ip:icmp" or
+// "ip4:1" into the IP network and the protocol number.
+func splitNetProto(netProto string) (net string, proto int, err os.Error) {
+	i := last(netProto, ':')
+	if i < 0 {
+		return "", 0, UnknownNetworkError(netProto)
+	}
+	net = netProto[0:i]
+	switch net {
+	case "ip", "ip4", "ip6":
+	default:
+		return "", 0, UnknownNetworkError(netProto)
+	}
+	protostr := netProto[i+1:]
+	proto, j, ok := dtoi(protostr, 0)
+	if !ok || j != len(protostr) {
+		once.Do(readProtocols)
+		if proto, ok = protocols[protostr]; !ok {
+			return "", 0, &AddrError{"unknown IP protocol", protostr}
+		}
+	}
+	return net, proto, nil
+}
+
+// DialIP connects to the remote address raddr on the network net,
+// which must be "ip", "ip4", or "ip6" followed by a colon and
+// a protocol name or number, as in "ip4:icmp" or "ip6:58".
+// If laddr is not nil, it is used as the local address for
+// the connection.
+func DialIP(netProto string, laddr, raddr *IPAddr) (c *IPConn, err os.Error) {
+	net, proto, err := splitNetProto(netProto)
+	if err != nil {
+		return nil, err
+	}
+	if raddr == nil {
+		return nil, &OpError{"dial", netProto, nil, errMissingAddress}
+	}
+	fd, e := internetSocket(net, laddr.toAddr(), raddr.toAddr(), 0, syscall.SOCK_RAW, proto, "dial", sockaddrToIP)
+	if e != nil {
+		return nil, e
+	}
+	return newIPConn(fd), nil
+}
+
+// ListenIP listens for incoming IP packets addressed to the
+// local address laddr, or to any local address if laddr is nil.
+// The network net has the same form as for DialIP.  The returned
+// connection c's ReadFrom and WriteTo methods can be used to
+// receive and send IP packets with per-packet addressing.
+func ListenIP(netProto string, laddr *IPAddr) (c *IPConn, err os.Error) {
+	net, proto, err := splitNetProto(netProto)
+	if err != nil {
+		return nil, err
+	}
+	fd, e := internetSocket(net, laddr.toAddr(), nil, 0, syscall.SOCK_RAW, proto, "listen", sockaddrToIP)
+	if e != nil {
+		return nil, e
+	}
+	return newIPConn(fd), nil
+}
+
+func prefixBefore(s string, c byte) string {
+	if i := byteIndex(s, c); i >= 0 {
+		return s[0:i]
+	}
+	return s
+}
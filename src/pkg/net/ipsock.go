@@ -36,9 +36,9 @@ var preferIPv4 = !kernelSupportsIPv6()
 // to take advantage of kernels that have raised the limit.
 func listenBacklog() int { return syscall.SOMAXCONN }
 
-// Internet sockets (TCP, UDP)
+// Internet sockets (TCP, UDP, raw IP)
 
-// A sockaddr represents a TCP or UDP network address that can
+// A sockaddr represents a TCP, UDP or IP network address that can
 // be converted into a syscall.Sockaddr.
 type sockaddr interface {
 	Addr
@@ -46,7 +46,7 @@ type sockaddr interface {
 	family() int
 }
 
-func internetSocket(net string, laddr, raddr sockaddr, proto int, mode string, toAddr func(syscall.Sockaddr) Addr) (fd *netFD, err os.Error) {
+func internetSocket(net string, laddr, raddr sockaddr, deadline int64, sotype, proto int, mode string, toAddr func(syscall.Sockaddr) Addr) (fd *netFD, err os.Error) {
 	// Figure out IP version.
 	// If network has a suffix like "tcp4", obey it.
 	family := syscall.AF_INET6
@@ -58,7 +58,9 @@ func internetSocket(net string, laddr, raddr sockaddr, proto int, mode string, t
 	default:
 		// Otherwise, guess.
 		// If the addresses are IPv4 and we prefer IPv4, use 4; else 6.
-		if preferIPv4 &&
+		// Raw sockets cannot use IPv4-mapped IPv6 addresses,
+		// so they always use 4 for IPv4 addresses.
+		if (preferIPv4 || sotype == syscall.SOCK_RAW) &&
 			(laddr == nil || laddr.family() == syscall.AF_INET) &&
 			(raddr == nil || raddr.family() == syscall.AF_INET) {
 			family = syscall.AF_INET
@@ -76,7 +78,7 @@ func internetSocket(net string, laddr, raddr sockaddr, proto int, mode string, t
 			goto Error
 		}
 	}
-	fd, err = socket(net, family, proto, 0, la, ra, toAddr)
+	fd, err = socket(net, family, sotype, proto, la, ra, deadline, toAddr)
 	if err != nil {
 		goto Error
 	}
@@ -184,27 +186,39 @@ func joinHostPort(host, port string) string {
 
 // Convert "host:port" into IP address and port.
 func hostPortToIP(net, hostport string) (ip IP, iport int, err os.Error) {
+	ips, iport, err := hostPortToIPs(net, hostport)
+	if err != nil {
+		return nil, 0, err
+	}
+	return ips[0], iport, nil
+}
+
+// Convert "host:port" into the list of the host's IP addresses
+// and the port.  If host is empty, the list holds a single nil IP.
+func hostPortToIPs(net, hostport string) (ips []IP, iport int, err os.Error) {
 	host, port, err := splitHostPort(hostport)
 	if err != nil {
 		goto Error
 	}
 
-	var addr IP
+	ips = make([]IP, 1)
 	if host != "" {
 		// Try as an IP address.
-		addr = ParseIP(host)
-		if addr == nil {
+		ips[0] = ParseIP(host)
+		if ips[0] == nil {
 			// Not an IP address.  Try as a DNS name.
 			_, addrs, err1 := LookupHost(host)
 			if err1 != nil {
 				err = err1
 				goto Error
 			}
-			addr = ParseIP(addrs[0])
-			if addr == nil {
-				// should not happen
-				err = &AddrError{"LookupHost returned invalid address", addrs[0]}
-				goto Error
+			ips = make([]IP, len(addrs))
+			for i, a := range addrs {
+				if ips[i] = ParseIP(a); ips[i] == nil {
+					// should not happen
+					err = &AddrError{"LookupHost returned invalid address", a}
+					goto Error
+				}
 			}
 		}
 	}
@@ -221,7 +235,7 @@ func hostPortToIP(net, hostport string) (ip IP, iport int, err os.Error) {
 		goto Error
 	}
 
-	return addr, p, nil
+	return ips, p, nil
 
 Error:
 	return nil, 0, err
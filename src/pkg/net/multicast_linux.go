@@ -0,0 +1,41 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// IPv4 multicast socket options for Linux, which
+// identifies an interface by index (struct ip_mreqn).
+
+package net
+
+import (
+	"os"
+	"syscall"
+)
+
+func ipv4Mreqn(ifi *Interface, ip IP) *syscall.IPMreqn {
+	mreq := new(syscall.IPMreqn)
+	copy(mreq.Multiaddr[0:], ip)
+	if ifi != nil {
+		mreq.Ifindex = int32(ifi.Index)
+	}
+	return mreq
+}
+
+func setIPv4Mreqn(fd *netFD, opt int, mreq *syscall.IPMreqn) os.Error {
+	fd.incref()
+	defer fd.decref()
+	e := syscall.SetsockoptIPMreqn(fd.sysfd, syscall.IPPROTO_IP, opt, mreq)
+	return os.NewSyscallError("setsockopt", e)
+}
+
+func setIPv4MulticastInterface(fd *netFD, ifi *Interface) os.Error {
+	return setIPv4Mreqn(fd, syscall.IP_MULTICAST_IF, ipv4Mreqn(ifi, nil))
+}
+
+func joinIPv4Group(fd *netFD, ifi *Interface, ip IP) os.Error {
+	return setIPv4Mreqn(fd, syscall.IP_ADD_MEMBERSHIP, ipv4Mreqn(ifi, ip))
+}
+
+func leaveIPv4Group(fd *netFD, ifi *Interface, ip IP) os.Error {
+	return setIPv4Mreqn(fd, syscall.IP_DROP_MEMBERSHIP, ipv4Mreqn(ifi, ip))
+}
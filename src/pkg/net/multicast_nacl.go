@@ -0,0 +1,22 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// IPv4 multicast socket options for Native Client.
+// Not implemented.
+
+package net
+
+import "os"
+
+func setIPv4MulticastInterface(fd *netFD, ifi *Interface) os.Error {
+	return os.EINVAL
+}
+
+func joinIPv4Group(fd *netFD, ifi *Interface, ip IP) os.Error {
+	return os.EINVAL
+}
+
+func leaveIPv4Group(fd *netFD, ifi *Interface, ip IP) os.Error {
+	return os.EINVAL
+}
@@ -0,0 +1,91 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package net
+
+import (
+	"syscall"
+	"testing"
+)
+
+func TestMulticastUDP(t *testing.T) {
+	if syscall.OS != "linux" {
+		return
+	}
+	lo, err := InterfaceByName("lo")
+	if err != nil {
+		t.Fatalf("InterfaceByName(lo): %v", err)
+	}
+	group := IPv4(224, 0, 0, 254)
+	c, err := ListenMulticastUDP("udp4", lo, &UDPAddr{group, 0})
+	if err != nil {
+		t.Fatalf("ListenMulticastUDP: %v", err)
+	}
+	defer c.Close()
+	port := c.LocalAddr().(*UDPAddr).Port
+
+	s, err := DialUDP("udp4", nil, &UDPAddr{group, port})
+	if err != nil {
+		t.Fatalf("DialUDP: %v", err)
+	}
+	defer s.Close()
+	if err := s.SetMulticastInterface(lo); err != nil {
+		t.Fatalf("SetMulticastInterface: %v", err)
+	}
+	if err := s.SetMulticastTTL(1); err != nil {
+		t.Fatalf("SetMulticastTTL: %v", err)
+	}
+	if err := s.SetMulticastLoopback(true); err != nil {
+		t.Fatalf("SetMulticastLoopback: %v", err)
+	}
+	if _, err := s.Write([]byte("hello")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	c.SetReadTimeout(1e9)
+	var b [100]byte
+	n, _, err := c.ReadFromUDP(&b)
+	if err != nil {
+		t.Fatalf("ReadFromUDP: %v", err)
+	}
+	if string(b[0:n]) != "hello" {
+		t.Errorf("ReadFromUDP = %q, want %q", b[0:n], "hello")
+	}
+
+	if err := c.LeaveGroup(lo, group); err != nil {
+		t.Errorf("LeaveGroup: %v", err)
+	}
+	if err := c.LeaveGroup(lo, group); err == nil {
+		t.Errorf("second LeaveGroup succeeded")
+	}
+}
+
+func TestMulticastInvalidGroup(t *testing.T) {
+	if _, err := ListenMulticastUDP("udp4", nil, &UDPAddr{IPv4(127, 0, 0, 1), 0}); err == nil {
+		t.Errorf("ListenMulticastUDP on unicast address succeeded")
+	}
+	if _, err := ListenMulticastUDP("udp6", nil, &UDPAddr{IPv4(224, 0, 0, 254), 0}); err == nil {
+		t.Errorf("ListenMulticastUDP on udp6 succeeded")
+	}
+	if _, err := InterfaceByName("no-such-interface"); err == nil {
+		t.Errorf("InterfaceByName(no-such-interface) succeeded")
+	}
+}
+
+func TestUDPBroadcast(t *testing.T) {
+	c, err := ListenUDP("udp4", &UDPAddr{IPv4(127, 0, 0, 1), 0})
+	if err != nil {
+		t.Fatalf("ListenUDP: %v", err)
+	}
+	defer c.Close()
+	if err := c.SetBroadcast(false); err != nil {
+		t.Errorf("SetBroadcast(false): %v", err)
+	}
+	if _, err := c.WriteToUDP([]byte("x"), &UDPAddr{IPv4bcast, 9}); err == nil {
+		t.Errorf("broadcast WriteToUDP succeeded with broadcast disabled")
+	}
+	if err := c.SetBroadcast(true); err != nil {
+		t.Errorf("SetBroadcast(true): %v", err)
+	}
+}
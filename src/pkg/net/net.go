@@ -11,7 +11,10 @@ package net
 //	support for raw IP sockets
 //	support for raw ethernet sockets
 
-import "os"
+import (
+	"os"
+	"time"
+)
 
 // Addr represents a network end point address.
 type Addr interface {
@@ -109,36 +112,31 @@ type Listener interface {
 // for the connection.
 //
 // Known networks are "tcp", "tcp4" (IPv4-only), "tcp6" (IPv6-only),
-// "udp", "udp4" (IPv4-only), and "udp6" (IPv6-only).
+// "udp", "udp4" (IPv4-only), "udp6" (IPv6-only), "ip", "ip4"
+// (IPv4-only), "ip6" (IPv6-only), "unix" and "unixgram".
 //
-// For IP networks, addresses have the form host:port.  If host is
-// a literal IPv6 address, it must be enclosed in square brackets.
+// For TCP and UDP networks, addresses have the form host:port.
+// If host is a literal IPv6 address, it must be enclosed in
+// square brackets.
+//
+// For IP networks, net must be "ip", "ip4" or "ip6" followed by a
+// colon and a protocol number or name, and addresses have the
+// form host.
 //
 // Examples:
 //	Dial("tcp", "", "12.34.56.78:80")
 //	Dial("tcp", "", "google.com:80")
 //	Dial("tcp", "", "[de:ad:be:ef::ca:fe]:80")
 //	Dial("tcp", "127.0.0.1:123", "127.0.0.1:88")
+//	Dial("ip4:icmp", "", "127.0.0.1")
 //
+// For TCP networks, if the host in raddr has several addresses,
+// Dial tries them as described for Dialer.
 func Dial(net, laddr, raddr string) (c Conn, err os.Error) {
 	switch net {
 	case "tcp", "tcp4", "tcp6":
-		var la, ra *TCPAddr
-		if laddr != "" {
-			if la, err = ResolveTCPAddr(laddr); err != nil {
-				goto Error
-			}
-		}
-		if raddr != "" {
-			if ra, err = ResolveTCPAddr(raddr); err != nil {
-				goto Error
-			}
-		}
-		c, err := DialTCP(net, la, ra)
-		if err != nil {
-			return nil, err
-		}
-		return c, nil
+		d := &Dialer{LocalAddr: laddr}
+		return d.Dial(net, raddr)
 	case "udp", "udp4", "upd6":
 		var la, ra *UDPAddr
 		if laddr != "" {
@@ -174,11 +172,242 @@ func Dial(net, laddr, raddr string) (c Conn, err os.Error) {
 		}
 		return c, nil
 	}
+	switch prefixBefore(net, ':') {
+	case "ip", "ip4", "ip6":
+		var la, ra *IPAddr
+		if laddr != "" {
+			if la, err = ResolveIPAddr(laddr); err != nil {
+				goto Error
+			}
+		}
+		if raddr != "" {
+			if ra, err = ResolveIPAddr(raddr); err != nil {
+				goto Error
+			}
+		}
+		c, err := DialIP(net, la, ra)
+		if err != nil {
+			return nil, err
+		}
+		return c, nil
+	}
 	err = UnknownNetworkError(net)
 Error:
 	return nil, &OpError{"dial", net + " " + raddr, nil, err}
 }
 
+// DefaultFallbackDelay is the FallbackDelay used by
+// a Dialer that does not set one.
+const DefaultFallbackDelay = 300e6 // 300ms
+
+// A Dialer contains options for connecting to an address.
+// The zero value for each field is a usable default.
+type Dialer struct {
+	// Timeout is the maximum time, in nanoseconds, that a dial
+	// waits for a connection to complete.  Zero means no timeout.
+	// The time spent looking up the host name is not included.
+	// Timeout applies only to TCP networks; dials on other
+	// networks do not wait in connect and ignore it.
+	Timeout int64
+
+	// FallbackDelay is the time, in nanoseconds, that a dial to a
+	// host with several addresses waits for one connection attempt
+	// before starting the next.  Zero means DefaultFallbackDelay.
+	FallbackDelay int64
+
+	// LocalAddr, if not empty, is the local address of the connection.
+	LocalAddr string
+}
+
+// DialTimeout is like Dial but gives up if the connection has not
+// completed after nsec nanoseconds, returning an error whose
+// underlying error is ETIMEDOUT.  As with Dialer.Timeout, the time
+// limit applies only to TCP networks.
+func DialTimeout(net, raddr string, nsec int64) (c Conn, err os.Error) {
+	d := &Dialer{Timeout: nsec}
+	return d.Dial(net, raddr)
+}
+
+// Dial connects to the address raddr on the network net, as the
+// function Dial does, using the options in d.
+//
+// For TCP networks, if the host in raddr has several addresses,
+// Dial tries each of them, alternating between IPv4 and IPv6
+// addresses.  It starts the next attempt as soon as the previous
+// one fails or after FallbackDelay, whichever comes first, and
+// returns the first connection to succeed, closing any others.
+// If every attempt fails, Dial returns the first error.
+func (d *Dialer) Dial(net, raddr string) (c Conn, err os.Error) {
+	switch net {
+	case "tcp", "tcp4", "tcp6":
+	default:
+		// Unix and packet sockets connect at once,
+		// so there is no Timeout to apply.
+		return Dial(net, d.LocalAddr, raddr)
+	}
+
+	var deadline int64
+	if d.Timeout > 0 {
+		deadline = time.Nanoseconds() + d.Timeout
+	}
+	delay := d.FallbackDelay
+	if delay <= 0 {
+		delay = DefaultFallbackDelay
+	}
+
+	var la *TCPAddr
+	var ras []*TCPAddr
+	var ips []IP
+	var port int
+	if d.LocalAddr != "" {
+		if la, err = ResolveTCPAddr(d.LocalAddr); err != nil {
+			goto Error
+		}
+	}
+	if raddr == "" {
+		return nil, &OpError{"dial", net, nil, errMissingAddress}
+	}
+	if ips, port, err = hostPortToIPs("tcp", raddr); err != nil {
+		goto Error
+	}
+	ras = make([]*TCPAddr, 0, len(ips))
+	for _, ip := range interleave(ips) {
+		if ip != nil {
+			if net == "tcp4" && ip.To4() == nil || net == "tcp6" && ip.To4() != nil {
+				continue
+			}
+		}
+		n := len(ras)
+		ras = ras[0 : n+1]
+		ras[n] = &TCPAddr{ip, port}
+	}
+	if len(ras) == 0 {
+		msg := "no suitable address for host"
+		switch net {
+		case "tcp4":
+			msg = "no IPv4 address for host"
+		case "tcp6":
+			msg = "no IPv6 address for host"
+		}
+		err = &AddrError{msg, raddr}
+		goto Error
+	}
+	if len(ras) == 1 {
+		c, err := dialTCP(net, la, ras[0], deadline)
+		if err != nil {
+			return nil, err
+		}
+		return c, nil
+	}
+	return dialStaggered(net, la, ras, delay, deadline)
+
+Error:
+	return nil, &OpError{"dial", net + " " + raddr, nil, err}
+}
+
+// interleave reorders ips so that IPv4 and IPv6 addresses
+// alternate, starting with the family of the first address
+// and keeping the order of the addresses within each family.
+func interleave(ips []IP) []IP {
+	var v4, v6 []IP
+	for _, ip := range ips {
+		if ip.To4() != nil {
+			v4 = addIP(v4, ip)
+		} else {
+			v6 = addIP(v6, ip)
+		}
+	}
+	if len(ips) > 0 && ips[0].To4() == nil {
+		v4, v6 = v6, v4
+	}
+	out := make([]IP, 0, len(ips))
+	for i := 0; i < len(v4) || i < len(v6); i++ {
+		if i < len(v4) {
+			out = addIP(out, v4[i])
+		}
+		if i < len(v6) {
+			out = addIP(out, v6[i])
+		}
+	}
+	return out
+}
+
+func addIP(ips []IP, ip IP) []IP {
+	n := len(ips)
+	if n == cap(ips) {
+		a := make([]IP, n, 2*n+1)
+		copy(a, ips)
+		ips = a
+	}
+	ips = ips[0 : n+1]
+	ips[n] = ip
+	return ips
+}
+
+type dialResult struct {
+	c   *TCPConn
+	err os.Error
+}
+
+// dialStaggered dials each of ras in turn, starting each attempt
+// when the one before it fails or has been running for delay
+// nanoseconds, and returns the first connection made.
+func dialStaggered(net string, la *TCPAddr, ras []*TCPAddr, delay, deadline int64) (c Conn, err os.Error) {
+	results := make(chan dialResult, len(ras))
+	timer := make(chan int, len(ras))
+	started, running := 0, 0
+	start := func() {
+		ra := ras[started]
+		started++
+		running++
+		go func() {
+			c, err := dialTCP(net, la, ra, deadline)
+			results <- dialResult{c, err}
+		}()
+		if started < len(ras) {
+			n := started
+			go func() {
+				time.Sleep(delay)
+				timer <- n
+			}()
+		}
+	}
+
+	start()
+	for running > 0 {
+		select {
+		case r := <-results:
+			running--
+			if r.err == nil {
+				go closeDials(results, running)
+				return r.c, nil
+			}
+			if err == nil {
+				err = r.err
+			}
+			if started < len(ras) {
+				start()
+			}
+		case n := <-timer:
+			// Ignore the timers of attempts that have
+			// already failed and been replaced.
+			if n == started && started < len(ras) {
+				start()
+			}
+		}
+	}
+	return nil, err
+}
+
+// Close the connections made by the n dials still running.
+func closeDials(results chan dialResult, n int) {
+	for ; n > 0; n-- {
+		if r := <-results; r.err == nil {
+			r.c.Close()
+		}
+	}
+}
+
 // Listen announces on the local network address laddr.
 // The network string net must be a stream-oriented
 // network: "tcp", "tcp4", "tcp6", or "unix".
@@ -214,7 +443,8 @@ func Listen(net, laddr string) (l Listener, err os.Error) {
 
 // ListenPacket announces on the local network address laddr.
 // The network string net must be a packet-oriented network:
-// "udp", "udp4", "udp6", or "unixgram".
+// "udp", "udp4", "udp6", "ip", "ip4", "ip6" or "unixgram".
+// IP networks are followed by a protocol, as for Dial.
 func ListenPacket(net, laddr string) (c PacketConn, err os.Error) {
 	switch net {
 	case "udp", "udp4", "udp6":
@@ -242,6 +472,20 @@ func ListenPacket(net, laddr string) (c PacketConn, err os.Error) {
 		}
 		return c, nil
 	}
+	switch prefixBefore(net, ':') {
+	case "ip", "ip4", "ip6":
+		var la *IPAddr
+		if laddr != "" {
+			if la, err = ResolveIPAddr(laddr); err != nil {
+				return nil, err
+			}
+		}
+		c, err := ListenIP(net, la)
+		if err != nil {
+			return nil, err
+		}
+		return c, nil
+	}
 	return nil, UnknownNetworkError(net)
 }
 
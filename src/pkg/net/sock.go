@@ -21,7 +21,9 @@ func boolint(b bool) int {
 }
 
 // Generic socket creation.
-func socket(net string, f, p, t int, la, ra syscall.Sockaddr, toAddr func(syscall.Sockaddr) Addr) (fd *netFD, err os.Error) {
+// If ra is not nil, the socket is connected to it, waiting
+// until deadline (nsec since 1970) if it is non-zero.
+func socket(net string, f, p, t int, la, ra syscall.Sockaddr, deadline int64, toAddr func(syscall.Sockaddr) Addr) (fd *netFD, err os.Error) {
 	// See ../syscall/exec.go for description of ForkLock.
 	syscall.ForkLock.RLock()
 	s, e := syscall.Socket(f, p, t)
@@ -46,11 +48,15 @@ func socket(net string, f, p, t int, la, ra syscall.Sockaddr, toAddr func(syscal
 		}
 	}
 
+	if fd, err = newFD(s, f, p, net); err != nil {
+		syscall.Close(s)
+		return nil, err
+	}
+
 	if ra != nil {
-		e = syscall.Connect(s, ra)
-		if e != 0 {
+		if err = fd.connect(ra, deadline); err != nil {
 			syscall.Close(s)
-			return nil, os.Errno(e)
+			return nil, err
 		}
 	}
 
@@ -58,12 +64,7 @@ func socket(net string, f, p, t int, la, ra syscall.Sockaddr, toAddr func(syscal
 	laddr := toAddr(sa)
 	sa, _ = syscall.Getpeername(s)
 	raddr := toAddr(sa)
-
-	fd, err = newFD(s, f, p, net, laddr, raddr)
-	if err != nil {
-		syscall.Close(s)
-		return nil, err
-	}
+	fd.setAddr(laddr, raddr)
 
 	return fd, nil
 }
@@ -129,6 +130,24 @@ func setKeepAlive(fd *netFD, keepalive bool) os.Error {
 	return setsockoptInt(fd.sysfd, syscall.SOL_SOCKET, syscall.SO_KEEPALIVE, boolint(keepalive))
 }
 
+func setBroadcast(fd *netFD, broadcast bool) os.Error {
+	fd.incref()
+	defer fd.decref()
+	return setsockoptInt(fd.sysfd, syscall.SOL_SOCKET, syscall.SO_BROADCAST, boolint(broadcast))
+}
+
+func setIPv4MulticastTTL(fd *netFD, ttl int) os.Error {
+	fd.incref()
+	defer fd.decref()
+	return setsockoptInt(fd.sysfd, syscall.IPPROTO_IP, syscall.IP_MULTICAST_TTL, ttl)
+}
+
+func setIPv4MulticastLoopback(fd *netFD, loop bool) os.Error {
+	fd.incref()
+	defer fd.decref()
+	return setsockoptInt(fd.sysfd, syscall.IPPROTO_IP, syscall.IP_MULTICAST_LOOP, boolint(loop))
+}
+
 func setLinger(fd *netFD, sec int) os.Error {
 	var l syscall.Linger
 	if sec >= 0 {
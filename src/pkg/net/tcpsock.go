@@ -178,6 +178,18 @@ func (c *TCPConn) SetWriteBuffer(bytes int) os.Error {
 	return setWriteBuffer(c.fd, bytes)
 }
 
+// File returns a copy of the underlying os.File.  The copy is in
+// non-blocking mode, which it shares with c: reads and writes on f
+// that cannot complete at once fail with os.EAGAIN.
+// It is the caller's responsibility to close f when finished.
+// Closing c does not affect f, and closing f does not affect c.
+func (c *TCPConn) File() (f *os.File, err os.Error) {
+	if !c.ok() {
+		return nil, os.EINVAL
+	}
+	return c.fd.dup()
+}
+
 // SetLinger sets the behavior of Close() on a connection
 // which still has data waiting to be sent or to be acknowledged.
 //
@@ -208,10 +220,16 @@ func (c *TCPConn) SetKeepAlive(keepalive bool) os.Error {
 // DialTCP is like Dial but can only connect to TCP networks
 // and returns a TCPConn structure.
 func DialTCP(net string, laddr, raddr *TCPAddr) (c *TCPConn, err os.Error) {
+	return dialTCP(net, laddr, raddr, 0)
+}
+
+// dialTCP is like DialTCP but gives up waiting for the connection
+// at deadline (nsec since 1970), if it is non-zero.
+func dialTCP(net string, laddr, raddr *TCPAddr, deadline int64) (c *TCPConn, err os.Error) {
 	if raddr == nil {
 		return nil, &OpError{"dial", "tcp", nil, errMissingAddress}
 	}
-	fd, e := internetSocket(net, laddr.toAddr(), raddr.toAddr(), syscall.SOCK_STREAM, "dial", sockaddrToTCP)
+	fd, e := internetSocket(net, laddr.toAddr(), raddr.toAddr(), deadline, syscall.SOCK_STREAM, 0, "dial", sockaddrToTCP)
 	if e != nil {
 		return nil, e
 	}
@@ -230,7 +248,7 @@ type TCPListener struct {
 // If laddr has a port of 0, it means to listen on some available port.
 // The caller can use l.Addr() to retrieve the chosen address.
 func ListenTCP(net string, laddr *TCPAddr) (l *TCPListener, err os.Error) {
-	fd, err := internetSocket(net, laddr.toAddr(), nil, syscall.SOCK_STREAM, "listen", sockaddrToTCP)
+	fd, err := internetSocket(net, laddr.toAddr(), nil, 0, syscall.SOCK_STREAM, 0, "listen", sockaddrToTCP)
 	if err != nil {
 		return nil, err
 	}
@@ -278,3 +296,10 @@ func (l *TCPListener) Close() os.Error {
 
 // Addr returns the listener's network address, a *TCPAddr.
 func (l *TCPListener) Addr() Addr { return l.fd.laddr }
+
+// File returns a copy of the underlying os.File.  The copy is in
+// non-blocking mode, which it shares with l: reads and writes on f
+// that cannot complete at once fail with os.EAGAIN.
+// It is the caller's responsibility to close f when finished.
+// Closing l does not affect f, and closing f does not affect l.
+func (l *TCPListener) File() (f *os.File, err os.Error) { return l.fd.dup() }
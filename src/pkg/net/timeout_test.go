@@ -51,3 +51,115 @@ func TestTimeoutTCP(t *testing.T) {
 	// timeouts and this is the timeout test.
 	testTimeout(t, "tcp", "74.125.19.99:80", false)
 }
+
+func TestDialTimeout(t *testing.T) {
+	// Port 81 of www.google.com drops connection attempts
+	// (if the network is reachable at all).
+	t0 := time.Nanoseconds()
+	c, err := DialTimeout("tcp", "74.125.19.99:81", 1e8) // 100ms
+	t1 := time.Nanoseconds()
+	if err == nil {
+		c.Close()
+		t.Errorf("DialTimeout succeeded, expected error")
+	}
+	if t1-t0 > 1e9 {
+		t.Errorf("DialTimeout took %f seconds, expected at most 1 for a 0.1 second timeout", float64(t1-t0)/1e9)
+	}
+}
+
+type interleaveTest struct {
+	in, out []string
+}
+
+var interleaveTests = []interleaveTest{
+	interleaveTest{[]string{"1.2.3.4"}, []string{"1.2.3.4"}},
+	interleaveTest{
+		[]string{"1.2.3.4", "5.6.7.8", "::1", "::2"},
+		[]string{"1.2.3.4", "::1", "5.6.7.8", "::2"},
+	},
+	interleaveTest{
+		[]string{"::1", "1.2.3.4", "5.6.7.8", "9.9.9.9"},
+		[]string{"::1", "1.2.3.4", "5.6.7.8", "9.9.9.9"},
+	},
+	interleaveTest{
+		[]string{"::1", "::2", "1.2.3.4"},
+		[]string{"::1", "1.2.3.4", "::2"},
+	},
+}
+
+func TestInterleave(t *testing.T) {
+	for _, tt := range interleaveTests {
+		ips := make([]IP, len(tt.in))
+		for i, s := range tt.in {
+			ips[i] = ParseIP(s)
+		}
+		out := interleave(ips)
+		ok := len(out) == len(tt.out)
+		for i := 0; ok && i < len(out); i++ {
+			ok = out[i].String() == tt.out[i]
+		}
+		if !ok {
+			t.Errorf("interleave(%v) = %v, want %v", tt.in, out, tt.out)
+		}
+	}
+}
+
+func TestDialFallback(t *testing.T) {
+	l, err := Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	defer l.Close()
+	go func() {
+		if c, err := l.Accept(); err == nil {
+			c.Close()
+		}
+	}()
+
+	// The first address hangs (or fails); the dial
+	// falls back to the listener after 100ms.
+	ras := []*TCPAddr{
+		&TCPAddr{IPv4(74, 125, 19, 99), 81},
+		l.Addr().(*TCPAddr),
+	}
+	t0 := time.Nanoseconds()
+	c, err := dialStaggered("tcp", nil, ras, 1e8, t0+5e9)
+	t1 := time.Nanoseconds()
+	if err != nil {
+		t.Fatalf("dialStaggered: %v", err)
+	}
+	if ra := c.RemoteAddr().String(); ra != l.Addr().String() {
+		t.Errorf("dialStaggered connected to %s, want %s", ra, l.Addr())
+	}
+	c.Close()
+	if t1-t0 > 1e9 {
+		t.Errorf("dialStaggered took %f seconds, expected at most 1 for a 0.1 second fallback", float64(t1-t0)/1e9)
+	}
+}
+
+func TestDialerFamily(t *testing.T) {
+	d := new(Dialer)
+	for _, net := range []string{"tcp4", "tcp6"} {
+		addr := "127.0.0.1:1"
+		want := "no IPv6 address for host"
+		if net == "tcp4" {
+			addr = "[::1]:1"
+			want = "no IPv4 address for host"
+		}
+		c, err := d.Dial(net, addr)
+		if err == nil {
+			c.Close()
+			t.Errorf("Dial(%q, %q) succeeded, want error", net, addr)
+			continue
+		}
+		var msg string
+		if oe, ok := err.(*OpError); ok {
+			if ae, ok := oe.Error.(*AddrError); ok {
+				msg = ae.Error
+			}
+		}
+		if msg != want {
+			t.Errorf("Dial(%q, %q) = %v, want %q", net, addr, err, want)
+		}
+	}
+}
@@ -176,6 +176,18 @@ func (c *UDPConn) SetWriteBuffer(bytes int) os.Error {
 	return setWriteBuffer(c.fd, bytes)
 }
 
+// File returns a copy of the underlying os.File.  The copy is in
+// non-blocking mode, which it shares with c: reads and writes on f
+// that cannot complete at once fail with os.EAGAIN.
+// It is the caller's responsibility to close f when finished.
+// Closing c does not affect f, and closing f does not affect c.
+func (c *UDPConn) File() (f *os.File, err os.Error) {
+	if !c.ok() {
+		return nil, os.EINVAL
+	}
+	return c.fd.dup()
+}
+
 // UDP-specific methods.
 
 // ReadFromUDP reads a UDP packet from c, copying the payload into b.
@@ -256,7 +268,7 @@ func DialUDP(net string, laddr, raddr *UDPAddr) (c *UDPConn, err os.Error) {
 	if raddr == nil {
 		return nil, &OpError{"dial", "udp", nil, errMissingAddress}
 	}
-	fd, e := internetSocket(net, laddr.toAddr(), raddr.toAddr(), syscall.SOCK_DGRAM, "dial", sockaddrToUDP)
+	fd, e := internetSocket(net, laddr.toAddr(), raddr.toAddr(), 0, syscall.SOCK_DGRAM, 0, "dial", sockaddrToUDP)
 	if e != nil {
 		return nil, e
 	}
@@ -276,9 +288,110 @@ func ListenUDP(net string, laddr *UDPAddr) (c *UDPConn, err os.Error) {
 	if laddr == nil {
 		return nil, &OpError{"listen", "udp", nil, errMissingAddress}
 	}
-	fd, e := internetSocket(net, laddr.toAddr(), nil, syscall.SOCK_DGRAM, "dial", sockaddrToUDP)
+	fd, e := internetSocket(net, laddr.toAddr(), nil, 0, syscall.SOCK_DGRAM, 0, "dial", sockaddrToUDP)
 	if e != nil {
 		return nil, e
 	}
 	return newUDPConn(fd), nil
 }
+
+// ListenMulticastUDP listens for incoming UDP packets addressed
+// to the IPv4 multicast group address gaddr, joining the group
+// on the interface ifi.  If ifi is nil, the system chooses the
+// interface.  The network net must be "udp" or "udp4".
+func ListenMulticastUDP(net string, ifi *Interface, gaddr *UDPAddr) (c *UDPConn, err os.Error) {
+	switch net {
+	case "udp", "udp4":
+	default:
+		return nil, UnknownNetworkError(net)
+	}
+	if gaddr == nil {
+		return nil, &OpError{"listen", net, nil, errMissingAddress}
+	}
+	if ip := gaddr.IP.To4(); ip == nil || !ip.IsMulticast() {
+		return nil, &OpError{"listen", net, gaddr, errInvalidGroup}
+	}
+	fd, e := internetSocket("udp4", gaddr.toAddr(), nil, 0, syscall.SOCK_DGRAM, 0, "listen", sockaddrToUDP)
+	if e != nil {
+		return nil, e
+	}
+	c = newUDPConn(fd)
+	if err = c.JoinGroup(ifi, gaddr.IP); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+var errInvalidGroup = os.ErrorString("invalid IPv4 multicast group address")
+
+// JoinGroup joins the IPv4 multicast group addr on the
+// interface ifi.  If ifi is nil, the system chooses the interface.
+func (c *UDPConn) JoinGroup(ifi *Interface, addr IP) os.Error {
+	if !c.ok() {
+		return os.EINVAL
+	}
+	ip := addr.To4()
+	if ip == nil || !ip.IsMulticast() {
+		return &OpError{"joingroup", "udp", &UDPAddr{addr, 0}, errInvalidGroup}
+	}
+	if err := joinIPv4Group(c.fd, ifi, ip); err != nil {
+		return &OpError{"joingroup", "udp", &UDPAddr{addr, 0}, err}
+	}
+	return nil
+}
+
+// LeaveGroup leaves the IPv4 multicast group addr on the
+// interface ifi, which must match the one given to JoinGroup.
+func (c *UDPConn) LeaveGroup(ifi *Interface, addr IP) os.Error {
+	if !c.ok() {
+		return os.EINVAL
+	}
+	ip := addr.To4()
+	if ip == nil || !ip.IsMulticast() {
+		return &OpError{"leavegroup", "udp", &UDPAddr{addr, 0}, errInvalidGroup}
+	}
+	if err := leaveIPv4Group(c.fd, ifi, ip); err != nil {
+		return &OpError{"leavegroup", "udp", &UDPAddr{addr, 0}, err}
+	}
+	return nil
+}
+
+// SetMulticastInterface sets the interface on which c sends
+// IPv4 multicast packets.  If ifi is nil, the system chooses
+// the interface.
+func (c *UDPConn) SetMulticastInterface(ifi *Interface) os.Error {
+	if !c.ok() {
+		return os.EINVAL
+	}
+	return setIPv4MulticastInterface(c.fd, ifi)
+}
+
+// SetMulticastTTL sets the time-to-live of IPv4 multicast
+// packets sent by c.  The default, 1, keeps packets on the
+// local network.
+func (c *UDPConn) SetMulticastTTL(ttl int) os.Error {
+	if !c.ok() {
+		return os.EINVAL
+	}
+	return setIPv4MulticastTTL(c.fd, ttl)
+}
+
+// SetMulticastLoopback sets whether IPv4 multicast packets sent
+// by c are also delivered to group members on the local host.
+// Loopback is enabled by default.
+func (c *UDPConn) SetMulticastLoopback(loop bool) os.Error {
+	if !c.ok() {
+		return os.EINVAL
+	}
+	return setIPv4MulticastLoopback(c.fd, loop)
+}
+
+// SetBroadcast sets whether c may send packets to a
+// broadcast address.  Broadcast is enabled by default.
+func (c *UDPConn) SetBroadcast(broadcast bool) os.Error {
+	if !c.ok() {
+		return os.EINVAL
+	}
+	return setBroadcast(c.fd, broadcast)
+}
@@ -51,7 +51,7 @@ func unixSocket(net string, laddr, raddr *UnixAddr, mode string) (fd *netFD, err
 	if proto != syscall.SOCK_STREAM {
 		f = sockaddrToUnixgram
 	}
-	fd, err = socket(net, syscall.AF_UNIX, proto, 0, la, ra, f)
+	fd, err = socket(net, syscall.AF_UNIX, proto, 0, la, ra, 0, f)
 	if err != nil {
 		goto Error
 	}
@@ -233,6 +233,18 @@ func (c *UnixConn) SetWriteBuffer(bytes int) os.Error {
 	return setWriteBuffer(c.fd, bytes)
 }
 
+// File returns a copy of the underlying os.File.  The copy is in
+// non-blocking mode, which it shares with c: reads and writes on f
+// that cannot complete at once fail with os.EAGAIN.
+// It is the caller's responsibility to close f when finished.
+// Closing c does not affect f, and closing f does not affect c.
+func (c *UnixConn) File() (f *os.File, err os.Error) {
+	if !c.ok() {
+		return nil, os.EINVAL
+	}
+	return c.fd.dup()
+}
+
 // ReadFromUnix reads a packet from c, copying the payload into b.
 // It returns the number of bytes copied into b and the return address
 // that was on the packet.
@@ -297,6 +309,42 @@ func (c *UnixConn) WriteTo(b []byte, addr Addr) (n int, err os.Error) {
 	return c.WriteToUnix(b, a)
 }
 
+// ReadMsgUnix reads a packet from c, copying the payload into b and
+// the associated out-of-band data into oob.  It returns the number of
+// bytes copied into b, the number of bytes copied into oob, the flags
+// that were set on the packet and the source address of the packet.
+// The out-of-band data can be decoded with syscall.ParseSocketControlMessage.
+func (c *UnixConn) ReadMsgUnix(b, oob []byte) (n, oobn, flags int, addr *UnixAddr, err os.Error) {
+	if !c.ok() {
+		return 0, 0, 0, nil, os.EINVAL
+	}
+	n, oobn, flags, sa, err := c.fd.ReadMsg(b, oob)
+	switch sa := sa.(type) {
+	case *syscall.SockaddrUnix:
+		addr = &UnixAddr{sa.Name, c.fd.proto == syscall.SOCK_DGRAM}
+	}
+	return
+}
+
+// WriteMsgUnix writes a packet to addr via c, copying the payload from
+// b and the associated out-of-band data from oob.  It returns the number
+// of payload and out-of-band bytes written.  The address may be nil on
+// connected sockets.  Control messages such as file descriptors can be
+// built with syscall.UnixRights.
+func (c *UnixConn) WriteMsgUnix(b, oob []byte, addr *UnixAddr) (n, oobn int, err os.Error) {
+	if !c.ok() {
+		return 0, 0, os.EINVAL
+	}
+	var sa syscall.Sockaddr
+	if addr != nil {
+		if addr.Datagram != (c.fd.proto == syscall.SOCK_DGRAM) {
+			return 0, 0, os.EAFNOSUPPORT
+		}
+		sa = &syscall.SockaddrUnix{Name: addr.Name}
+	}
+	return c.fd.WriteMsg(b, oob, sa)
+}
+
 // DialUnix connects to the remote address raddr on the network net,
 // which must be "unix" or "unixdgram".  If laddr is not nil, it is used
 // as the local address for the connection.
@@ -381,7 +429,7 @@ func (l *UnixListener) Close() os.Error {
 	// is at least compatible with the auto-remove
 	// sequence in ListenUnix.  It's only non-Go
 	// programs that can mess us up.
-	if l.path[0] != '@' {
+	if l.path != "" && l.path[0] != '@' {
 		syscall.Unlink(l.path)
 	}
 	err := l.fd.Close()
@@ -392,6 +440,13 @@ func (l *UnixListener) Close() os.Error {
 // Addr returns the listener's network address.
 func (l *UnixListener) Addr() Addr { return l.fd.laddr }
 
+// File returns a copy of the underlying os.File.  The copy is in
+// non-blocking mode, which it shares with l: reads and writes on f
+// that cannot complete at once fail with os.EAGAIN.
+// It is the caller's responsibility to close f when finished.
+// Closing l does not affect f, and closing f does not affect l.
+func (l *UnixListener) File() (f *os.File, err os.Error) { return l.fd.dup() }
+
 // ListenUnixgram listens for incoming Unix datagram packets addressed to the
 // local address laddr.  The returned connection c's ReadFrom
 // and WriteTo methods can be used to receive and send UDP
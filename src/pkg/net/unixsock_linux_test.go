@@ -0,0 +1,67 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package net
+
+import (
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestUnixCredentials(t *testing.T) {
+	path := "/tmp/_unixcreds_test"
+	os.Remove(path)
+	l, err := ListenUnix("unix", &UnixAddr{path, false})
+	if err != nil {
+		t.Fatalf("ListenUnix: %v", err)
+	}
+	defer l.Close()
+	c, err := DialUnix("unix", nil, &UnixAddr{path, false})
+	if err != nil {
+		t.Fatalf("DialUnix: %v", err)
+	}
+	defer c.Close()
+	s, err := l.AcceptUnix()
+	if err != nil {
+		t.Fatalf("AcceptUnix: %v", err)
+	}
+	defer s.Close()
+
+	// The receiving socket must ask for credentials.
+	f, err := s.File()
+	if err != nil {
+		t.Fatalf("File: %v", err)
+	}
+	e := syscall.SetsockoptInt(f.Fd(), syscall.SOL_SOCKET, syscall.SO_PASSCRED, 1)
+	f.Close()
+	if e != 0 {
+		t.Fatalf("SetsockoptInt SO_PASSCRED: %v", os.Errno(e))
+	}
+
+	ucred := &syscall.Ucred{Pid: int32(os.Getpid()), Uid: uint32(os.Getuid()), Gid: uint32(os.Getgid())}
+	creds := syscall.UnixCredentials(ucred)
+	n, oobn, err := c.WriteMsgUnix([]byte("creds"), creds, nil)
+	if err != nil || n != 5 || oobn != len(creds) {
+		t.Fatalf("WriteMsgUnix = %d, %d, %v", n, oobn, err)
+	}
+
+	b := make([]byte, 16)
+	oob := make([]byte, syscall.CmsgSpace(syscall.SizeofUcred))
+	n, oobn, _, _, err = s.ReadMsgUnix(b, oob)
+	if err != nil || string(b[0:n]) != "creds" {
+		t.Fatalf("ReadMsgUnix = %q, %v", b[0:n], err)
+	}
+	msgs, e := syscall.ParseSocketControlMessage(oob[0:oobn])
+	if e != 0 || len(msgs) != 1 {
+		t.Fatalf("ParseSocketControlMessage = %d messages, errno %d", len(msgs), e)
+	}
+	got, e := syscall.ParseUnixCredentials(&msgs[0])
+	if e != 0 {
+		t.Fatalf("ParseUnixCredentials: errno %d", e)
+	}
+	if got.Pid != ucred.Pid || got.Uid != ucred.Uid || got.Gid != ucred.Gid {
+		t.Errorf("ParseUnixCredentials = %+v, want %+v", *got, *ucred)
+	}
+}
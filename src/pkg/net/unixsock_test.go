@@ -0,0 +1,73 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package net
+
+import (
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestUnixRights(t *testing.T) {
+	if syscall.OS == "nacl" {
+		return
+	}
+	path := "/tmp/_unixrights_test"
+	os.Remove(path)
+	l, err := ListenUnix("unix", &UnixAddr{path, false})
+	if err != nil {
+		t.Fatalf("ListenUnix: %v", err)
+	}
+	defer l.Close()
+	c, err := DialUnix("unix", nil, &UnixAddr{path, false})
+	if err != nil {
+		t.Fatalf("DialUnix: %v", err)
+	}
+	defer c.Close()
+	s, err := l.AcceptUnix()
+	if err != nil {
+		t.Fatalf("AcceptUnix: %v", err)
+	}
+	defer s.Close()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("os.Pipe: %v", err)
+	}
+	defer r.Close()
+	defer w.Close()
+
+	rights := syscall.UnixRights(r.Fd())
+	n, oobn, err := c.WriteMsgUnix([]byte("fd"), rights, nil)
+	if err != nil || n != 2 || oobn != len(rights) {
+		t.Fatalf("WriteMsgUnix = %d, %d, %v", n, oobn, err)
+	}
+
+	b := make([]byte, 16)
+	oob := make([]byte, syscall.CmsgSpace(4))
+	n, oobn, _, _, err = s.ReadMsgUnix(b, oob)
+	if err != nil || string(b[0:n]) != "fd" {
+		t.Fatalf("ReadMsgUnix = %q, %v", b[0:n], err)
+	}
+	msgs, e := syscall.ParseSocketControlMessage(oob[0:oobn])
+	if e != 0 || len(msgs) != 1 {
+		t.Fatalf("ParseSocketControlMessage = %d messages, errno %d", len(msgs), e)
+	}
+	fds, e := syscall.ParseUnixRights(&msgs[0])
+	if e != 0 || len(fds) != 1 {
+		t.Fatalf("ParseUnixRights = %v, errno %d", fds, e)
+	}
+	f := os.NewFile(fds[0], "passed")
+	defer f.Close()
+
+	// The received descriptor refers to the same pipe.
+	if _, err := w.Write([]byte("hello")); err != nil {
+		t.Fatalf("pipe write: %v", err)
+	}
+	n, err = f.Read(b)
+	if err != nil || string(b[0:n]) != "hello" {
+		t.Errorf("read from passed fd = %q, %v; want %q", b[0:n], err, "hello")
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"net"
 	"os"
 	"sync"
+	"time"
 )
 
 type Dir int
@@ -19,6 +20,57 @@ const (
 	Send
 )
 
+// The operations an importer asks of the exporter.
+const (
+	opImport = iota // start sending values on a channel
+	opCredit        // count more values may be sent; acknowledges values received
+	opHangup        // stop sending values on a channel
+)
+
+// The number of values an exporter may send on a channel before
+// the importer acknowledges any of them.  It bounds the values in
+// transit, and so what the importer must buffer, for each channel.
+const window = 10
+
+// Sent from importer to exporter to start listening to a channel,
+// to acknowledge values and to hang up.
+type request struct {
+	name  string
+	dir   Dir
+	count int
+	op    int
+}
+
+// Sent from exporter to importer before each value.  A response with
+// eof set, or with an error, is sent alone and ends the channel.
+type response struct {
+	name  string
+	error string
+	eof   bool
+}
+
+// How often Drain checks for outstanding values.
+const drainInterval = 10e6 // 10ms
+
+// drain waits until pending reports no outstanding values or, if
+// timeout is positive, until timeout nanoseconds have passed.
+func drain(timeout int64, pending func() int) os.Error {
+	deadline := time.Nanoseconds() + timeout
+	for pending() > 0 {
+		if timeout > 0 && time.Nanoseconds() > deadline {
+			return os.ErrorString("netchan: timeout draining values")
+		}
+		time.Sleep(drainInterval)
+	}
+	return nil
+}
+
+// report sends err on errors without blocking; errors
+// are dropped when nobody is reading them.
+func report(errors chan os.Error, err os.Error) {
+	_ = errors <- err
+}
+
 // Mutex-protected encoder and decoder pair
 
 type encDec struct {
@@ -16,7 +16,18 @@
 
 	Networked channels are not synchronized; they always behave
 	as if there is a buffer of at least one element between the
-	two machines.
+	two machines.  The buffer is bounded: the exporter takes at
+	most a small window of values from a channel before the
+	importer acknowledges their delivery.
+
+	An exporter may withdraw a channel with Drop, and an importer
+	may stop receiving one with Hangup; either way the imported
+	channel is closed.  When the connection between the two is
+	lost, the importer closes all its imported channels.  Errors,
+	including the loss of a connection, are reported on the
+	channels returned by the Errors methods.  A value the exporter
+	has already taken from a channel when an import stops cannot
+	be delivered and is reported as lost.
 
 	TODO: at the moment, the exporting machine must send and
 	the importing machine must receive.  This restriction will
@@ -34,55 +45,107 @@ import (
 
 // Export
 
-// A channel and its associated information: a direction
+// A channel and its associated information: a direction, the
+// clients it is being sent to, and a channel closed when the
+// export is dropped
 type exportChan struct {
-	ch  *reflect.ChanValue
-	dir Dir
+	ch      *reflect.ChanValue
+	dir     Dir
+	serves  map[*serve]bool // protected by Exporter.chanLock
+	dropped chan bool
 }
 
 // An Exporter allows a set of channels to be published on a single
-// network port.  A single machine may have multiple Exporters
+// network port or connection.  A single machine may have multiple Exporters
 // but they must use different ports.
 type Exporter struct {
-	listener net.Listener
-	chanLock sync.Mutex // protects access to channel map
-	chans    map[string]*exportChan
+	listener   net.Listener // nil if serving a single connection
+	addr       net.Addr
+	chanLock   sync.Mutex // protects access to channel map
+	chans      map[string]*exportChan
+	clientLock sync.Mutex // protects access to the clients and their unacked counts
+	clients    map[*expClient]bool
+	errors     chan os.Error
 }
 
 type expClient struct {
 	*encDec
-	exp *Exporter
+	exp     *Exporter
+	conn    net.Conn
+	unacked int // values sent but not yet acknowledged; protected by exp.clientLock
 }
 
 func newClient(exp *Exporter, conn net.Conn) *expClient {
 	client := new(expClient)
 	client.exp = exp
+	client.conn = conn
 	client.encDec = newEncDec(conn)
+	exp.clientLock.Lock()
+	exp.clients[client] = true
+	exp.clientLock.Unlock()
 	return client
 
 }
 
-// TODO: ASSUMES EXPORT MEANS SEND
+// A serve is the sending of one exported channel to one client,
+// paced by the credit the client has granted.
+type serve struct {
+	client *expClient
+	name   string
+	credit chan bool  // a value for each value the client will accept; closed to stop
+	mu     sync.Mutex // protects ended and orders sends after it
+	ended  bool       // no more values are to be sent
+}
 
-// Sent once per channel from importer to exporter to report that it's listening to a channel
-type request struct {
-	name  string
-	dir   Dir
-	count int
+func newServe(client *expClient, name string) *serve {
+	s := &serve{client: client, name: name, credit: make(chan bool, window)}
+	grant(s.credit, window)
+	return s
 }
 
-// Reply to request, sent from exporter to importer on each send.
-type response struct {
-	name  string
-	error string
+// send sends val to the client unless the serve has ended,
+// and reports whether it did.
+func (s *serve) send(val reflect.Value) (sent bool, err os.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ended {
+		return false, nil
+	}
+	exp := s.client.exp
+	exp.clientLock.Lock()
+	s.client.unacked++
+	exp.clientLock.Unlock()
+	resp := new(response)
+	resp.name = s.name
+	return true, s.client.encode(resp, val.Interface())
 }
 
+// stop ends the serve, telling the client there will be no more
+// values if tell is set.  Only the first call has any effect.
+func (s *serve) stop(tell bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ended {
+		return
+	}
+	s.ended = true
+	if tell {
+		resp := new(response)
+		resp.name = s.name
+		resp.eof = true
+		s.client.encode(resp, nil)
+	}
+}
+
+// TODO: ASSUMES EXPORT MEANS SEND
+
 // Wait for incoming connections, start a new runner for each
 func (exp *Exporter) listen() {
 	for {
 		conn, err := exp.listener.Accept()
 		if err != nil {
 			log.Stderr("exporter.listen:", err)
+			report(exp.errors, err)
 			break
 		}
 		log.Stderr("accepted call from", conn.RemoteAddr())
@@ -94,59 +157,138 @@ func (exp *Exporter) listen() {
 // Send a single client all its data.  For each request, this will launch
 // a serveRecv goroutine to deliver the data for that channel.
 func (client *expClient) run() {
-	req := new(request)
+	exp := client.exp
+	serves := make(map[string]*serve)
 	for {
+		req := new(request)
 		if err := client.decode(req); err != nil {
 			log.Stderr("error decoding client request:", err)
-			// TODO: tear down connection
+			report(exp.errors, err)
 			break
 		}
-		log.Stderrf("export request: %+v", req)
-		if req.dir == Recv {
-			go client.serveRecv(req)
-		} else {
-			log.Stderr("export request: can't handle channel direction", req.dir)
-			resp := new(response)
-			resp.name = req.name
-			resp.error = "export request: can't handle channel direction"
-			client.encode(resp, nil)
-			break
+		switch req.op {
+		case opImport:
+			if req.dir != Recv {
+				log.Stderr("export request: can't handle channel direction", req.dir)
+				resp := new(response)
+				resp.name = req.name
+				resp.error = "export request: can't handle channel direction"
+				client.encode(resp, nil)
+				continue
+			}
+			s := newServe(client, req.name)
+			serves[req.name] = s
+			go client.serveRecv(req, s)
+		case opCredit:
+			exp.clientLock.Lock()
+			client.unacked -= req.count
+			exp.clientLock.Unlock()
+			if s, ok := serves[req.name]; ok {
+				grant(s.credit, req.count)
+			}
+		case opHangup:
+			if s, ok := serves[req.name]; ok {
+				s.stop(true)
+				close(s.credit)
+				serves[req.name] = nil, false
+			}
 		}
 	}
+	// The client is gone; stop serving it.
+	for _, s := range serves {
+		s.stop(false)
+		close(s.credit)
+	}
+	exp.clientLock.Lock()
+	exp.clients[client] = false, false
+	exp.clientLock.Unlock()
+	client.conn.Close()
 }
 
-// Send all the data on a single channel to a client asking for a Recv
-func (client *expClient) serveRecv(req *request) {
+// grant adds n to the credit available on a channel.
+func grant(credit chan bool, n int) {
+	for i := 0; i < n; i++ {
+		_ = credit <- true // never blocks unless the client misbehaves
+	}
+}
+
+// Send all the data on a single channel to a client asking for a Recv.
+// A value is taken from the channel only once the client has credit
+// for it, so a slow client leaves the values with their sender.
+func (client *expClient) serveRecv(req *request, s *serve) {
 	exp := client.exp
-	resp := new(response)
-	resp.name = req.name
 	var ok bool
 	exp.chanLock.Lock()
 	ech, ok := exp.chans[req.name]
+	if ok {
+		ech.serves[s] = true
+	}
 	exp.chanLock.Unlock()
 	if !ok {
+		resp := new(response)
+		resp.name = req.name
 		resp.error = "no such channel: " + req.name
 		log.Stderr("export:", resp.error)
 		client.encode(resp, nil) // ignore any encode error, hope client gets it
 		return
 	}
+	defer func() {
+		exp.chanLock.Lock()
+		ech.serves[s] = false, false
+		exp.chanLock.Unlock()
+	}()
+	count := req.count
 	for {
 		if ech.dir != Send {
 			log.Stderr("TODO: recv export unimplemented")
 			break
 		}
+		stop := false
+		select {
+		case <-s.credit:
+			// Closed if the client hung up or went away.
+			stop = closed(s.credit)
+		case <-ech.dropped:
+			stop = true
+		}
+		if stop {
+			break
+		}
 		val := ech.ch.Recv()
-		if err := client.encode(resp, val.Interface()); err != nil {
-			log.Stderr("error encoding client response:", err)
+		if ech.ch.Closed() {
 			break
 		}
-		if req.count > 0 {
-			req.count--
-			if req.count == 0 {
+		sent, err := s.send(val)
+		if err != nil {
+			log.Stderr("error encoding client response:", err)
+			report(exp.errors, err)
+			return
+		}
+		if !sent {
+			// The serve was stopped while we waited for the value,
+			// and nobody will receive it.  Putting it back could
+			// block, and would reorder it after later values.
+			report(exp.errors, os.ErrorString("netchan export: value lost after "+s.name+" stopped"))
+			return
+		}
+		if count > 0 {
+			count--
+			if count == 0 {
 				break
 			}
 		}
 	}
+	// Tell the client there will be no more values.
+	s.stop(true)
+}
+
+func newExporter(addr net.Addr) *Exporter {
+	return &Exporter{
+		addr:    addr,
+		chans:   make(map[string]*exportChan),
+		clients: make(map[*expClient]bool),
+		errors:  make(chan os.Error, 10),
+	}
 }
 
 // NewExporter creates a new Exporter to export channels
@@ -156,16 +298,51 @@ func NewExporter(network, localaddr string) (*Exporter, os.Error) {
 	if err != nil {
 		return nil, err
 	}
-	e := &Exporter{
-		listener: listener,
-		chans:    make(map[string]*exportChan),
-	}
+	return NewExporterListener(listener), nil
+}
+
+// NewExporterListener creates a new Exporter to export channels
+// to the importers that connect to listener, which may be any
+// net.Listener, such as one for Unix domain sockets.
+func NewExporterListener(listener net.Listener) *Exporter {
+	e := newExporter(listener.Addr())
+	e.listener = listener
 	go e.listen()
-	return e, nil
+	return e
+}
+
+// NewExporterConn creates a new Exporter to export channels to
+// the single importer at the other end of conn, a connection the
+// caller has already established, such as a tls.Conn.
+func NewExporterConn(conn net.Conn) *Exporter {
+	e := newExporter(conn.LocalAddr())
+	go newClient(e, conn).run()
+	return e
 }
 
 // Addr returns the Exporter's local network address.
-func (exp *Exporter) Addr() net.Addr { return exp.listener.Addr() }
+func (exp *Exporter) Addr() net.Addr { return exp.addr }
+
+// Errors returns a channel on which the Exporter reports errors,
+// such as an importer's connection being lost.  Errors are dropped
+// if the channel's buffer is full.
+func (exp *Exporter) Errors() chan os.Error { return exp.errors }
+
+// Drain waits until every value sent on an exported channel has been
+// acknowledged by the importer that received it.  If timeout is positive
+// it waits at most timeout nanoseconds and then returns an error.
+// Values sent to an importer whose connection is lost are not waited for.
+func (exp *Exporter) Drain(timeout int64) os.Error {
+	return drain(timeout, func() int {
+		exp.clientLock.Lock()
+		defer exp.clientLock.Unlock()
+		n := 0
+		for client := range exp.clients {
+			n += client.unacked
+		}
+		return n
+	})
+}
 
 func checkChan(chT interface{}, dir Dir) (*reflect.ChanValue, os.Error) {
 	chanType, ok := reflect.Typeof(chT).(*reflect.ChanType)
@@ -206,6 +383,34 @@ func (exp *Exporter) Export(name string, chT interface{}, dir Dir) os.Error {
 	if present {
 		return os.ErrorString("channel name already being exported:" + name)
 	}
-	exp.chans[name] = &exportChan{ch, dir}
+	exp.chans[name] = &exportChan{ch, dir, make(map[*serve]bool), make(chan bool)}
+	return nil
+}
+
+// Drop withdraws the named channel from export.  Importers receiving
+// from it are told there will be no more values, so their imported
+// channels are closed, and later attempts to import it fail.  If the
+// Exporter is waiting for a value on the channel, it still takes the
+// next one sent, which is lost and reported on Errors.  The name may
+// be exported again afterwards.
+func (exp *Exporter) Drop(name string) os.Error {
+	exp.chanLock.Lock()
+	ech, present := exp.chans[name]
+	if !present {
+		exp.chanLock.Unlock()
+		return os.ErrorString("channel name not being exported:" + name)
+	}
+	exp.chans[name] = nil, false
+	close(ech.dropped)
+	serves := make([]*serve, len(ech.serves))
+	i := 0
+	for s := range ech.serves {
+		serves[i] = s
+		i++
+	}
+	exp.chanLock.Unlock()
+	for _, s := range serves {
+		s.stop(true)
+	}
 	return nil
 }
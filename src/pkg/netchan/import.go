@@ -14,12 +14,35 @@ import (
 
 // Import
 
-// A channel and its associated information: a template value, direction and a count
+// A channel and its associated information: a template value, direction and a count,
+// and a queue of received values waiting to be delivered to the channel.
 type importChan struct {
-	ch    *reflect.ChanValue
-	dir   Dir
-	ptr   *reflect.PtrValue // a pointer value we can point at each new item
-	count int
+	ch      *reflect.ChanValue
+	dir     Dir
+	ptr     *reflect.PtrValue // a pointer value we can point at each new item
+	count   int
+	queue   chan reflect.Value
+	stopped bool // queue is closed; protected by Importer.chanLock
+	hungUp  bool // values are to be discarded; protected by Importer.chanLock
+	closed  bool // ch is closed; protected by Importer.chanLock
+}
+
+// stop closes the queue so that the channel is closed once the values
+// already queued have been delivered.  The caller holds chanLock.
+func (ich *importChan) stop() {
+	if !ich.stopped {
+		ich.stopped = true
+		close(ich.queue)
+	}
+}
+
+// closeChan closes the imported channel if it is not closed
+// already.  The caller holds chanLock.
+func (ich *importChan) closeChan() {
+	if !ich.closed {
+		ich.closed = true
+		ich.ch.Close()
+	}
 }
 
 // An Importer allows a set of channels to be imported from a single
@@ -27,9 +50,11 @@ type importChan struct {
 // importers, even from the same machine/network port.
 type Importer struct {
 	*encDec
-	conn     net.Conn
-	chanLock sync.Mutex // protects access to channel map
-	chans    map[string]*importChan
+	conn        net.Conn
+	chanLock    sync.Mutex // protects access to channel map and undelivered
+	chans       map[string]*importChan
+	undelivered int // values received but not yet delivered
+	errors      chan os.Error
 }
 
 // TODO: ASSUMES IMPORT MEANS RECEIVE
@@ -43,12 +68,20 @@ func NewImporter(network, remoteaddr string) (*Importer, os.Error) {
 	if err != nil {
 		return nil, err
 	}
+	return NewImporterConn(conn), nil
+}
+
+// NewImporterConn creates a new Importer object to import channels
+// from the Exporter at the other end of conn, a connection the caller
+// has already established, such as a tls.Conn or a Unix domain socket.
+func NewImporterConn(conn net.Conn) *Importer {
 	imp := new(Importer)
 	imp.encDec = newEncDec(conn)
 	imp.conn = conn
 	imp.chans = make(map[string]*importChan)
+	imp.errors = make(chan os.Error, 10)
 	go imp.run()
-	return imp, nil
+	return imp
 }
 
 // Handle the data from a single imported data stream, which will
@@ -58,15 +91,11 @@ func NewImporter(network, remoteaddr string) (*Importer, os.Error) {
 // TODO: allow an importer to send.
 func (imp *Importer) run() {
 	// Loop on responses; requests are sent by ImportNValues()
-	resp := new(response)
 	for {
+		resp := new(response)
 		if err := imp.decode(resp); err != nil {
 			log.Stderr("importer response decode:", err)
-			break
-		}
-		if resp.error != "" {
-			log.Stderr("importer response error:", resp.error)
-			// TODO: tear down connection
+			report(imp.errors, err)
 			break
 		}
 		imp.chanLock.Lock()
@@ -74,8 +103,20 @@ func (imp *Importer) run() {
 		imp.chanLock.Unlock()
 		if !ok {
 			log.Stderr("unknown name in request:", resp.name)
+			report(imp.errors, os.ErrorString("netchan import: unknown name in response: "+resp.name))
 			break
 		}
+		if resp.error != "" || resp.eof {
+			if resp.error != "" {
+				log.Stderr("importer response error:", resp.error)
+				report(imp.errors, os.ErrorString("netchan import: "+resp.name+": "+resp.error))
+			}
+			imp.chanLock.Lock()
+			imp.chans[resp.name] = nil, false
+			ich.stop()
+			imp.chanLock.Unlock()
+			continue
+		}
 		if ich.dir != Recv {
 			log.Stderr("TODO: import send unimplemented")
 			break
@@ -85,10 +126,65 @@ func (imp *Importer) run() {
 		ich.ptr.PointTo(val)
 		if err := imp.decode(ich.ptr.Interface()); err != nil {
 			log.Stderr("importer value decode:", err)
-			return
+			report(imp.errors, err)
+			break
 		}
-		ich.ch.Send(val)
+		imp.chanLock.Lock()
+		// The exporter's credit guarantees there is room; only
+		// a misbehaving exporter sends more.
+		queued := !ich.hungUp && len(ich.queue) < cap(ich.queue)
+		if queued {
+			ich.queue <- val
+			imp.undelivered++
+		}
+		imp.chanLock.Unlock()
+		if !queued {
+			imp.ack(resp.name)
+		}
+	}
+	// The connection is gone; close the imported channels.
+	imp.chanLock.Lock()
+	for _, ich := range imp.chans {
+		ich.stop()
 	}
+	imp.chanLock.Unlock()
+	imp.conn.Close()
+}
+
+// deliver sends the values received for an imported channel to the
+// channel, acknowledging each, and closes the channel at the end.
+func (imp *Importer) deliver(name string, ich *importChan) {
+	for {
+		val := <-ich.queue
+		if closed(ich.queue) {
+			break
+		}
+		imp.chanLock.Lock()
+		hungUp := ich.hungUp
+		imp.chanLock.Unlock()
+		if !hungUp {
+			// If Hangup closes the channel while we wait
+			// here, the send is abandoned.
+			ich.ch.Send(val)
+		}
+		imp.chanLock.Lock()
+		imp.undelivered--
+		imp.chanLock.Unlock()
+		imp.ack(name)
+	}
+	imp.chanLock.Lock()
+	ich.closeChan()
+	imp.chanLock.Unlock()
+}
+
+// ack tells the exporter that a value on the named channel has been
+// dealt with, allowing it to send another.
+func (imp *Importer) ack(name string) {
+	req := new(request)
+	req.name = name
+	req.op = opCredit
+	req.count = 1
+	imp.encode(req, nil) // an error means the connection is going away
 }
 
 // Import imports a channel of the given type and specified direction.
@@ -135,15 +231,57 @@ func (imp *Importer) ImportNValues(name string, chT interface{}, dir Dir, pT int
 		return os.ErrorString("channel name already being imported:" + name)
 	}
 	ptr := reflect.MakeZero(reflect.Typeof(pT)).(*reflect.PtrValue)
-	imp.chans[name] = &importChan{ch, dir, ptr, n}
+	ich := &importChan{ch: ch, dir: dir, ptr: ptr, count: n, queue: make(chan reflect.Value, window)}
+	imp.chans[name] = ich
+	go imp.deliver(name, ich)
 	// Tell the other side about this channel.
 	req := new(request)
 	req.name = name
 	req.dir = dir
 	req.count = n
+	req.op = opImport
 	if err := imp.encode(req, nil); err != nil {
 		log.Stderr("importer request encode:", err)
 		return err
 	}
 	return nil
 }
+
+// Hangup stops receiving values on the named imported channel and
+// closes the channel at once, even if a value is waiting to be
+// received from it.  The exporter is asked to stop sending; values
+// already received or in transit are discarded.  The name cannot be
+// imported again until the exporter has confirmed the hangup.
+func (imp *Importer) Hangup(name string) os.Error {
+	imp.chanLock.Lock()
+	ich, ok := imp.chans[name]
+	if ok {
+		ich.hungUp = true
+		ich.stop()
+		ich.closeChan()
+	}
+	imp.chanLock.Unlock()
+	if !ok {
+		return os.ErrorString("netchan hangup: no such channel: " + name)
+	}
+	req := new(request)
+	req.name = name
+	req.op = opHangup
+	return imp.encode(req, nil)
+}
+
+// Errors returns a channel on which the Importer reports errors, such
+// as the connection to the exporter being lost or the exporter refusing
+// an import.  Errors are dropped if the channel's buffer is full.
+func (imp *Importer) Errors() chan os.Error { return imp.errors }
+
+// Drain waits until every value received by the Importer has been
+// delivered to its imported channel.  If timeout is positive it waits
+// at most timeout nanoseconds and then returns an error.
+func (imp *Importer) Drain(timeout int64) os.Error {
+	return drain(timeout, func() int {
+		imp.chanLock.Lock()
+		defer imp.chanLock.Unlock()
+		return imp.undelivered
+	})
+}
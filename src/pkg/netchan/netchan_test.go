@@ -6,7 +6,10 @@ package netchan
 
 import (
 	"fmt"
+	"net"
+	"os"
 	"testing"
+	"time"
 )
 
 type value struct {
@@ -48,3 +51,219 @@ func TestBabyStep(t *testing.T) {
 	}
 	importReceive(imp, t)
 }
+
+func newPair(t *testing.T) (*Exporter, *Importer) {
+	exp, err := NewExporter("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("new exporter:", err)
+	}
+	imp, err := NewImporter("tcp", exp.Addr().String())
+	if err != nil {
+		t.Fatal("new importer:", err)
+	}
+	return exp, imp
+}
+
+func TestFlowControl(t *testing.T) {
+	exp, imp := newPair(t)
+	c := make(chan value)
+	if err := exp.Export("flow", c, Send); err != nil {
+		t.Fatal("export:", err)
+	}
+	const n = 3 * window
+	sent := make(chan int, n)
+	go func() {
+		for i := 0; i < n; i++ {
+			c <- value{i, "flow"}
+			sent <- i
+		}
+	}()
+	ch := make(chan value)
+	if err := imp.Import("flow", ch, Recv, new(value)); err != nil {
+		t.Fatal("import:", err)
+	}
+	// Nothing is received, so the exporter stops after a window's worth.
+	time.Sleep(200e6)
+	if len(sent) != window {
+		t.Errorf("exporter took %d values before any were received; want %d", len(sent), window)
+	}
+	for i := 0; i < n; i++ {
+		if v := <-ch; v.i != i {
+			t.Fatalf("value %d: got %+v", i, v)
+		}
+	}
+	if err := exp.Drain(5e9); err != nil {
+		t.Error("exporter drain:", err)
+	}
+	if err := imp.Drain(5e9); err != nil {
+		t.Error("importer drain:", err)
+	}
+}
+
+func TestHangup(t *testing.T) {
+	exp, imp := newPair(t)
+	c := make(chan value)
+	if err := exp.Export("hangup", c, Send); err != nil {
+		t.Fatal("export:", err)
+	}
+	go func() {
+		for i := 0; ; i++ {
+			c <- value{i, "hangup"}
+		}
+	}()
+	ch := make(chan value)
+	if err := imp.Import("hangup", ch, Recv, new(value)); err != nil {
+		t.Fatal("import:", err)
+	}
+	<-ch
+	// Give the importer time to block delivering the next value;
+	// hanging up must close the channel all the same.
+	time.Sleep(200e6)
+	if err := imp.Hangup("hangup"); err != nil {
+		t.Fatal("hangup:", err)
+	}
+	<-ch
+	if !closed(ch) {
+		t.Fatal("channel still open after hangup")
+	}
+	if err := imp.Hangup("nonexistent"); err == nil {
+		t.Error("expected error hanging up an unknown channel")
+	}
+}
+
+func TestExporterClose(t *testing.T) {
+	exp, imp := newPair(t)
+	c := make(chan value)
+	if err := exp.Export("close", c, Send); err != nil {
+		t.Fatal("export:", err)
+	}
+	ch := make(chan value)
+	if err := imp.Import("close", ch, Recv, new(value)); err != nil {
+		t.Fatal("import:", err)
+	}
+	c <- value{1, "close"}
+	close(c)
+	if v := <-ch; v.i != 1 {
+		t.Errorf("got %+v", v)
+	}
+	<-ch
+	if !closed(ch) {
+		t.Error("imported channel not closed after exported channel")
+	}
+}
+
+func TestLostConnection(t *testing.T) {
+	exp, imp := newPair(t)
+	c := make(chan value)
+	if err := exp.Export("lost", c, Send); err != nil {
+		t.Fatal("export:", err)
+	}
+	ch := make(chan value)
+	if err := imp.Import("lost", ch, Recv, new(value)); err != nil {
+		t.Fatal("import:", err)
+	}
+	c <- value{1, "lost"}
+	<-ch
+	imp.conn.Close()
+	if err := <-exp.Errors(); err == nil {
+		t.Error("exporter reported nil error")
+	}
+	if err := <-imp.Errors(); err == nil {
+		t.Error("importer reported nil error")
+	}
+	<-ch
+	if !closed(ch) {
+		t.Error("imported channel not closed after connection loss")
+	}
+}
+
+func TestDrop(t *testing.T) {
+	exp, imp := newPair(t)
+	c := make(chan value)
+	if err := exp.Export("drop", c, Send); err != nil {
+		t.Fatal("export:", err)
+	}
+	ch := make(chan value)
+	if err := imp.Import("drop", ch, Recv, new(value)); err != nil {
+		t.Fatal("import:", err)
+	}
+	c <- value{1, "drop"}
+	<-ch
+	// Let the exporter start waiting for the next value.
+	time.Sleep(200e6)
+	if err := exp.Drop("drop"); err != nil {
+		t.Fatal("drop:", err)
+	}
+	<-ch
+	if !closed(ch) {
+		t.Error("imported channel not closed after Drop")
+	}
+	if err := exp.Drop("drop"); err == nil {
+		t.Error("expected error dropping a channel twice")
+	}
+
+	// Importing it again fails, and the name can be exported anew.
+	ch = make(chan value)
+	if err := imp.Import("drop", ch, Recv, new(value)); err != nil {
+		t.Fatal("import:", err)
+	}
+	if err := <-imp.Errors(); err == nil {
+		t.Error("expected error importing a dropped channel")
+	}
+	if err := exp.Export("drop", c, Send); err != nil {
+		t.Error("export after drop:", err)
+	}
+
+	// The value the exporter was waiting for is lost, not sent back.
+	c <- value{2, "drop"}
+	if err := <-exp.Errors(); err == nil {
+		t.Error("expected error reporting a lost value")
+	}
+}
+
+// Run a single value through an Exporter and Importer
+// made from an existing listener and connection.
+func testConn(t *testing.T, exp *Exporter, imp *Importer) {
+	c := make(chan value)
+	if err := exp.Export("name", c, Send); err != nil {
+		t.Fatal("export:", err)
+	}
+	go func() { c <- value{23, "hello"} }()
+	importReceive(imp, t)
+}
+
+func TestListenerConn(t *testing.T) {
+	os.Remove("/tmp/gotest.netchan")
+	l, err := net.Listen("unix", "/tmp/gotest.netchan")
+	if err != nil {
+		t.Fatal("listen:", err)
+	}
+	defer l.Close()
+	exp := NewExporterListener(l)
+	conn, err := net.Dial("unix", "", "/tmp/gotest.netchan")
+	if err != nil {
+		t.Fatal("dial:", err)
+	}
+	testConn(t, exp, NewImporterConn(conn))
+}
+
+func TestExporterConn(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("listen:", err)
+	}
+	defer l.Close()
+	conn, err := net.Dial("tcp", "", l.Addr().String())
+	if err != nil {
+		t.Fatal("dial:", err)
+	}
+	server, err := l.Accept()
+	if err != nil {
+		t.Fatal("accept:", err)
+	}
+	exp := NewExporterConn(server)
+	if exp.Addr().String() != l.Addr().String() {
+		t.Errorf("exporter address %s, want %s", exp.Addr(), l.Addr())
+	}
+	testConn(t, exp, NewImporterConn(conn))
+}
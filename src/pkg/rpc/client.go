@@ -12,7 +12,16 @@ import (
 	"log"
 	"net"
 	"os"
+	"reflect"
 	"sync"
+	"time"
+)
+
+// Errors with which the client completes calls it gives up on.
+var (
+	ErrShutdown = os.ErrorString("rpc: connection is shut down")
+	ErrTimeout  = os.ErrorString("rpc: call timed out")
+	ErrCanceled = os.ErrorString("rpc: call canceled")
 )
 
 // Call represents an active RPC.
@@ -23,27 +32,44 @@ type Call struct {
 	Error         os.Error    // After completion, the error status.
 	Done          chan *Call  // Strobes when call is complete; value is the error status.
 	seq           uint64
+	abandoned     bool // completed by Cancel or a timeout; protected by Client.mutex
 }
 
 // Client represents an RPC Client.
 // There may be multiple outstanding Calls associated
 // with a single Client.
 type Client struct {
-	mutex    sync.Mutex // protects pending, seq
+	mutex    sync.Mutex // protects pending, seq, closing
 	shutdown os.Error   // non-nil if the client is shut down
+	closing  bool       // Close has been called
 	sending  sync.Mutex
 	seq      uint64
-	conn     io.ReadWriteCloser
-	enc      *gob.Encoder
-	dec      *gob.Decoder
+	codec    ClientCodec
 	pending  map[uint64]*Call
 }
 
+// A ClientCodec implements writing of RPC requests and
+// reading of RPC responses for the client side of an RPC session.
+// The client calls WriteRequest to write a request to the connection
+// and calls ReadResponseHeader and ReadResponseBody in pairs
+// to read responses.  The client calls Close when finished with the
+// connection.
+type ClientCodec interface {
+	WriteRequest(*Request, interface{}) os.Error
+	ReadResponseHeader(*Response) os.Error
+	ReadResponseBody(interface{}) os.Error
+
+	Close() os.Error
+}
+
 func (client *Client) send(c *Call) {
 	// Register this call.
 	client.mutex.Lock()
-	if client.shutdown != nil {
+	if client.shutdown != nil || client.closing {
 		c.Error = client.shutdown
+		if client.closing {
+			c.Error = ErrShutdown
+		}
 		client.mutex.Unlock()
 		_ = c.Done <- c // do not block
 		return
@@ -58,19 +84,27 @@ func (client *Client) send(c *Call) {
 	client.sending.Lock()
 	request.Seq = c.seq
 	request.ServiceMethod = c.ServiceMethod
-	client.enc.Encode(request)
-	err := client.enc.Encode(c.Args)
+	err := client.codec.WriteRequest(request, c.Args)
+	client.sending.Unlock()
 	if err != nil {
-		panicln("rpc: client encode error:", err.String())
+		// Fail the call unless something else has completed it already.
+		client.mutex.Lock()
+		if client.pending[c.seq] != c || c.abandoned {
+			client.mutex.Unlock()
+			return
+		}
+		client.pending[c.seq] = c, false
+		client.mutex.Unlock()
+		c.Error = err
+		_ = c.Done <- c // do not block
 	}
-	client.sending.Unlock()
 }
 
 func (client *Client) input() {
 	var err os.Error
 	for err == nil {
 		response := new(Response)
-		err = client.dec.Decode(response)
+		err = client.codec.ReadResponseHeader(response)
 		if err != nil {
 			if err == os.EOF {
 				err = io.ErrUnexpectedEOF
@@ -81,8 +115,21 @@ func (client *Client) input() {
 		client.mutex.Lock()
 		c := client.pending[seq]
 		client.pending[seq] = c, false
+		abandoned := c != nil && c.abandoned
 		client.mutex.Unlock()
-		err = client.dec.Decode(c.Reply)
+		if c == nil {
+			// No call is waiting; its request may have failed
+			// part way through being sent.  Discard the reply.
+			err = client.codec.ReadResponseBody(new(struct{}))
+			continue
+		}
+		if abandoned {
+			// The caller has stopped waiting; read the reply
+			// into a scratch value rather than the caller's.
+			err = client.codec.ReadResponseBody(newReply(c.Reply))
+			continue
+		}
+		err = client.codec.ReadResponseBody(c.Reply)
 		// Empty strings should turn into nil os.Errors
 		if response.Error != "" {
 			c.Error = os.ErrorString(response.Error)
@@ -95,34 +142,101 @@ func (client *Client) input() {
 	}
 	// Terminate pending calls.
 	client.mutex.Lock()
+	closing := client.closing
+	if closing {
+		err = ErrShutdown
+	}
 	client.shutdown = err
 	for _, call := range client.pending {
+		if call.abandoned {
+			continue
+		}
 		call.Error = err
 		_ = call.Done <- call // do not block
 	}
 	client.mutex.Unlock()
-	log.Stderr("rpc: client protocol error:", err)
+	if !closing {
+		log.Stderr("rpc: client protocol error:", err)
+	}
+}
+
+// newReply returns a pointer to a new zero value of the type reply points to.
+func newReply(reply interface{}) interface{} {
+	if t, ok := reflect.Typeof(reply).(*reflect.PtrType); ok {
+		return _new(t).Interface()
+	}
+	return reply
+}
+
+// abandon completes c with err if its response has not arrived yet.
+func (client *Client) abandon(c *Call, err os.Error) {
+	client.mutex.Lock()
+	if client.pending[c.seq] != c || c.abandoned {
+		client.mutex.Unlock()
+		return
+	}
+	c.abandoned = true
+	c.Error = err
+	client.mutex.Unlock()
+	_ = c.Done <- c // do not block
 }
 
 // NewClient returns a new Client to handle requests to the
 // set of services at the other end of the connection.
+// It uses gob to encode the requests and decode the responses.
 func NewClient(conn io.ReadWriteCloser) *Client {
+	return NewClientWithCodec(&gobClientCodec{conn, gob.NewDecoder(conn), gob.NewEncoder(conn)})
+}
+
+// NewClientWithCodec is like NewClient but uses the specified
+// codec to encode requests and decode responses.
+func NewClientWithCodec(codec ClientCodec) *Client {
 	client := new(Client)
-	client.conn = conn
-	client.enc = gob.NewEncoder(conn)
-	client.dec = gob.NewDecoder(conn)
+	client.codec = codec
 	client.pending = make(map[uint64]*Call)
 	go client.input()
 	return client
 }
 
-// DialHTTP connects to an HTTP RPC server at the specified network address.
+type gobClientCodec struct {
+	rwc io.ReadWriteCloser
+	dec *gob.Decoder
+	enc *gob.Encoder
+}
+
+func (c *gobClientCodec) WriteRequest(r *Request, body interface{}) os.Error {
+	if err := c.enc.Encode(r); err != nil {
+		return err
+	}
+	return c.enc.Encode(body)
+}
+
+func (c *gobClientCodec) ReadResponseHeader(r *Response) os.Error {
+	return c.dec.Decode(r)
+}
+
+func (c *gobClientCodec) ReadResponseBody(body interface{}) os.Error {
+	return c.dec.Decode(body)
+}
+
+func (c *gobClientCodec) Close() os.Error {
+	return c.rwc.Close()
+}
+
+// DialHTTP connects to an HTTP RPC server at the specified network address
+// listening on the default HTTP RPC path.
 func DialHTTP(network, address string) (*Client, os.Error) {
+	return DialHTTPPath(network, address, DefaultRPCPath)
+}
+
+// DialHTTPPath connects to an HTTP RPC server
+// at the specified network address and path.
+func DialHTTPPath(network, address, path string) (*Client, os.Error) {
 	conn, err := net.Dial(network, "", address)
 	if err != nil {
 		return nil, err
 	}
-	io.WriteString(conn, "CONNECT "+rpcPath+" HTTP/1.0\n\n")
+	io.WriteString(conn, "CONNECT "+path+" HTTP/1.0\n\n")
 
 	// Require successful HTTP response
 	// before switching to RPC protocol.
@@ -184,3 +298,134 @@ func (client *Client) Call(serviceMethod string, args interface{}, reply interfa
 	call := <-client.Go(serviceMethod, args, reply, nil).Done
 	return call.Error
 }
+
+// CallTimeout is like Call but gives up after ns nanoseconds, returning
+// ErrTimeout.  A reply that arrives after the timeout is discarded.
+func (client *Client) CallTimeout(serviceMethod string, args interface{}, reply interface{}, ns int64) os.Error {
+	if client.shutdown != nil {
+		return client.shutdown
+	}
+	call := client.Go(serviceMethod, args, reply, nil)
+	if ns < 1 {
+		ns = 1
+	}
+	timeout := time.NewTicker(ns)
+	defer timeout.Stop()
+	select {
+	case call = <-call.Done:
+	case <-timeout.C:
+		client.abandon(call, ErrTimeout)
+		call = <-call.Done
+	}
+	return call.Error
+}
+
+// Cancel abandons an outstanding call started with Go.  Unless its
+// response has already arrived, the call completes at once with
+// ErrCanceled and a later response is discarded without touching
+// the call's Reply.
+func (client *Client) Cancel(call *Call) { client.abandon(call, ErrCanceled) }
+
+// Close closes the connection to the server.  Outstanding calls,
+// and any made afterwards, complete with ErrShutdown.
+func (client *Client) Close() os.Error {
+	client.mutex.Lock()
+	if client.closing {
+		client.mutex.Unlock()
+		return ErrShutdown
+	}
+	client.closing = true
+	client.mutex.Unlock()
+	return client.codec.Close()
+}
+
+// broken reports whether the client can no longer make calls.
+func (client *Client) broken() bool {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+	return client.shutdown != nil || client.closing
+}
+
+// A RedialClient makes calls through a Client that it dials on demand
+// and dials again after the connection breaks.  A call that fails
+// because the connection broke is not retried, since the server may
+// already have acted on it; the next call reconnects.
+type RedialClient struct {
+	mutex  sync.Mutex // protects client, closed
+	dial   func() (*Client, os.Error)
+	client *Client
+	closed bool
+}
+
+// NewRedialClient returns a RedialClient that uses dial,
+// for instance a closure calling Dial or DialHTTP, to connect.
+// No connection is made until the first call.
+func NewRedialClient(dial func() (*Client, os.Error)) *RedialClient {
+	return &RedialClient{dial: dial}
+}
+
+// Client returns a connected Client, dialing if there is none
+// or the last one has broken.
+func (rc *RedialClient) Client() (*Client, os.Error) {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+	if rc.closed {
+		return nil, ErrShutdown
+	}
+	if rc.client != nil && !rc.client.broken() {
+		return rc.client, nil
+	}
+	client, err := rc.dial()
+	if err != nil {
+		return nil, err
+	}
+	rc.client = client
+	return client, nil
+}
+
+// Go is like Client.Go on the current connection.  If it cannot
+// connect, the returned Call has already completed with the error.
+func (rc *RedialClient) Go(serviceMethod string, args interface{}, reply interface{}, done chan *Call) *Call {
+	client, err := rc.Client()
+	if err != nil {
+		c := &Call{ServiceMethod: serviceMethod, Args: args, Reply: reply, Error: err, Done: done}
+		if c.Done == nil {
+			c.Done = make(chan *Call, 1)
+		}
+		_ = c.Done <- c // do not block
+		return c
+	}
+	return client.Go(serviceMethod, args, reply, done)
+}
+
+// Call is like Client.Call on the current connection.
+func (rc *RedialClient) Call(serviceMethod string, args interface{}, reply interface{}) os.Error {
+	client, err := rc.Client()
+	if err != nil {
+		return err
+	}
+	return client.Call(serviceMethod, args, reply)
+}
+
+// CallTimeout is like Client.CallTimeout on the current connection.
+func (rc *RedialClient) CallTimeout(serviceMethod string, args interface{}, reply interface{}, ns int64) os.Error {
+	client, err := rc.Client()
+	if err != nil {
+		return err
+	}
+	return client.CallTimeout(serviceMethod, args, reply, ns)
+}
+
+// Close closes the current connection, if any, and stops further dialing.
+func (rc *RedialClient) Close() os.Error {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+	if rc.closed {
+		return ErrShutdown
+	}
+	rc.closed = true
+	if rc.client == nil {
+		return nil
+	}
+	return rc.client.Close()
+}
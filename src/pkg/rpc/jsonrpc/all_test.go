@@ -0,0 +1,168 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package jsonrpc
+
+import (
+	"bufio"
+	"io"
+	"log"
+	"net"
+	"once"
+	"os"
+	"rpc"
+	"strings"
+	"testing"
+)
+
+type Args struct {
+	A, B int
+}
+
+type Reply struct {
+	C int
+}
+
+type Arith int
+
+func (t *Arith) Add(args *Args, reply *Reply) os.Error {
+	reply.C = args.A + args.B
+	return nil
+}
+
+func (t *Arith) Mul(args *Args, reply *Reply) os.Error {
+	reply.C = args.A * args.B
+	return nil
+}
+
+func (t *Arith) Div(args *Args, reply *Reply) os.Error {
+	if args.B == 0 {
+		return os.ErrorString("divide by zero")
+	}
+	reply.C = args.A / args.B
+	return nil
+}
+
+var serverAddr string
+
+func startServer() {
+	rpc.Register(new(Arith))
+
+	l, e := net.Listen("tcp", "127.0.0.1:0") // any available address
+	if e != nil {
+		log.Exitf("net.Listen tcp :0: %v", e)
+	}
+	serverAddr = l.Addr().String()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				log.Exit("accept:", err)
+			}
+			ServeConn(conn)
+		}
+	}()
+}
+
+func TestClient(t *testing.T) {
+	once.Do(startServer)
+
+	client, err := Dial("tcp", serverAddr)
+	if err != nil {
+		t.Fatal("dialing", err)
+	}
+
+	args := &Args{7, 8}
+	reply := new(Reply)
+	err = client.Call("Arith.Add", args, reply)
+	if err != nil {
+		t.Errorf("Add: expected no error but got string %q", err.String())
+	}
+	if reply.C != args.A+args.B {
+		t.Errorf("Add: expected %d got %d", args.A+args.B, reply.C)
+	}
+
+	// Out of order.
+	mulReply := new(Reply)
+	mulCall := client.Go("Arith.Mul", args, mulReply, nil)
+	addReply := new(Reply)
+	addCall := client.Go("Arith.Add", args, addReply, nil)
+	addCall = <-addCall.Done
+	if addCall.Error != nil || addReply.C != args.A+args.B {
+		t.Errorf("Add: got %d, %v", addReply.C, addCall.Error)
+	}
+	mulCall = <-mulCall.Done
+	if mulCall.Error != nil || mulReply.C != args.A*args.B {
+		t.Errorf("Mul: got %d, %v", mulReply.C, mulCall.Error)
+	}
+
+	args = &Args{7, 0}
+	err = client.Call("Arith.Div", args, reply)
+	if err == nil || err.String() != "divide by zero" {
+		t.Error("Div: expected divide by zero error; got", err)
+	}
+
+	err = client.Call("Arith.Unknown", args, reply)
+	if err == nil || strings.Index(err.String(), "method") < 0 {
+		t.Error("expected error about method; got", err)
+	}
+}
+
+type serverTest struct {
+	request, id, result, error string
+}
+
+// Talk to the server the way a client in another language would.
+func TestServer(t *testing.T) {
+	once.Do(startServer)
+
+	conn, err := net.Dial("tcp", "", serverAddr)
+	if err != nil {
+		t.Fatal("dialing:", err)
+	}
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+
+	tests := []serverTest{
+		serverTest{`{"method": "Arith.Add", "params": [{"a": 1, "b": 2}], "id": "x"}`, `"x"`, `{"C":3}`, "null"},
+		serverTest{`{"id": 7, "params": [{"A": 6, "B": 7}], "method": "Arith.Mul"}`, "7", `{"C":42}`, "null"},
+		serverTest{`{"method": "Arith.Div", "params": [{"A": 1, "B": 0}], "id": [1]}`, "[1]", "null", `"divide by zero"`},
+		serverTest{`{"method": "Arith.Add", "params": [], "id": 8}`, "8", "null", `"jsonrpc: request must have exactly one parameter"`},
+	}
+	for _, tt := range tests {
+		io.WriteString(conn, tt.request)
+		s, err := readObject(r)
+		if err != nil {
+			t.Fatal("reading response:", err)
+		}
+		m, err := members(s)
+		if err != nil {
+			t.Fatalf("bad response %s: %s", s, err)
+		}
+		if m["id"] != tt.id || m["result"] != tt.result || m["error"] != tt.error {
+			t.Errorf("%s: got %s", tt.request, s)
+		}
+	}
+}
+
+func TestElements(t *testing.T) {
+	a, err := elements(` [ 1, "a,]", {"b": [2, 3]}, null ] `)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"1", `"a,]"`, `{"b": [2, 3]}`, "null"}
+	if len(a) != len(want) {
+		t.Fatalf("got %q", a)
+	}
+	for i := range a {
+		if a[i] != want[i] {
+			t.Errorf("element %d: got %q want %q", i, a[i], want[i])
+		}
+	}
+	for _, s := range []string{"", "[", "[1,", "[1 2]", `["a]`, "{}"} {
+		if _, err := elements(s); err == nil {
+			t.Errorf("elements(%q) succeeded", s)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// The jsonrpc package implements a JSON-RPC 1.0 ClientCodec and
+// ServerCodec for the rpc package, so that services registered with
+// rpc can be called by clients written in other languages.
+//
+// A request is an object with members "method", naming the service
+// and method as "Service.Method", "params", an array holding the
+// single argument, and "id".  The response echoes the id and holds
+// the reply in "result" or an error string in "error".
+package jsonrpc
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"json"
+	"net"
+	"os"
+	"rpc"
+	"strconv"
+)
+
+type clientCodec struct {
+	r      *bufio.Reader
+	c      io.ReadWriteCloser
+	result string // result of the response being read
+}
+
+// NewClientCodec returns a new rpc.ClientCodec using JSON-RPC on conn.
+func NewClientCodec(conn io.ReadWriteCloser) rpc.ClientCodec {
+	return &clientCodec{r: bufio.NewReader(conn), c: conn}
+}
+
+func (c *clientCodec) WriteRequest(r *rpc.Request, param interface{}) os.Error {
+	var buf bytes.Buffer
+	buf.WriteString(`{"method":` + json.Quote(r.ServiceMethod) + `,"params":[`)
+	if err := json.Marshal(&buf, param); err != nil {
+		return err
+	}
+	buf.WriteString(`],"id":` + strconv.Uitoa64(r.Seq) + "}\n")
+	_, err := c.c.Write(buf.Bytes())
+	return err
+}
+
+func (c *clientCodec) ReadResponseHeader(r *rpc.Response) os.Error {
+	c.result = ""
+	s, err := readObject(c.r)
+	if err != nil {
+		return err
+	}
+	m, err := members(s)
+	if err != nil {
+		return err
+	}
+	seq, err := strconv.Atoui64(m["id"])
+	if err != nil {
+		return os.ErrorString("jsonrpc: invalid response id " + strconv.Quote(m["id"]))
+	}
+	r.Seq = seq
+	r.Error = ""
+	if e := m["error"]; e != "" && e != "null" {
+		if msg, ok := json.Unquote(e); ok {
+			r.Error = msg
+		} else {
+			// Not a string; pass the JSON text along.
+			r.Error = e
+		}
+	}
+	c.result = m["result"]
+	return nil
+}
+
+func (c *clientCodec) ReadResponseBody(x interface{}) os.Error {
+	if c.result == "" || c.result == "null" {
+		return nil
+	}
+	return unmarshal(c.result, x)
+}
+
+func (c *clientCodec) Close() os.Error {
+	return c.c.Close()
+}
+
+// NewClient returns a new rpc.Client to handle requests to the
+// set of services at the other end of the connection.
+func NewClient(conn io.ReadWriteCloser) *rpc.Client {
+	return rpc.NewClientWithCodec(NewClientCodec(conn))
+}
+
+// Dial connects to a JSON-RPC server at the specified network address.
+func Dial(network, address string) (*rpc.Client, os.Error) {
+	conn, err := net.Dial(network, "", address)
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(conn), nil
+}
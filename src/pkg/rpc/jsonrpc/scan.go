@@ -0,0 +1,211 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package jsonrpc
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"json"
+	"os"
+	"strconv"
+)
+
+var errMalformed = os.ErrorString("jsonrpc: malformed JSON")
+
+// A scanner follows the nesting of a JSON object or array
+// one byte at a time, so that its end can be found in a stream.
+type scanner struct {
+	depth    int
+	inString bool
+	escaped  bool
+}
+
+// step consumes c and reports whether it closed the outermost value.
+func (s *scanner) step(c byte) bool {
+	switch {
+	case s.escaped:
+		s.escaped = false
+	case s.inString:
+		switch c {
+		case '\\':
+			s.escaped = true
+		case '"':
+			s.inString = false
+		}
+	case c == '"':
+		s.inString = true
+	case c == '{' || c == '[':
+		s.depth++
+	case c == '}' || c == ']':
+		s.depth--
+		return s.depth == 0
+	}
+	return false
+}
+
+func isSpace(c byte) bool { return c == ' ' || c == '\t' || c == '\r' || c == '\n' }
+
+// readObject reads the text of the next JSON object from r.
+func readObject(r *bufio.Reader) (string, os.Error) {
+	var buf bytes.Buffer
+	var s scanner
+	for {
+		c, err := r.ReadByte()
+		if err != nil {
+			if err == os.EOF && buf.Len() > 0 {
+				err = io.ErrUnexpectedEOF
+			}
+			return "", err
+		}
+		if buf.Len() == 0 {
+			if isSpace(c) {
+				continue
+			}
+			if c != '{' {
+				return "", errMalformed
+			}
+		}
+		buf.WriteByte(c)
+		if s.step(c) {
+			return buf.String(), nil
+		}
+	}
+	panic("unreachable")
+}
+
+func skipSpace(s string, i int) int {
+	for i < len(s) && isSpace(s[i]) {
+		i++
+	}
+	return i
+}
+
+// skipValue returns the index just past the JSON value
+// that starts at s[i], or -1 if there is none.  The value
+// is only delimited here; json.Unmarshal checks its syntax.
+func skipValue(s string, i int) int {
+	if i >= len(s) {
+		return -1
+	}
+	switch s[i] {
+	case '{', '[':
+		var sc scanner
+		for ; i < len(s); i++ {
+			if sc.step(s[i]) {
+				return i + 1
+			}
+		}
+		return -1
+	case '"':
+		for i++; i < len(s); i++ {
+			switch s[i] {
+			case '\\':
+				i++
+			case '"':
+				return i + 1
+			}
+		}
+		return -1
+	}
+	// A number, true, false or null.
+	j := i
+	for j < len(s) && !isSpace(s[j]) && s[j] != ',' && s[j] != ']' && s[j] != '}' {
+		j++
+	}
+	if j == i {
+		return -1
+	}
+	return j
+}
+
+// members splits the JSON object s into the text of its
+// member values, keyed by name.
+func members(s string) (map[string]string, os.Error) {
+	i := skipSpace(s, 0)
+	if i >= len(s) || s[i] != '{' {
+		return nil, errMalformed
+	}
+	m := make(map[string]string)
+	i = skipSpace(s, i+1)
+	if i < len(s) && s[i] == '}' {
+		return m, nil
+	}
+	for {
+		j := skipValue(s, i)
+		if j < 0 || s[i] != '"' {
+			return nil, errMalformed
+		}
+		name, ok := json.Unquote(s[i:j])
+		if !ok {
+			return nil, errMalformed
+		}
+		i = skipSpace(s, j)
+		if i >= len(s) || s[i] != ':' {
+			return nil, errMalformed
+		}
+		i = skipSpace(s, i+1)
+		j = skipValue(s, i)
+		if j < 0 {
+			return nil, errMalformed
+		}
+		m[name] = s[i:j]
+		i = skipSpace(s, j)
+		if i >= len(s) {
+			return nil, errMalformed
+		}
+		if s[i] == '}' {
+			return m, nil
+		}
+		if s[i] != ',' {
+			return nil, errMalformed
+		}
+		i = skipSpace(s, i+1)
+	}
+	panic("unreachable")
+}
+
+// elements splits the JSON array s into the text of its elements.
+func elements(s string) ([]string, os.Error) {
+	i := skipSpace(s, 0)
+	if i >= len(s) || s[i] != '[' {
+		return nil, errMalformed
+	}
+	var a []string
+	i = skipSpace(s, i+1)
+	if i < len(s) && s[i] == ']' {
+		return a, nil
+	}
+	for {
+		j := skipValue(s, i)
+		if j < 0 {
+			return nil, errMalformed
+		}
+		b := make([]string, len(a)+1)
+		copy(b, a)
+		b[len(a)] = s[i:j]
+		a = b
+		i = skipSpace(s, j)
+		if i >= len(s) {
+			return nil, errMalformed
+		}
+		if s[i] == ']' {
+			return a, nil
+		}
+		if s[i] != ',' {
+			return nil, errMalformed
+		}
+		i = skipSpace(s, i+1)
+	}
+	panic("unreachable")
+}
+
+// unmarshal decodes the JSON text s into v.
+func unmarshal(s string, v interface{}) os.Error {
+	if ok, errtok := json.Unmarshal(s, v); !ok {
+		return os.ErrorString("jsonrpc: cannot decode JSON at " + strconv.Quote(errtok))
+	}
+	return nil
+}
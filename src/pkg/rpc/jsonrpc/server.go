@@ -0,0 +1,111 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package jsonrpc
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"json"
+	"os"
+	"rpc"
+	"sync"
+)
+
+type serverCodec struct {
+	r      *bufio.Reader
+	c      io.ReadWriteCloser
+	params string // params of the request being read
+
+	// JSON-RPC clients may use any JSON value as an id, so the
+	// codec numbers the requests itself and keeps the text of each
+	// id until its response is written.
+	mutex   sync.Mutex // protects seq, pending
+	seq     uint64
+	pending map[uint64]string
+}
+
+// NewServerCodec returns a new rpc.ServerCodec using JSON-RPC on conn.
+func NewServerCodec(conn io.ReadWriteCloser) rpc.ServerCodec {
+	return &serverCodec{
+		r:       bufio.NewReader(conn),
+		c:       conn,
+		pending: make(map[uint64]string),
+	}
+}
+
+func (c *serverCodec) ReadRequestHeader(r *rpc.Request) os.Error {
+	c.params = ""
+	s, err := readObject(c.r)
+	if err != nil {
+		return err
+	}
+	m, err := members(s)
+	if err != nil {
+		return err
+	}
+	id, ok := m["id"]
+	if !ok {
+		id = "null"
+	}
+	c.mutex.Lock()
+	c.seq++
+	c.pending[c.seq] = id
+	r.Seq = c.seq
+	c.mutex.Unlock()
+
+	method, ok := json.Unquote(m["method"])
+	if !ok {
+		return os.ErrorString("jsonrpc: request has no method")
+	}
+	r.ServiceMethod = method
+	c.params = m["params"]
+	return nil
+}
+
+func (c *serverCodec) ReadRequestBody(x interface{}) os.Error {
+	params, err := elements(c.params)
+	if err != nil {
+		return err
+	}
+	if len(params) != 1 {
+		return os.ErrorString("jsonrpc: request must have exactly one parameter")
+	}
+	return unmarshal(params[0], x)
+}
+
+func (c *serverCodec) WriteResponse(r *rpc.Response, x interface{}) os.Error {
+	c.mutex.Lock()
+	id, ok := c.pending[r.Seq]
+	c.pending[r.Seq] = "", false
+	c.mutex.Unlock()
+	if !ok {
+		// The request could not be read far enough to find its id.
+		id = "null"
+	}
+	var buf bytes.Buffer
+	buf.WriteString(`{"id":` + id + `,"result":`)
+	if r.Error == "" {
+		if err := json.Marshal(&buf, x); err != nil {
+			return err
+		}
+		buf.WriteString(`,"error":null}` + "\n")
+	} else {
+		buf.WriteString(`null,"error":` + json.Quote(r.Error) + "}\n")
+	}
+	_, err := c.c.Write(buf.Bytes())
+	return err
+}
+
+func (c *serverCodec) Close() os.Error {
+	return c.c.Close()
+}
+
+// ServeConn runs the JSON-RPC server on a single connection, serving
+// the services registered with rpc.Register until the client hangs up.
+// Like rpc.ServeConn, it returns at once and serves in a new goroutine.
+func ServeConn(conn io.ReadWriteCloser) {
+	rpc.ServeCodec(NewServerCodec(conn))
+}
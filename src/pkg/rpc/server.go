@@ -8,7 +8,9 @@
 	as a service with the name of the type of the object.  After registration, public
 	methods of the object will be accessible remotely.  A server may register multiple
 	objects (services) of different types but it is an error to register multiple
-	objects of the same type.
+	objects of the same type, unless each is given its own name with RegisterName.
+	The package-level functions use DefaultServer; a program needing separate
+	sets of services can create more with NewServer.
 
 	Only methods that satisfy these criteria will be made available for remote access;
 	other methods will be ignored:
@@ -36,7 +38,10 @@
 	Call waits for the remote call to complete; Go launches the call asynchronously
 	and returns a channel that will signal completion.
 
-	Package "gob" is used to transport the data.
+	By default package "gob" is used to transport the data.  Other encodings
+	may be supplied by implementing ServerCodec and ClientCodec and passing
+	them to ServeCodec and NewClientWithCodec; package "rpc/jsonrpc" provides
+	one that speaks JSON-RPC.
 
 	Here is a simple example.  A server wishes to export an object of type Arith:
 
@@ -115,6 +120,7 @@ import (
 	"net"
 	"os"
 	"reflect"
+	"strconv"
 	"strings"
 	"sync"
 	"unicode"
@@ -158,15 +164,20 @@ type Response struct {
 	Error         string // error, if any.
 }
 
-type serverType struct {
+// Server represents an RPC Server.
+type Server struct {
 	sync.Mutex // protects the serviceMap
 	serviceMap map[string]*service
 }
 
-// This variable is a global whose "public" methods are really private methods
-// called from the global functions of this package: rpc.Register, rpc.ServeConn, etc.
-// For example, rpc.Register() calls server.add().
-var server = &serverType{serviceMap: make(map[string]*service)}
+// NewServer returns a new Server.
+func NewServer() *Server {
+	return &Server{serviceMap: make(map[string]*service)}
+}
+
+// DefaultServer is the default instance of *Server, used by the global
+// functions of this package: rpc.Register, rpc.ServeConn, etc.
+var DefaultServer = NewServer()
 
 // Is this a publicly visible - upper case - name?
 func isPublic(name string) bool {
@@ -174,7 +185,26 @@ func isPublic(name string) bool {
 	return unicode.IsUpper(rune)
 }
 
-func (server *serverType) register(rcvr interface{}) os.Error {
+// Register publishes in the server the set of methods of the
+// receiver value that satisfy the following conditions:
+//	- public method
+//	- two arguments, both pointers to public structs
+//	- one return value of type os.Error
+// It returns an error if the receiver is not public or has no
+// suitable methods.  The service is named after the receiver's type.
+func (server *Server) Register(rcvr interface{}) os.Error {
+	return server.register(rcvr, "", false)
+}
+
+// RegisterName is like Register but uses the provided name for the
+// service instead of the receiver's type name, so that several values
+// of one type can be published side by side.  The name may not be
+// empty or contain a period.
+func (server *Server) RegisterName(name string, rcvr interface{}) os.Error {
+	return server.register(rcvr, name, true)
+}
+
+func (server *Server) register(rcvr interface{}, name string, useName bool) os.Error {
 	server.Lock()
 	defer server.Unlock()
 	if server.serviceMap == nil {
@@ -183,14 +213,21 @@ func (server *serverType) register(rcvr interface{}) os.Error {
 	s := new(service)
 	s.typ = reflect.Typeof(rcvr)
 	s.rcvr = reflect.NewValue(rcvr)
-	sname := reflect.Indirect(s.rcvr).Type().Name()
-	if sname == "" {
-		log.Exit("rpc: no service name for type", s.typ.String())
-	}
-	if !isPublic(sname) {
-		s := "rpc Register: type " + sname + " is not public"
-		log.Stderr(s)
-		return os.ErrorString(s)
+	sname := name
+	if useName {
+		if sname == "" || strings.Index(sname, ".") >= 0 {
+			return os.ErrorString("rpc RegisterName: invalid service name " + strconv.Quote(sname))
+		}
+	} else {
+		sname = reflect.Indirect(s.rcvr).Type().Name()
+		if sname == "" {
+			log.Exit("rpc: no service name for type", s.typ.String())
+		}
+		if !isPublic(sname) {
+			s := "rpc Register: type " + sname + " is not public"
+			log.Stderr(s)
+			return os.ErrorString(s)
+		}
 	}
 	if _, present := server.serviceMap[sname]; present {
 		return os.ErrorString("rpc: service already defined: " + sname)
@@ -272,7 +309,7 @@ func _new(t *reflect.PtrType) *reflect.PtrValue {
 	return v
 }
 
-func sendResponse(sending *sync.Mutex, req *Request, reply interface{}, enc *gob.Encoder, errmsg string) {
+func sendResponse(sending *sync.Mutex, req *Request, reply interface{}, codec ServerCodec, errmsg string) {
 	resp := new(Response)
 	// Encode the response header
 	resp.ServiceMethod = req.ServiceMethod
@@ -281,13 +318,11 @@ func sendResponse(sending *sync.Mutex, req *Request, reply interface{}, enc *gob
 	}
 	resp.Seq = req.Seq
 	sending.Lock()
-	enc.Encode(resp)
-	// Encode the reply value.
-	enc.Encode(reply)
+	codec.WriteResponse(resp, reply)
 	sending.Unlock()
 }
 
-func (s *service) call(sending *sync.Mutex, mtype *methodType, req *Request, argv, replyv reflect.Value, enc *gob.Encoder) {
+func (s *service) call(sending *sync.Mutex, mtype *methodType, req *Request, argv, replyv reflect.Value, codec ServerCodec) {
 	mtype.Lock()
 	mtype.numCalls++
 	mtype.Unlock()
@@ -300,30 +335,57 @@ func (s *service) call(sending *sync.Mutex, mtype *methodType, req *Request, arg
 	if errInter != nil {
 		errmsg = errInter.(os.Error).String()
 	}
-	sendResponse(sending, req, replyv.Interface(), enc, errmsg)
+	sendResponse(sending, req, replyv.Interface(), codec, errmsg)
+}
+
+type gobServerCodec struct {
+	rwc io.ReadWriteCloser
+	dec *gob.Decoder
+	enc *gob.Encoder
+}
+
+func newGobServerCodec(conn io.ReadWriteCloser) *gobServerCodec {
+	return &gobServerCodec{conn, gob.NewDecoder(conn), gob.NewEncoder(conn)}
+}
+
+func (c *gobServerCodec) ReadRequestHeader(r *Request) os.Error {
+	return c.dec.Decode(r)
+}
+
+func (c *gobServerCodec) ReadRequestBody(body interface{}) os.Error {
+	return c.dec.Decode(body)
+}
+
+func (c *gobServerCodec) WriteResponse(r *Response, body interface{}) os.Error {
+	if err := c.enc.Encode(r); err != nil {
+		return err
+	}
+	return c.enc.Encode(body)
 }
 
-func (server *serverType) input(conn io.ReadWriteCloser) {
-	dec := gob.NewDecoder(conn)
-	enc := gob.NewEncoder(conn)
+func (c *gobServerCodec) Close() os.Error {
+	return c.rwc.Close()
+}
+
+func (server *Server) input(codec ServerCodec) {
 	sending := new(sync.Mutex)
 	for {
 		// Grab the request header.
 		req := new(Request)
-		err := dec.Decode(req)
+		err := codec.ReadRequestHeader(req)
 		if err != nil {
 			if err == os.EOF || err == io.ErrUnexpectedEOF {
 				log.Stderr("rpc: ", err)
 				break
 			}
 			s := "rpc: server cannot decode request: " + err.String()
-			sendResponse(sending, req, invalidRequest, enc, s)
+			sendResponse(sending, req, invalidRequest, codec, s)
 			continue
 		}
 		serviceMethod := strings.Split(req.ServiceMethod, ".", 0)
 		if len(serviceMethod) != 2 {
 			s := "rpc: service/method request ill:formed: " + req.ServiceMethod
-			sendResponse(sending, req, invalidRequest, enc, s)
+			sendResponse(sending, req, invalidRequest, codec, s)
 			continue
 		}
 		// Look up the request.
@@ -332,68 +394,105 @@ func (server *serverType) input(conn io.ReadWriteCloser) {
 		server.Unlock()
 		if !ok {
 			s := "rpc: can't find service " + req.ServiceMethod
-			sendResponse(sending, req, invalidRequest, enc, s)
+			sendResponse(sending, req, invalidRequest, codec, s)
 			continue
 		}
 		mtype, ok := service.method[serviceMethod[1]]
 		if !ok {
 			s := "rpc: can't find method " + req.ServiceMethod
-			sendResponse(sending, req, invalidRequest, enc, s)
+			sendResponse(sending, req, invalidRequest, codec, s)
 			continue
 		}
 		// Decode the argument value.
 		argv := _new(mtype.argType)
 		replyv := _new(mtype.replyType)
-		err = dec.Decode(argv.Interface())
+		err = codec.ReadRequestBody(argv.Interface())
 		if err != nil {
 			log.Stderr("rpc: tearing down", serviceMethod[0], "connection:", err)
-			sendResponse(sending, req, replyv.Interface(), enc, err.String())
+			sendResponse(sending, req, replyv.Interface(), codec, err.String())
 			continue
 		}
-		go service.call(sending, mtype, req, argv, replyv, enc)
+		go service.call(sending, mtype, req, argv, replyv, codec)
 	}
-	conn.Close()
+	codec.Close()
 }
 
-func (server *serverType) accept(lis net.Listener) {
+// ServeConn runs the server on a single connection.  When the connection
+// completes, service terminates.  ServeConn returns at once; requests
+// are served in a new goroutine.
+func (server *Server) ServeConn(conn io.ReadWriteCloser) {
+	go server.input(newGobServerCodec(conn))
+}
+
+// ServeCodec is like ServeConn but uses the specified codec to
+// decode requests and encode responses.
+func (server *Server) ServeCodec(codec ServerCodec) { go server.input(codec) }
+
+// Accept accepts connections on the listener and serves requests
+// for each incoming connection.  Accept blocks; the caller typically
+// invokes it in a go statement.
+func (server *Server) Accept(lis net.Listener) {
 	for {
 		conn, err := lis.Accept()
 		if err != nil {
 			log.Exit("rpc.Serve: accept:", err.String()) // TODO(r): exit?
 		}
-		go server.input(conn)
+		go server.input(newGobServerCodec(conn))
 	}
 }
 
-// Register publishes in the server the set of methods of the
-// receiver value that satisfy the following conditions:
-//	- public method
-//	- two arguments, both pointers to public structs
-//	- one return value of type os.Error
-// It returns an error if the receiver is not public or has no
-// suitable methods.
-func Register(rcvr interface{}) os.Error { return server.register(rcvr) }
+// A ServerCodec implements reading of RPC requests and writing of
+// RPC responses for the server side of an RPC session.
+// The server calls ReadRequestHeader and ReadRequestBody in pairs
+// to read requests from the connection, and it calls WriteResponse to
+// write a response back.  The server calls Close when finished with the
+// connection.
+type ServerCodec interface {
+	ReadRequestHeader(*Request) os.Error
+	ReadRequestBody(interface{}) os.Error
+	WriteResponse(*Response, interface{}) os.Error
+
+	Close() os.Error
+}
 
-// ServeConn runs the server on a single connection.  When the connection
-// completes, service terminates.  ServeConn blocks; the caller typically
-// invokes it in a go statement.
-func ServeConn(conn io.ReadWriteCloser) { go server.input(conn) }
+// Register publishes the receiver's methods in the DefaultServer.
+func Register(rcvr interface{}) os.Error { return DefaultServer.Register(rcvr) }
+
+// RegisterName is like Register but uses the provided name for the type
+// instead of the receiver's concrete type.
+func RegisterName(name string, rcvr interface{}) os.Error {
+	return DefaultServer.RegisterName(name, rcvr)
+}
+
+// ServeConn runs the DefaultServer on a single connection.  When the connection
+// completes, service terminates.  ServeConn returns at once; requests
+// are served in a new goroutine.
+func ServeConn(conn io.ReadWriteCloser) { DefaultServer.ServeConn(conn) }
+
+// ServeCodec is like ServeConn but uses the specified codec to
+// decode requests and encode responses.
+func ServeCodec(codec ServerCodec) { DefaultServer.ServeCodec(codec) }
 
 // Accept accepts connections on the listener and serves requests
-// for each incoming connection.  Accept blocks; the caller typically
-// invokes it in a go statement.
-func Accept(lis net.Listener) { server.accept(lis) }
+// to DefaultServer for each incoming connection.  Accept blocks; the
+// caller typically invokes it in a go statement.
+func Accept(lis net.Listener) { DefaultServer.Accept(lis) }
+
+// Defaults used by HandleHTTP
+const (
+	DefaultRPCPath   = "/_goRPC_"
+	DefaultDebugPath = "/debug/rpc"
+)
 
-// Can connect to RPC service using HTTP CONNECT to rpcPath.
-var rpcPath string = "/_goRPC_"
-var debugPath string = "/debug/rpc"
+// The status line answering a successful HTTP CONNECT.
 var connected = "200 Connected to Go RPC"
 
-func serveHTTP(c *http.Conn, req *http.Request) {
+// ServeHTTP implements an http.Handler that answers RPC requests.
+func (server *Server) ServeHTTP(c *http.Conn, req *http.Request) {
 	if req.Method != "CONNECT" {
 		c.SetHeader("Content-Type", "text/plain; charset=utf-8")
 		c.WriteHeader(http.StatusMethodNotAllowed)
-		io.WriteString(c, "405 must CONNECT to "+rpcPath+"\n")
+		io.WriteString(c, "405 must CONNECT\n")
 		return
 	}
 	conn, _, err := c.Hijack()
@@ -402,12 +501,20 @@ func serveHTTP(c *http.Conn, req *http.Request) {
 		return
 	}
 	io.WriteString(conn, "HTTP/1.0 "+connected+"\n\n")
-	server.input(conn)
+	server.input(newGobServerCodec(conn))
+}
+
+// HandleHTTP registers an HTTP handler for RPC messages on rpcPath,
+// and a debugging handler on debugPath.
+// It is still necessary to invoke http.Serve(), typically in a go statement.
+func (server *Server) HandleHTTP(rpcPath, debugPath string) {
+	http.Handle(rpcPath, server)
+	http.Handle(debugPath, debugHTTP{server})
 }
 
-// HandleHTTP registers an HTTP handler for RPC messages.
+// HandleHTTP registers an HTTP handler for RPC messages to DefaultServer
+// on DefaultRPCPath and a debugging handler on DefaultDebugPath.
 // It is still necessary to invoke http.Serve(), typically in a go statement.
 func HandleHTTP() {
-	http.Handle(rpcPath, http.HandlerFunc(serveHTTP))
-	http.Handle(debugPath, http.HandlerFunc(debugHTTP))
+	DefaultServer.HandleHTTP(DefaultRPCPath, DefaultDebugPath)
 }
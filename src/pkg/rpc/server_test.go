@@ -5,6 +5,7 @@
 package rpc
 
 import (
+	"gob"
 	"http"
 	"log"
 	"net"
@@ -12,6 +13,7 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
 )
 
 var serverAddr string
@@ -217,6 +219,211 @@ func TestCheckBadType(t *testing.T) {
 	}
 }
 
+func TestNewServer(t *testing.T) {
+	server := NewServer()
+	if err := server.RegisterName("Calc", new(Arith)); err != nil {
+		t.Fatal("RegisterName:", err)
+	}
+	if err := server.RegisterName("Calc2", new(Arith)); err != nil {
+		t.Fatal("RegisterName second instance:", err)
+	}
+	if err := server.RegisterName("Calc", new(Arith)); err == nil {
+		t.Error("expected error registering Calc twice")
+	}
+	for _, name := range []string{"", "a.b"} {
+		if err := server.RegisterName(name, new(Arith)); err == nil {
+			t.Errorf("expected error registering name %q", name)
+		}
+	}
+
+	l, e := net.Listen("tcp", ":0") // any available address
+	if e != nil {
+		t.Fatalf("net.Listen tcp :0: %v", e)
+	}
+	go server.Accept(l)
+	server.HandleHTTP("/_newServerRPC_", "/debug/newServerRPC")
+
+	once.Do(startServer)
+	client, err := Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal("dialing", err)
+	}
+	httpClient, err := DialHTTPPath("tcp", httpServerAddr, "/_newServerRPC_")
+	if err != nil {
+		t.Fatal("dialing http", err)
+	}
+
+	for _, c := range []*Client{client, httpClient} {
+		args := &Args{7, 8}
+		for _, sname := range []string{"Calc", "Calc2"} {
+			reply := new(Reply)
+			err = c.Call(sname+".Add", args, reply)
+			if err != nil {
+				t.Errorf("%s.Add: expected no error but got string %q", sname, err.String())
+			} else if reply.C != args.A+args.B {
+				t.Errorf("%s.Add: expected %d got %d", sname, args.A+args.B, reply.C)
+			}
+		}
+
+		// The service registered in DefaultServer is not visible here.
+		err = c.Call("Arith.Add", args, new(Reply))
+		if err == nil {
+			t.Error("expected error calling Arith.Add on new server")
+		} else if strings.Index(err.String(), "service") < 0 {
+			t.Error("expected error about service; got", err)
+		}
+	}
+}
+
+// Slow answers after sleeping for A milliseconds.
+type Slow int
+
+func (t *Slow) Sleep(args *Args, reply *Reply) os.Error {
+	time.Sleep(int64(args.A) * 1e6)
+	reply.C = args.A
+	return nil
+}
+
+func startSlowServer(t *testing.T) string {
+	server := NewServer()
+	server.Register(new(Slow))
+	l, e := net.Listen("tcp", ":0") // any available address
+	if e != nil {
+		t.Fatalf("net.Listen tcp :0: %v", e)
+	}
+	go server.Accept(l)
+	return l.Addr().String()
+}
+
+func TestCallTimeout(t *testing.T) {
+	client, err := Dial("tcp", startSlowServer(t))
+	if err != nil {
+		t.Fatal("dialing", err)
+	}
+	defer client.Close()
+
+	reply := new(Reply)
+	err = client.CallTimeout("Slow.Sleep", &Args{A: 500}, reply, 50e6)
+	if err != ErrTimeout {
+		t.Fatal("expected timeout; got", err)
+	}
+	err = client.CallTimeout("Slow.Sleep", &Args{A: 1}, reply, 5*second)
+	if err != nil || reply.C != 1 {
+		t.Fatalf("expected reply 1; got %d, %v", reply.C, err)
+	}
+	// Wait for the late reply to the first call; it must be discarded.
+	time.Sleep(600e6)
+	if reply.C != 1 {
+		t.Errorf("late reply overwrote result: got %d", reply.C)
+	}
+}
+
+func TestCancel(t *testing.T) {
+	client, err := Dial("tcp", startSlowServer(t))
+	if err != nil {
+		t.Fatal("dialing", err)
+	}
+	defer client.Close()
+
+	reply := new(Reply)
+	call := client.Go("Slow.Sleep", &Args{A: 100}, reply, nil)
+	client.Cancel(call)
+	call = <-call.Done
+	if call.Error != ErrCanceled {
+		t.Fatal("expected canceled call; got", call.Error)
+	}
+	// The connection still works after the reply arrives.
+	time.Sleep(200e6)
+	if reply.C != 0 {
+		t.Errorf("canceled call got reply %d", reply.C)
+	}
+	err = client.Call("Slow.Sleep", &Args{A: 2}, reply)
+	if err != nil || reply.C != 2 {
+		t.Errorf("expected reply 2; got %d, %v", reply.C, err)
+	}
+}
+
+func TestClientClose(t *testing.T) {
+	client, err := Dial("tcp", startSlowServer(t))
+	if err != nil {
+		t.Fatal("dialing", err)
+	}
+	call := client.Go("Slow.Sleep", &Args{A: 500}, new(Reply), nil)
+	client.Close()
+	call = <-call.Done
+	if call.Error != ErrShutdown {
+		t.Error("pending call: expected ErrShutdown; got", call.Error)
+	}
+	err = client.Call("Slow.Sleep", &Args{A: 1}, new(Reply))
+	if err != ErrShutdown {
+		t.Error("call after Close: expected ErrShutdown; got", err)
+	}
+}
+
+// A failingCodec sends each request but reports the first as failed.
+type failingCodec struct {
+	ClientCodec
+	failed bool
+}
+
+func (c *failingCodec) WriteRequest(r *Request, body interface{}) os.Error {
+	err := c.ClientCodec.WriteRequest(r, body)
+	if !c.failed {
+		c.failed = true
+		return os.ErrorString("write failed")
+	}
+	return err
+}
+
+func TestReplyAfterWriteError(t *testing.T) {
+	conn, err := net.Dial("tcp", "", startSlowServer(t))
+	if err != nil {
+		t.Fatal("dialing", err)
+	}
+	codec := &gobClientCodec{conn, gob.NewDecoder(conn), gob.NewEncoder(conn)}
+	client := NewClientWithCodec(&failingCodec{ClientCodec: codec})
+	defer client.Close()
+	reply := new(Reply)
+	if err = client.Call("Slow.Sleep", &Args{A: 1}, reply); err == nil {
+		t.Error("expected write error")
+	}
+	// The reply to the failed call must not break the client.
+	err = client.Call("Slow.Sleep", &Args{A: 2}, reply)
+	if err != nil || reply.C != 2 {
+		t.Errorf("expected reply 2; got %d, %v", reply.C, err)
+	}
+}
+
+func TestRedialClient(t *testing.T) {
+	addr := startSlowServer(t)
+	dials := 0
+	rc := NewRedialClient(func() (*Client, os.Error) {
+		dials++
+		return Dial("tcp", addr)
+	})
+	defer rc.Close()
+
+	reply := new(Reply)
+	if err := rc.Call("Slow.Sleep", &Args{A: 1}, reply); err != nil {
+		t.Fatal("first call:", err)
+	}
+	client, err := rc.Client()
+	if err != nil {
+		t.Fatal("Client:", err)
+	}
+	client.Close()
+	if err := rc.Call("Slow.Sleep", &Args{A: 2}, reply); err != nil || reply.C != 2 {
+		t.Fatalf("call after redial: got %d, %v", reply.C, err)
+	}
+	if dials != 2 {
+		t.Errorf("expected 2 dials; got %d", dials)
+	}
+	rc.Close()
+	if err := rc.Call("Slow.Sleep", &Args{A: 1}, reply); err != ErrShutdown {
+		t.Error("call after Close: expected ErrShutdown; got", err)
+	}
+}
+
 type Bad int
 type local struct{}
 
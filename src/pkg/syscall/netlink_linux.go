@@ -0,0 +1,174 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Netlink sockets and messages
+
+package syscall
+
+import "unsafe"
+
+// Round the length of a netlink message up to align it properly.
+func nlmAlignOf(msglen int) int {
+	return (msglen + NLMSG_ALIGNTO - 1) & ^(NLMSG_ALIGNTO - 1)
+}
+
+// Round the length of a netlink route attribute up to align it properly.
+func rtaAlignOf(attrlen int) int {
+	return (attrlen + RTA_ALIGNTO - 1) & ^(RTA_ALIGNTO - 1)
+}
+
+// NetlinkRouteRequest represents the request message to receive
+// routing and link states from the kernel.
+type NetlinkRouteRequest struct {
+	Header NlMsghdr
+	Data   RtGenmsg
+}
+
+func (rr *NetlinkRouteRequest) toWireFormat() []byte {
+	b := (*[SizeofNlMsghdr + SizeofRtGenmsg]byte)(unsafe.Pointer(rr))
+	return b[0:int(rr.Header.Len)]
+}
+
+func newNetlinkRouteRequest(proto, seq, family int) []byte {
+	rr := new(NetlinkRouteRequest)
+	rr.Header.Len = uint32(NLMSG_HDRLEN + SizeofRtGenmsg)
+	rr.Header.Type = uint16(proto)
+	rr.Header.Flags = NLM_F_DUMP | NLM_F_REQUEST
+	rr.Header.Seq = uint32(seq)
+	rr.Data.Family = uint8(family)
+	return rr.toWireFormat()
+}
+
+// NetlinkRIB returns routing information base, as known as RIB,
+// which consists of network facility information, states and
+// parameters.  The proto argument is the request type, such as
+// RTM_GETLINK or RTM_GETADDR; family selects the address family,
+// AF_UNSPEC meaning all of them.
+func NetlinkRIB(proto, family int) ([]byte, int) {
+	s, e := Socket(AF_NETLINK, SOCK_RAW, NETLINK_ROUTE)
+	if e != 0 {
+		return nil, e
+	}
+	defer Close(s)
+
+	lsa := &SockaddrNetlink{Family: AF_NETLINK}
+	if e = Bind(s, lsa); e != 0 {
+		return nil, e
+	}
+	if e = Sendto(s, newNetlinkRouteRequest(proto, 1, family), 0, lsa); e != 0 {
+		return nil, e
+	}
+	sa, e := Getsockname(s)
+	if e != 0 {
+		return nil, e
+	}
+	pid := sa.(*SockaddrNetlink).Pid
+
+	var tab []byte
+	for done := false; !done; {
+		rb := make([]byte, Getpagesize())
+		nr, _, e := Recvfrom(s, rb, 0)
+		if e != 0 {
+			return nil, e
+		}
+		if nr < NLMSG_HDRLEN {
+			return nil, EINVAL
+		}
+		rb = rb[0:nr]
+		msgs, e := ParseNetlinkMessage(rb)
+		if e != 0 {
+			return nil, e
+		}
+		for _, m := range msgs {
+			if m.Header.Seq != 1 || m.Header.Pid != pid {
+				return nil, EINVAL
+			}
+			switch m.Header.Type {
+			case NLMSG_DONE:
+				done = true
+			case NLMSG_ERROR:
+				return nil, EINVAL
+			}
+		}
+		tab = appendBytes(tab, rb)
+	}
+	return tab, 0
+}
+
+func appendBytes(a, b []byte) []byte {
+	n := make([]byte, len(a)+len(b))
+	copy(n, a)
+	copy(n[len(a):], b)
+	return n
+}
+
+// NetlinkMessage represents a netlink message.
+type NetlinkMessage struct {
+	Header NlMsghdr
+	Data   []byte
+}
+
+// ParseNetlinkMessage parses b as an array of netlink messages
+// and returns the slice containing the NetlinkMessage structures.
+func ParseNetlinkMessage(b []byte) ([]NetlinkMessage, int) {
+	var msgs []NetlinkMessage
+	for len(b) >= NLMSG_HDRLEN {
+		h := (*NlMsghdr)(unsafe.Pointer(&b[0]))
+		if int(h.Len) < NLMSG_HDRLEN || int(h.Len) > len(b) {
+			return nil, EINVAL
+		}
+		n := make([]NetlinkMessage, len(msgs)+1)
+		copy(n, msgs)
+		n[len(msgs)] = NetlinkMessage{*h, b[NLMSG_HDRLEN:int(h.Len)]}
+		msgs = n
+		l := nlmAlignOf(int(h.Len))
+		if l > len(b) {
+			l = len(b)
+		}
+		b = b[l:]
+	}
+	return msgs, 0
+}
+
+// NetlinkRouteAttr represents a netlink route attribute.
+type NetlinkRouteAttr struct {
+	Attr  RtAttr
+	Value []byte
+}
+
+// ParseNetlinkRouteAttr parses the payload of m, a link or
+// address message, as an array of netlink route attributes and
+// returns the slice containing the NetlinkRouteAttr structures.
+func ParseNetlinkRouteAttr(m *NetlinkMessage) ([]NetlinkRouteAttr, int) {
+	var hdrlen int
+	switch m.Header.Type {
+	case RTM_NEWLINK, RTM_DELLINK:
+		hdrlen = SizeofIfInfomsg
+	case RTM_NEWADDR, RTM_DELADDR:
+		hdrlen = SizeofIfAddrmsg
+	default:
+		return nil, EINVAL
+	}
+	if len(m.Data) < hdrlen {
+		return nil, EINVAL
+	}
+	b := m.Data[hdrlen:]
+	var attrs []NetlinkRouteAttr
+	for len(b) >= SizeofRtAttr {
+		a := (*RtAttr)(unsafe.Pointer(&b[0]))
+		if int(a.Len) < SizeofRtAttr || int(a.Len) > len(b) {
+			return nil, EINVAL
+		}
+		n := make([]NetlinkRouteAttr, len(attrs)+1)
+		copy(n, attrs)
+		n[len(attrs)] = NetlinkRouteAttr{*a, b[SizeofRtAttr:int(a.Len)]}
+		attrs = n
+		l := rtaAlignOf(int(a.Len))
+		if l > len(b) {
+			l = len(b)
+		}
+		b = b[l:]
+	}
+	return attrs, 0
+}
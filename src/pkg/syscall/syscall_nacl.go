@@ -226,8 +226,12 @@ const (
 	AF_INET = 1 + iota
 	AF_INET6
 	AF_UNIX
+	IPPROTO_IP
 	IPPROTO_TCP
+	IP_MULTICAST_LOOP
+	IP_MULTICAST_TTL
 	SOCK_DGRAM
+	SOCK_RAW
 	SOCK_STREAM
 	SOL_SOCKET
 	SOMAXCONN
@@ -237,6 +241,7 @@ const (
 	SO_RCVBUF
 	SO_REUSEADDR
 	SO_SNDBUF
+	SO_TYPE
 	TCP_NODELAY
 	WNOHANG
 	WSTOPPED
@@ -265,12 +270,18 @@ func Socket(domain, typ, proto int) (fd, errno int) {
 	return 0, ENACL
 }
 
+func GetsockoptInt(fd, level, opt int) (value, errno int) {
+	return 0, ENACL
+}
+
 func SetsockoptInt(fd, level, opt int, value int) (errno int) {
 	return ENACL
 }
 
 func Shutdown(fd, how int) (errno int) { return ENACL }
 
+func Dup(oldfd int) (fd int, errno int) { return 0, ENACL }
+
 func Recvfrom(fd int, p []byte, flags int) (n int, from Sockaddr, errno int) {
 	return 0, nil, ENACL
 }
@@ -279,6 +290,14 @@ func Sendto(fd int, p []byte, flags int, to Sockaddr) (errno int) {
 	return ENACL
 }
 
+func Recvmsg(fd int, p, oob []byte, flags int) (n, oobn int, recvflags int, from Sockaddr, errno int) {
+	return 0, 0, 0, nil, ENACL
+}
+
+func Sendmsg(fd int, p, oob []byte, to Sockaddr, flags int) (errno int) {
+	return ENACL
+}
+
 func SetsockoptTimeval(fd, level, opt int, tv *Timeval) (errno int) {
 	return ENACL
 }
@@ -13,7 +13,10 @@ Input to godefs.  See also mkerrors.sh and mkall.sh
 
 #include <dirent.h>
 #include <fcntl.h>
+#include <linux/netlink.h>
+#include <linux/rtnetlink.h>
 #include <linux/user.h>
+#include <net/if.h>
 #include <netinet/in.h>
 #include <netinet/tcp.h>
 #include <signal.h>
@@ -100,22 +103,83 @@ struct sockaddr_any {
 typedef struct sockaddr_in $RawSockaddrInet4;
 typedef struct sockaddr_in6 $RawSockaddrInet6;
 typedef struct sockaddr_un $RawSockaddrUnix;
+typedef struct sockaddr_nl $RawSockaddrNetlink;
 typedef struct sockaddr $RawSockaddr;
 typedef struct sockaddr_any $RawSockaddrAny;
 typedef socklen_t $_Socklen;
 typedef struct linger $Linger;
+typedef struct ip_mreqn $IPMreqn;
 typedef struct iovec $Iovec;
 typedef struct msghdr $Msghdr;
 typedef struct cmsghdr $Cmsghdr;
+typedef struct ucred $Ucred;
 
 enum {
 	$SizeofSockaddrInet4 = sizeof(struct sockaddr_in),
 	$SizeofSockaddrInet6 = sizeof(struct sockaddr_in6),
 	$SizeofSockaddrAny = sizeof(struct sockaddr_any),
 	$SizeofSockaddrUnix = sizeof(struct sockaddr_un),
+	$SizeofSockaddrNetlink = sizeof(struct sockaddr_nl),
 	$SizeofLinger = sizeof(struct linger),
+	$SizeofIPMreqn = sizeof(struct ip_mreqn),
 	$SizeofMsghdr = sizeof(struct msghdr),
 	$SizeofCmsghdr = sizeof(struct cmsghdr),
+	$SizeofUcred = sizeof(struct ucred),
+};
+
+// Netlink routing messages
+
+typedef struct nlmsghdr $NlMsghdr;
+typedef struct nlmsgerr $NlMsgerr;
+typedef struct rtgenmsg $RtGenmsg;
+typedef struct rtattr $RtAttr;
+typedef struct ifinfomsg $IfInfomsg;
+typedef struct ifaddrmsg $IfAddrmsg;
+
+enum {
+	$SizeofNlMsghdr = sizeof(struct nlmsghdr),
+	$SizeofNlMsgerr = sizeof(struct nlmsgerr),
+	$SizeofRtGenmsg = sizeof(struct rtgenmsg),
+	$SizeofRtAttr = sizeof(struct rtattr),
+	$SizeofIfInfomsg = sizeof(struct ifinfomsg),
+	$SizeofIfAddrmsg = sizeof(struct ifaddrmsg),
+};
+
+enum {
+	$NETLINK_ROUTE = NETLINK_ROUTE,
+	$NLM_F_REQUEST = NLM_F_REQUEST,
+	$NLM_F_MULTI = NLM_F_MULTI,
+	$NLM_F_ROOT = NLM_F_ROOT,
+	$NLM_F_MATCH = NLM_F_MATCH,
+	$NLM_F_DUMP = NLM_F_DUMP,
+	$NLMSG_ALIGNTO = NLMSG_ALIGNTO,
+	$NLMSG_HDRLEN = NLMSG_HDRLEN,
+	$NLMSG_NOOP = NLMSG_NOOP,
+	$NLMSG_ERROR = NLMSG_ERROR,
+	$NLMSG_DONE = NLMSG_DONE,
+	$RTA_ALIGNTO = RTA_ALIGNTO,
+	$RTM_NEWLINK = RTM_NEWLINK,
+	$RTM_DELLINK = RTM_DELLINK,
+	$RTM_GETLINK = RTM_GETLINK,
+	$RTM_NEWADDR = RTM_NEWADDR,
+	$RTM_DELADDR = RTM_DELADDR,
+	$RTM_GETADDR = RTM_GETADDR,
+	$IFLA_UNSPEC = IFLA_UNSPEC,
+	$IFLA_ADDRESS = IFLA_ADDRESS,
+	$IFLA_BROADCAST = IFLA_BROADCAST,
+	$IFLA_IFNAME = IFLA_IFNAME,
+	$IFLA_MTU = IFLA_MTU,
+	$IFA_UNSPEC = IFA_UNSPEC,
+	$IFA_ADDRESS = IFA_ADDRESS,
+	$IFA_LOCAL = IFA_LOCAL,
+	$IFA_LABEL = IFA_LABEL,
+	$IFA_BROADCAST = IFA_BROADCAST,
+	$IFF_UP = IFF_UP,
+	$IFF_BROADCAST = IFF_BROADCAST,
+	$IFF_LOOPBACK = IFF_LOOPBACK,
+	$IFF_POINTOPOINT = IFF_POINTOPOINT,
+	$IFF_RUNNING = IFF_RUNNING,
+	$IFF_MULTICAST = IFF_MULTICAST,
 };
 
 
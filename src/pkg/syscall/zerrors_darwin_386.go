@@ -395,6 +395,9 @@ const (
 	O_SYNC                    = 0x80
 	O_TRUNC                   = 0x400
 	O_WRONLY                  = 0x1
+	SCM_CREDS                 = 0x3
+	SCM_RIGHTS                = 0x1
+	SCM_TIMESTAMP             = 0x2
 	SHUT_RD                   = 0
 	SHUT_RDWR                 = 0x2
 	SHUT_WR                   = 0x1
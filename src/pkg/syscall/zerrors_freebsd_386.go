@@ -431,6 +431,10 @@ const (
 	O_TRUNC                   = 0x400
 	O_TTY_INIT                = 0x80000
 	O_WRONLY                  = 0x1
+	SCM_BINTIME               = 0x4
+	SCM_CREDS                 = 0x3
+	SCM_RIGHTS                = 0x1
+	SCM_TIMESTAMP             = 0x2
 	SHUT_RD                   = 0
 	SHUT_RDWR                 = 0x2
 	SHUT_WR                   = 0x1
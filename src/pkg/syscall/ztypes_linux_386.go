@@ -6,19 +6,62 @@ package syscall
 
 // Constants
 const (
-	sizeofPtr           = 0x4
-	sizeofShort         = 0x2
-	sizeofInt           = 0x4
-	sizeofLong          = 0x4
-	sizeofLongLong      = 0x8
-	PathMax             = 0x1000
-	SizeofSockaddrInet4 = 0x10
-	SizeofSockaddrInet6 = 0x1c
-	SizeofSockaddrAny   = 0x70
-	SizeofSockaddrUnix  = 0x6e
-	SizeofLinger        = 0x8
-	SizeofMsghdr        = 0x1c
-	SizeofCmsghdr       = 0xc
+	sizeofPtr             = 0x4
+	sizeofShort           = 0x2
+	sizeofInt             = 0x4
+	sizeofLong            = 0x4
+	sizeofLongLong        = 0x8
+	PathMax               = 0x1000
+	SizeofSockaddrInet4   = 0x10
+	SizeofSockaddrInet6   = 0x1c
+	SizeofSockaddrAny     = 0x70
+	SizeofSockaddrUnix    = 0x6e
+	SizeofSockaddrNetlink = 0xc
+	SizeofLinger          = 0x8
+	SizeofIPMreqn         = 0xc
+	SizeofMsghdr          = 0x1c
+	SizeofCmsghdr         = 0xc
+	SizeofUcred           = 0xc
+	SizeofNlMsghdr        = 0x10
+	SizeofNlMsgerr        = 0x14
+	SizeofRtGenmsg        = 0x1
+	SizeofRtAttr          = 0x4
+	SizeofIfInfomsg       = 0x10
+	SizeofIfAddrmsg       = 0x8
+	NETLINK_ROUTE         = 0
+	NLM_F_REQUEST         = 0x1
+	NLM_F_MULTI           = 0x2
+	NLM_F_ROOT            = 0x100
+	NLM_F_MATCH           = 0x200
+	NLM_F_DUMP            = 0x300
+	NLMSG_ALIGNTO         = 0x4
+	NLMSG_HDRLEN          = 0x10
+	NLMSG_NOOP            = 0x1
+	NLMSG_ERROR           = 0x2
+	NLMSG_DONE            = 0x3
+	RTA_ALIGNTO           = 0x4
+	RTM_NEWLINK           = 0x10
+	RTM_DELLINK           = 0x11
+	RTM_GETLINK           = 0x12
+	RTM_NEWADDR           = 0x14
+	RTM_DELADDR           = 0x15
+	RTM_GETADDR           = 0x16
+	IFLA_UNSPEC           = 0
+	IFLA_ADDRESS          = 0x1
+	IFLA_BROADCAST        = 0x2
+	IFLA_IFNAME           = 0x3
+	IFLA_MTU              = 0x4
+	IFA_UNSPEC            = 0
+	IFA_ADDRESS           = 0x1
+	IFA_LOCAL             = 0x2
+	IFA_LABEL             = 0x3
+	IFA_BROADCAST         = 0x4
+	IFF_UP                = 0x1
+	IFF_BROADCAST         = 0x2
+	IFF_LOOPBACK          = 0x8
+	IFF_POINTOPOINT       = 0x10
+	IFF_RUNNING           = 0x40
+	IFF_MULTICAST         = 0x1000
 )
 
 // Types
@@ -179,6 +222,13 @@ type RawSockaddrUnix struct {
 	Path   [108]int8
 }
 
+type RawSockaddrNetlink struct {
+	Family uint16
+	Pad    uint16
+	Pid    uint32
+	Groups uint32
+}
+
 type RawSockaddr struct {
 	Family uint16
 	Data   [14]int8
@@ -196,6 +246,12 @@ type Linger struct {
 	Linger int32
 }
 
+type IPMreqn struct {
+	Multiaddr [4]byte /* in_addr */
+	Address   [4]byte /* in_addr */
+	Ifindex   int32
+}
+
 type Iovec struct {
 	Base *byte
 	Len  uint32
@@ -217,6 +273,51 @@ type Cmsghdr struct {
 	Type  int32
 }
 
+type Ucred struct {
+	Pid int32
+	Uid uint32
+	Gid uint32
+}
+
+type NlMsghdr struct {
+	Len   uint32
+	Type  uint16
+	Flags uint16
+	Seq   uint32
+	Pid   uint32
+}
+
+type NlMsgerr struct {
+	Error int32
+	Msg   NlMsghdr
+}
+
+type RtGenmsg struct {
+	Family uint8
+}
+
+type RtAttr struct {
+	Len  uint16
+	Type uint16
+}
+
+type IfInfomsg struct {
+	Family     uint8
+	X__ifi_pad uint8
+	Type       uint16
+	Index      int32
+	Flags      uint32
+	Change     uint32
+}
+
+type IfAddrmsg struct {
+	Family    uint8
+	Prefixlen uint8
+	Flags     uint8
+	Scope     uint8
+	Index     uint32
+}
+
 type PtraceRegs struct {
 	Ebx      int32
 	Ecx      int32
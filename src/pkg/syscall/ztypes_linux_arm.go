@@ -52,9 +52,11 @@ const (
 	WCLONE                  = 0x80000000
 	WALL                    = 0x40000000
 	WNOTHREAD               = 0x20000000
+	AF_UNSPEC               = 0
 	AF_UNIX                 = 0x1
 	AF_INET                 = 0x2
 	AF_INET6                = 0xa
+	AF_NETLINK              = 0x10
 	SOCK_STREAM             = 0x1
 	SOCK_DGRAM              = 0x2
 	SOCK_RAW                = 0x3
@@ -69,14 +71,69 @@ const (
 	SO_RCVBUF               = 0x8
 	SO_SNDTIMEO             = 0x15
 	SO_RCVTIMEO             = 0x14
+	SO_TYPE                 = 0x3
+	SO_PASSCRED             = 0x10
+	SCM_RIGHTS              = 0x1
+	SCM_CREDENTIALS         = 0x2
+	IPPROTO_IP              = 0
 	IPPROTO_TCP             = 0x6
 	IPPROTO_UDP             = 0x11
 	TCP_NODELAY             = 0x1
+	IP_MULTICAST_IF         = 0x20
+	IP_MULTICAST_TTL        = 0x21
+	IP_MULTICAST_LOOP       = 0x22
+	IP_ADD_MEMBERSHIP       = 0x23
+	IP_DROP_MEMBERSHIP      = 0x24
 	SOMAXCONN               = 0x80
 	SizeofSockaddrInet4     = 0x10
 	SizeofSockaddrInet6     = 0x1c
 	SizeofSockaddrAny       = 0x1c
 	SizeofSockaddrUnix      = 0x6e
+	SizeofSockaddrNetlink   = 0xc
+	SizeofIPMreqn           = 0xc
+	SizeofMsghdr            = 0x1c
+	SizeofCmsghdr           = 0xc
+	SizeofUcred             = 0xc
+	SizeofNlMsghdr          = 0x10
+	SizeofNlMsgerr          = 0x14
+	SizeofRtGenmsg          = 0x1
+	SizeofRtAttr            = 0x4
+	SizeofIfInfomsg         = 0x10
+	SizeofIfAddrmsg         = 0x8
+	NETLINK_ROUTE           = 0
+	NLM_F_REQUEST           = 0x1
+	NLM_F_MULTI             = 0x2
+	NLM_F_ROOT              = 0x100
+	NLM_F_MATCH             = 0x200
+	NLM_F_DUMP              = 0x300
+	NLMSG_ALIGNTO           = 0x4
+	NLMSG_HDRLEN            = 0x10
+	NLMSG_NOOP              = 0x1
+	NLMSG_ERROR             = 0x2
+	NLMSG_DONE              = 0x3
+	RTA_ALIGNTO             = 0x4
+	RTM_NEWLINK             = 0x10
+	RTM_DELLINK             = 0x11
+	RTM_GETLINK             = 0x12
+	RTM_NEWADDR             = 0x14
+	RTM_DELADDR             = 0x15
+	RTM_GETADDR             = 0x16
+	IFLA_UNSPEC             = 0
+	IFLA_ADDRESS            = 0x1
+	IFLA_BROADCAST          = 0x2
+	IFLA_IFNAME             = 0x3
+	IFLA_MTU                = 0x4
+	IFA_UNSPEC              = 0
+	IFA_ADDRESS             = 0x1
+	IFA_LOCAL               = 0x2
+	IFA_LABEL               = 0x3
+	IFA_BROADCAST           = 0x4
+	IFF_UP                  = 0x1
+	IFF_BROADCAST           = 0x2
+	IFF_LOOPBACK            = 0x8
+	IFF_POINTOPOINT         = 0x10
+	IFF_RUNNING             = 0x40
+	IFF_MULTICAST           = 0x1000
 	PTRACE_TRACEME          = 0
 	PTRACE_PEEKTEXT         = 0x1
 	PTRACE_PEEKDATA         = 0x2
@@ -281,6 +338,13 @@ type RawSockaddrUnix struct {
 	Path   [108]int8
 }
 
+type RawSockaddrNetlink struct {
+	Family uint16
+	Pad    uint16
+	Pid    uint32
+	Groups uint32
+}
+
 type RawSockaddr struct {
 	Family uint16
 	Data   [14]int8
@@ -298,6 +362,78 @@ type Linger struct {
 	Linger int32
 }
 
+type IPMreqn struct {
+	Multiaddr [4]byte /* in_addr */
+	Address   [4]byte /* in_addr */
+	Ifindex   int32
+}
+
+type Iovec struct {
+	Base *byte
+	Len  uint32
+}
+
+type Msghdr struct {
+	Name       *byte
+	Namelen    uint32
+	Iov        *Iovec
+	Iovlen     uint32
+	Control    *byte
+	Controllen uint32
+	Flags      int32
+}
+
+type Cmsghdr struct {
+	Len   uint32
+	Level int32
+	Type  int32
+}
+
+type Ucred struct {
+	Pid int32
+	Uid uint32
+	Gid uint32
+}
+
+type NlMsghdr struct {
+	Len   uint32
+	Type  uint16
+	Flags uint16
+	Seq   uint32
+	Pid   uint32
+}
+
+type NlMsgerr struct {
+	Error int32
+	Msg   NlMsghdr
+}
+
+type RtGenmsg struct {
+	Family uint8
+}
+
+type RtAttr struct {
+	Len  uint16
+	Type uint16
+}
+
+type IfInfomsg struct {
+	Family     uint8
+	X__ifi_pad uint8
+	Type       uint16
+	Index      int32
+	Flags      uint32
+	Change     uint32
+}
+
+type IfAddrmsg struct {
+	Family    uint8
+	Prefixlen uint8
+	Flags     uint8
+	Scope     uint8
+	Index     uint32
+}
+
 type PtraceRegs struct {
 	Ebx      int32
 	Ecx      int32
@@ -10,6 +10,7 @@ import (
 	"io"
 	"net"
 	"os"
+	"strings"
 )
 
 type ProtocolError struct {
@@ -26,9 +27,11 @@ var (
 	ErrBadWebSocketLocation = &ProtocolError{"bad WebSocket-Location"}
 	ErrNoWebSocketProtocol  = &ProtocolError{"no WebSocket-Protocol"}
 	ErrBadWebSocketProtocol = &ProtocolError{"bad WebSocket-Protocol"}
+	ErrBadWebSocketAccept   = &ProtocolError{"bad Sec-WebSocket-Accept"}
 )
 
-// newClient creates a new Web Socket client connection.
+// newClient creates a new Web Socket client connection
+// using draft-75 framing.
 func newClient(resourceName, host, origin, location, protocol string, rwc io.ReadWriteCloser) (ws *Conn, err os.Error) {
 	br := bufio.NewReader(rwc)
 	bw := bufio.NewWriter(rwc)
@@ -41,6 +44,22 @@ func newClient(resourceName, host, origin, location, protocol string, rwc io.Rea
 	return
 }
 
+// newHybiClient creates a new Web Socket client connection
+// using hybi framing.
+func newHybiClient(resourceName, host, origin, location, protocol string, rwc io.ReadWriteCloser) (ws *Conn, err os.Error) {
+	br := bufio.NewReader(rwc)
+	bw := bufio.NewWriter(rwc)
+	err = hybiHandshake(resourceName, host, origin, protocol, br, bw)
+	if err != nil {
+		return
+	}
+	buf := bufio.NewReadWriter(br, bw)
+	ws = newConn(origin, location, protocol, buf, rwc)
+	ws.hybi = true
+	ws.client = true
+	return
+}
+
 /*
 	Dial opens a new client connection to a Web Socket.
 	A trivial example client is:
@@ -79,6 +98,25 @@ func Dial(url, protocol, origin string) (ws *Conn, err os.Error) {
 	return newClient(parsedUrl.Path, parsedUrl.Host, origin, url, protocol, client)
 }
 
+// DialHybi is like Dial but speaks the newer hybi version of the
+// protocol, with binary messages, fragmentation, ping/pong and the
+// close handshake.  The server must support hybi.
+func DialHybi(url, protocol, origin string) (ws *Conn, err os.Error) {
+	parsedUrl, err := http.ParseURL(url)
+	if err != nil {
+		return
+	}
+	client, err := net.Dial("tcp", "", parsedUrl.Host)
+	if err != nil {
+		return
+	}
+	ws, err = newHybiClient(parsedUrl.Path, parsedUrl.Host, origin, url, protocol, client)
+	if err != nil {
+		client.Close()
+	}
+	return
+}
+
 func handshake(resourceName, host, origin, location, protocol string, br *bufio.Reader, bw *bufio.Writer) (err os.Error) {
 	bw.WriteString("GET " + resourceName + " HTTP/1.1\r\n")
 	bw.WriteString("Upgrade: WebSocket\r\n")
@@ -134,3 +172,64 @@ func handshake(resourceName, host, origin, location, protocol string, br *bufio.
 	}
 	return
 }
+
+// hybiHandshake performs the client side of the hybi opening handshake.
+func hybiHandshake(resourceName, host, origin, protocol string, br *bufio.Reader, bw *bufio.Writer) (err os.Error) {
+	key := newKey()
+	bw.WriteString("GET " + resourceName + " HTTP/1.1\r\n")
+	bw.WriteString("Host: " + host + "\r\n")
+	bw.WriteString("Upgrade: websocket\r\n")
+	bw.WriteString("Connection: Upgrade\r\n")
+	bw.WriteString("Sec-WebSocket-Key: " + key + "\r\n")
+	bw.WriteString("Origin: " + origin + "\r\n")
+	bw.WriteString("Sec-WebSocket-Version: " + hybiVersion + "\r\n")
+	if protocol != "" {
+		bw.WriteString("Sec-WebSocket-Protocol: " + protocol + "\r\n")
+	}
+	bw.WriteString("\r\n")
+	if err = bw.Flush(); err != nil {
+		return
+	}
+	resp, err := http.ReadResponse(br, "GET")
+	if err != nil {
+		return
+	}
+	if resp.StatusCode != 101 {
+		return ErrBadStatus
+	}
+	upgrade, found := resp.Header["Upgrade"]
+	if !found {
+		return ErrNoUpgrade
+	}
+	if strings.ToLower(upgrade) != "websocket" {
+		return ErrBadUpgrade
+	}
+	connection, found := resp.Header["Connection"]
+	if !found || !hasToken(connection, "upgrade") {
+		return ErrBadUpgrade
+	}
+	if accept := resp.Header["Sec-Websocket-Accept"]; accept != getAcceptKey(key) {
+		return ErrBadWebSocketAccept
+	}
+	if protocol != "" {
+		ws_protocol, found := resp.Header["Sec-Websocket-Protocol"]
+		if !found {
+			return ErrNoWebSocketProtocol
+		}
+		if ws_protocol != protocol {
+			return ErrBadWebSocketProtocol
+		}
+	}
+	return
+}
+
+// hasToken reports whether the comma-separated header
+// value v contains token, ignoring case.
+func hasToken(v, token string) bool {
+	for _, t := range strings.Split(v, ",", 0) {
+		if strings.ToLower(strings.TrimSpace(t)) == token {
+			return true
+		}
+	}
+	return false
+}
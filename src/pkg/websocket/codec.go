@@ -0,0 +1,156 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package websocket
+
+import (
+	"bytes"
+	"gob"
+	"io/ioutil"
+	"json"
+	"os"
+)
+
+// Codec represents a symmetric pair of functions that implement a
+// codec for the messages of a Conn.  Marshal returns the payload of
+// the message encoding v and its payload type, TextFrame or
+// BinaryFrame; Unmarshal decodes such a payload into v.
+//
+// Each call to Send or Receive transfers exactly one message, so the
+// boundaries of the values are those of the messages.  Like the
+// messages of a Conn, Send and Receive must not be used by several
+// goroutines at once.
+type Codec struct {
+	Marshal   func(v interface{}) (data []byte, payloadType byte, err os.Error)
+	Unmarshal func(data []byte, payloadType byte, v interface{}) (err os.Error)
+}
+
+// Send sends v marshaled by cd.Marshal as a single message to ws.
+func (cd Codec) Send(ws *Conn, v interface{}) (err os.Error) {
+	data, payloadType, err := cd.Marshal(v)
+	if err != nil {
+		return
+	}
+	w, err := ws.NextWriter(payloadType)
+	if err != nil {
+		return
+	}
+	if _, err = w.Write(data); err != nil {
+		return
+	}
+	return w.Close()
+}
+
+// Receive receives a single message from ws and unmarshals
+// it into v by cd.Unmarshal.
+func (cd Codec) Receive(ws *Conn, v interface{}) (err os.Error) {
+	payloadType, r, err := ws.NextReader()
+	if err != nil {
+		return
+	}
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return
+	}
+	return cd.Unmarshal(data, payloadType, v)
+}
+
+// ErrBadCodecType is returned by the Message codec for a value
+// that is neither a string nor a []byte.
+var ErrBadCodecType = os.ErrorString("websocket: codec value must be a string or []byte")
+
+func marshalMessage(v interface{}) (data []byte, payloadType byte, err os.Error) {
+	switch v := v.(type) {
+	case string:
+		return []byte(v), TextFrame, nil
+	case []byte:
+		return v, BinaryFrame, nil
+	}
+	return nil, 0, ErrBadCodecType
+}
+
+func unmarshalMessage(data []byte, payloadType byte, v interface{}) (err os.Error) {
+	switch v := v.(type) {
+	case *string:
+		*v = string(data)
+		return nil
+	case *[]byte:
+		*v = data
+		return nil
+	}
+	return ErrBadCodecType
+}
+
+/*
+	Message is a codec to send and receive text and binary data
+	in single messages.
+
+	To send a string as a text message or a []byte as a binary
+	message:
+		websocket.Message.Send(ws, "hello")
+	To receive the data of a message of either type:
+		var msg string
+		websocket.Message.Receive(ws, &msg)
+*/
+var Message = Codec{marshalMessage, unmarshalMessage}
+
+// JSONError is returned by JSON.Receive for a
+// message that is not valid JSON.
+type JSONError struct {
+	Token string // the offending token
+}
+
+func (e *JSONError) String() string { return "websocket: JSON syntax error at " + e.Token }
+
+func marshalJSON(v interface{}) (data []byte, payloadType byte, err os.Error) {
+	var b bytes.Buffer
+	if err = json.Marshal(&b, v); err != nil {
+		return
+	}
+	return b.Bytes(), TextFrame, nil
+}
+
+func unmarshalJSON(data []byte, payloadType byte, v interface{}) (err os.Error) {
+	if ok, errtok := json.Unmarshal(string(data), v); !ok {
+		return &JSONError{errtok}
+	}
+	return nil
+}
+
+/*
+	JSON is a codec to send and receive values as JSON text
+	messages.
+
+	Trivial usage:
+		type T struct {
+			Msg   string
+			Count int
+		}
+
+		// receive a JSON message
+		var data T
+		websocket.JSON.Receive(ws, &data)
+
+		// send a JSON message
+		websocket.JSON.Send(ws, data)
+*/
+var JSON = Codec{marshalJSON, unmarshalJSON}
+
+func marshalGob(v interface{}) (data []byte, payloadType byte, err os.Error) {
+	var b bytes.Buffer
+	if err = gob.NewEncoder(&b).Encode(v); err != nil {
+		return
+	}
+	return b.Bytes(), BinaryFrame, nil
+}
+
+func unmarshalGob(data []byte, payloadType byte, v interface{}) (err os.Error) {
+	return gob.NewDecoder(bytes.NewBuffer(data)).Decode(v)
+}
+
+// Gob is a codec to send and receive values as gobs in binary
+// messages.  Each message is a complete gob stream, carrying the
+// type information along with the value, so messages can be
+// decoded independently of each other.
+var Gob = Codec{marshalGob, unmarshalGob}
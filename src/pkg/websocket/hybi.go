@@ -0,0 +1,310 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Hybi framing and handshake keys.
+// See http://tools.ietf.org/html/draft-ietf-hybi-thewebsocketprotocol
+
+package websocket
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"io"
+	"once"
+	"os"
+	"rand"
+)
+
+// Opcodes of the frames used internally.
+const (
+	continuationFrame = 0
+	closeFrame        = 8
+	pingFrame         = 9
+	pongFrame         = 10
+)
+
+const (
+	hybiVersion       = "13"
+	maxControlPayload = 125
+	maxFrameLength    = 1<<31 - 1
+)
+
+// The GUID appended to the client's key to compute the accept key.
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// A frameHeader is the decoded header of a hybi frame.
+type frameHeader struct {
+	fin     bool
+	opcode  byte
+	masked  bool
+	maskKey [4]byte
+	length  int64
+}
+
+func readFrameHeader(r io.Reader) (h *frameHeader, err os.Error) {
+	var b [8]byte
+	if _, err = io.ReadFull(r, b[0:2]); err != nil {
+		return
+	}
+	h = new(frameHeader)
+	h.fin = b[0]&0x80 != 0
+	h.opcode = b[0] & 0x0f
+	h.masked = b[1]&0x80 != 0
+	if b[0]&0x70 != 0 {
+		// No extensions are negotiated.
+		return nil, ErrBadFrame
+	}
+	switch length := b[1] & 0x7f; length {
+	case 126:
+		if _, err = io.ReadFull(r, b[0:2]); err != nil {
+			return nil, err
+		}
+		h.length = int64(b[0])<<8 | int64(b[1])
+	case 127:
+		if _, err = io.ReadFull(r, b[0:8]); err != nil {
+			return nil, err
+		}
+		for i := 0; i < 8; i++ {
+			h.length = h.length<<8 | int64(b[i])
+		}
+		if h.length < 0 || h.length > maxFrameLength {
+			return nil, ErrFrameTooBig
+		}
+	default:
+		h.length = int64(length)
+	}
+	if h.masked {
+		if _, err = io.ReadFull(r, h.maskKey[0:]); err != nil {
+			return nil, err
+		}
+	}
+	if h.opcode&0x8 != 0 && (!h.fin || h.length > maxControlPayload) {
+		// Control frames cannot be fragmented or long.
+		return nil, ErrBadFrame
+	}
+	return h, nil
+}
+
+// maskBytes masks b with key, starting at position pos of
+// the key, and returns the position following b.
+func maskBytes(key [4]byte, pos int, b []byte) int {
+	for i := range b {
+		b[i] ^= key[pos&3]
+		pos++
+	}
+	return pos & 3
+}
+
+// nextHybiMessage reads frames until the first frame of a
+// data message, handling any control frames on the way.
+func (ws *Conn) nextHybiMessage() (payloadType byte, err os.Error) {
+	h, err := ws.nextHybiFrame()
+	if err != nil {
+		return 0, err
+	}
+	if h.opcode != TextFrame && h.opcode != BinaryFrame {
+		// A continuation without a message
+		// to continue, or a reserved opcode.
+		return 0, ws.fail(CloseProtocolError, ErrBadFrame)
+	}
+	return h.opcode, nil
+}
+
+// nextHybiFrame reads frames until a data frame, answering pings
+// and close frames, and makes the data frame the current frame.
+func (ws *Conn) nextHybiFrame() (h *frameHeader, err os.Error) {
+	for {
+		if h, err = readFrameHeader(ws.buf); err != nil {
+			if err == ErrFrameTooBig {
+				return nil, ws.fail(CloseMessageTooBig, err)
+			}
+			if _, ok := err.(*ProtocolError); ok {
+				return nil, ws.fail(CloseProtocolError, err)
+			}
+			return nil, err
+		}
+		if h.masked == ws.client {
+			// Clients mask every frame; servers mask none.
+			return nil, ws.fail(CloseProtocolError, ErrBadFrame)
+		}
+		if h.opcode&0x8 == 0 {
+			ws.rlen = h.length
+			ws.rfin = h.fin
+			ws.rmask = h.maskKey
+			ws.rmasked = h.masked
+			ws.rpos = 0
+			return h, nil
+		}
+		payload := make([]byte, int(h.length))
+		if _, err = io.ReadFull(ws.buf, payload); err != nil {
+			return nil, err
+		}
+		if h.masked {
+			maskBytes(h.maskKey, 0, payload)
+		}
+		switch h.opcode {
+		case pingFrame:
+			if err = ws.writeFrame(pongFrame, true, payload); err != nil {
+				return nil, err
+			}
+		case pongFrame:
+		case closeFrame:
+			code := CloseNoStatusReceived
+			if len(payload) >= 2 {
+				code = int(payload[0])<<8 | int(payload[1])
+				ws.closeReason = string(payload[2:])
+			}
+			ws.closeCode = code
+			if code == CloseNoStatusReceived {
+				code = CloseNormalClosure
+			}
+			ws.writeClose(code, "")
+			return nil, os.EOF
+		default:
+			return nil, ws.fail(CloseProtocolError, ErrBadFrame)
+		}
+	}
+	panic("unreachable")
+}
+
+// readHybi reads the data of the current message, moving on
+// to its continuation frames as needed.
+func (ws *Conn) readHybi(p []byte) (n int, err os.Error) {
+	for ws.rlen == 0 {
+		if ws.rfin {
+			return 0, os.EOF
+		}
+		h, err := ws.nextHybiFrame()
+		if err != nil {
+			return 0, err
+		}
+		if h.opcode != continuationFrame {
+			return 0, ws.fail(CloseProtocolError, ErrBadFrame)
+		}
+	}
+	if int64(len(p)) > ws.rlen {
+		p = p[0:int(ws.rlen)]
+	}
+	n, err = ws.buf.Read(p)
+	ws.rlen -= int64(n)
+	if ws.rmasked {
+		ws.rpos = maskBytes(ws.rmask, ws.rpos, p[0:n])
+	}
+	if err == os.EOF && ws.rlen > 0 {
+		// The connection ended in the middle of a frame.
+		err = io.ErrUnexpectedEOF
+	}
+	return
+}
+
+// writeFrame writes a single hybi frame, masking
+// the payload if the connection is a client.
+func (ws *Conn) writeFrame(opcode byte, fin bool, payload []byte) os.Error {
+	ws.wmu.Lock()
+	defer ws.wmu.Unlock()
+	if ws.closeSent {
+		return os.EPIPE
+	}
+	if opcode == closeFrame {
+		ws.closeSent = true
+	}
+
+	var h [14]byte
+	h[0] = opcode
+	if fin {
+		h[0] |= 0x80
+	}
+	n := 2
+	switch length := len(payload); {
+	case length <= 125:
+		h[1] = byte(length)
+	case length <= 0xffff:
+		h[1] = 126
+		h[2] = byte(length >> 8)
+		h[3] = byte(length)
+		n = 4
+	default:
+		h[1] = 127
+		for i := 0; i < 8; i++ {
+			h[2+i] = byte(uint64(length) >> uint(56-8*i))
+		}
+		n = 10
+	}
+	if ws.client {
+		var key [4]byte
+		randomBytes(key[0:])
+		h[1] |= 0x80
+		copy(h[n:], key[0:])
+		n += 4
+		masked := make([]byte, len(payload))
+		copy(masked, payload)
+		maskBytes(key, 0, masked)
+		payload = masked
+	}
+	ws.buf.Write(h[0:n])
+	ws.buf.Write(payload)
+	return ws.buf.Flush()
+}
+
+// writeClose sends a close frame unless one has been sent already.
+func (ws *Conn) writeClose(code int, reason string) os.Error {
+	payload := make([]byte, 2+len(reason))
+	payload[0] = byte(code >> 8)
+	payload[1] = byte(code)
+	copy(payload[2:], []byte(reason))
+	if len(payload) > maxControlPayload {
+		return ErrFrameTooBig
+	}
+	return ws.writeFrame(closeFrame, true, payload)
+}
+
+// fail starts the close handshake with the given
+// status code after a protocol error and returns err.
+func (ws *Conn) fail(code int, err os.Error) os.Error {
+	ws.writeClose(code, "")
+	return err
+}
+
+// getAcceptKey returns the Sec-WebSocket-Accept value
+// answering the client's Sec-WebSocket-Key.
+func getAcceptKey(key string) string {
+	h := sha1.New()
+	io.WriteString(h, key+acceptGUID)
+	return encode64(h.Sum())
+}
+
+// newKey returns a new random Sec-WebSocket-Key.
+func newKey() string {
+	var b [16]byte
+	randomBytes(b[0:])
+	return encode64(b[0:])
+}
+
+func encode64(b []byte) string {
+	dst := make([]byte, base64.StdEncoding.EncodedLen(len(b)))
+	base64.StdEncoding.Encode(dst, b)
+	return string(dst)
+}
+
+var urandom io.Reader
+
+func openURandom() {
+	if f, err := os.Open("/dev/urandom", os.O_RDONLY, 0); err == nil {
+		urandom = f
+	}
+}
+
+// randomBytes fills b with unpredictable bytes for keys and masks,
+// falling back to the rand package where /dev/urandom is missing.
+func randomBytes(b []byte) {
+	once.Do(openURandom)
+	if urandom != nil {
+		if _, err := io.ReadFull(urandom, b); err == nil {
+			return
+		}
+	}
+	for i := range b {
+		b[i] = byte(rand.Int())
+	}
+}
@@ -7,6 +7,7 @@ package websocket
 import (
 	"http"
 	"io"
+	"strings"
 )
 
 /*
@@ -37,12 +38,18 @@ import (
 type Handler func(*Conn)
 
 // ServeHTTP implements the http.Handler interface for a Web Socket.
+// Requests carrying a Sec-WebSocket-Key header get the hybi
+// handshake; others get the draft-75 one.
 func (f Handler) ServeHTTP(c *http.Conn, req *http.Request) {
 	if req.Method != "GET" || req.Proto != "HTTP/1.1" {
 		c.WriteHeader(http.StatusBadRequest)
 		io.WriteString(c, "Unexpected request")
 		return
 	}
+	if _, present := req.Header["Sec-Websocket-Key"]; present {
+		f.serveHybi(c, req)
+		return
+	}
 	if v, present := req.Header["Upgrade"]; !present || v != "WebSocket" {
 		c.WriteHeader(http.StatusBadRequest)
 		io.WriteString(c, "missing Upgrade: WebSocket header")
@@ -87,3 +94,57 @@ func (f Handler) ServeHTTP(c *http.Conn, req *http.Request) {
 	ws := newConn(origin, location, protocol, buf, rwc)
 	f(ws)
 }
+
+// serveHybi performs the server side of the hybi opening handshake.
+func (f Handler) serveHybi(c *http.Conn, req *http.Request) {
+	if v, present := req.Header["Upgrade"]; !present || strings.ToLower(v) != "websocket" {
+		c.WriteHeader(http.StatusBadRequest)
+		io.WriteString(c, "missing Upgrade: websocket header")
+		return
+	}
+	if v, present := req.Header["Connection"]; !present || !hasToken(v, "upgrade") {
+		c.WriteHeader(http.StatusBadRequest)
+		io.WriteString(c, "missing Connection: Upgrade header")
+		return
+	}
+	if v := req.Header["Sec-Websocket-Version"]; v != hybiVersion && v != "8" {
+		c.SetHeader("Sec-WebSocket-Version", hybiVersion)
+		c.WriteHeader(http.StatusBadRequest)
+		io.WriteString(c, "unsupported Sec-WebSocket-Version")
+		return
+	}
+	key := req.Header["Sec-Websocket-Key"]
+	origin, present := req.Header["Origin"]
+	if !present {
+		// Version 8 clients send Sec-WebSocket-Origin.
+		origin = req.Header["Sec-Websocket-Origin"]
+	}
+	// Of the subprotocols the client offers, take the first.
+	var protocol string
+	if v, present := req.Header["Sec-Websocket-Protocol"]; present {
+		protocol = strings.TrimSpace(strings.Split(v, ",", 2)[0])
+	}
+
+	rwc, buf, err := c.Hijack()
+	if err != nil {
+		panic("Hijack failed: ", err.String())
+		return
+	}
+	defer rwc.Close()
+	location := "ws://" + req.Host + req.URL.Path
+
+	buf.WriteString("HTTP/1.1 101 Switching Protocols\r\n")
+	buf.WriteString("Upgrade: websocket\r\n")
+	buf.WriteString("Connection: Upgrade\r\n")
+	buf.WriteString("Sec-WebSocket-Accept: " + getAcceptKey(key) + "\r\n")
+	if protocol != "" {
+		buf.WriteString("Sec-WebSocket-Protocol: " + protocol + "\r\n")
+	}
+	buf.WriteString("\r\n")
+	if err := buf.Flush(); err != nil {
+		return
+	}
+	ws := newConn(origin, location, protocol, buf, rwc)
+	ws.hybi = true
+	f(ws)
+}
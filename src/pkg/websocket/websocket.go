@@ -3,7 +3,13 @@
 // license that can be found in the LICENSE file.
 
 // The websocket package implements a client and server for the Web Socket protocol.
-// The protocol is defined at http://tools.ietf.org/html/draft-hixie-thewebsocketprotocol
+// Two versions of the protocol are supported: the framing of
+// http://tools.ietf.org/html/draft-hixie-thewebsocketprotocol-75
+// and the newer framing of
+// http://tools.ietf.org/html/draft-ietf-hybi-thewebsocketprotocol
+// (Sec-WebSocket-Version 13), with masking, fragmentation, binary
+// messages, ping/pong and the close handshake.  Servers accept both;
+// the version is negotiated during the opening handshake.
 package websocket
 
 // TODO(ukai):
@@ -14,6 +20,7 @@ import (
 	"io"
 	"net"
 	"os"
+	"sync"
 )
 
 // WebSocketAddr is an implementation of net.Addr for Web Sockets.
@@ -25,8 +32,37 @@ func (addr WebSocketAddr) Network() string { return "websocket" }
 // String returns the network address for a Web Socket.
 func (addr WebSocketAddr) String() string { return string(addr) }
 
+// Payload types of messages, as passed to NextWriter and
+// returned by NextReader.  The values are the frame opcodes
+// of the hybi protocol.
+const (
+	TextFrame   = 1
+	BinaryFrame = 2
+)
+
+// Status codes of the close handshake.
+const (
+	CloseNormalClosure    = 1000
+	CloseGoingAway        = 1001
+	CloseProtocolError    = 1002
+	CloseUnsupportedData  = 1003
+	CloseNoStatusReceived = 1005
+	CloseMessageTooBig    = 1009
+)
+
+var (
+	ErrNotSupported = &ProtocolError{"not supported by protocol version"}
+	ErrBadFrame     = &ProtocolError{"bad frame"}
+	ErrFrameTooBig  = &ProtocolError{"frame too big"}
+)
+
 // Conn is a channel to communicate to a Web Socket.
 // It implements the net.Conn interface.
+//
+// Read and Write treat the connection as a byte stream: Write sends
+// its argument as a single message of type PayloadType and Read
+// returns the data of successive messages.  NextReader and NextWriter
+// give access to individual messages.
 type Conn struct {
 	// The origin URI for the Web Socket.
 	Origin string
@@ -34,9 +70,33 @@ type Conn struct {
 	Location string
 	// The subprotocol for the Web Socket.
 	Protocol string
+	// The type of the messages sent by Write,
+	// TextFrame (the default) or BinaryFrame.
+	PayloadType byte
 
 	buf *bufio.ReadWriter
 	rwc io.ReadWriteCloser
+
+	hybi   bool // hybi framing rather than draft-75
+	client bool // outgoing frames are masked
+
+	// Owned by the reader.
+	r       io.Reader      // message being read by Read, or nil
+	rmsg    *messageReader // message returned by NextReader, or nil
+	rtype   byte           // draft-75 frame type
+	rlen    int64          // bytes left in the current frame
+	rfin    bool           // current frame is the last of its message
+	rmask   [4]byte
+	rmasked bool
+	rpos    int // position in rmask
+
+	// Serializes frames written by the writer,
+	// pongs sent by the reader and pings.
+	wmu       sync.Mutex
+	closeSent bool
+
+	closeCode   int
+	closeReason string
 }
 
 // newConn creates a new Web Socket.
@@ -46,12 +106,37 @@ func newConn(origin, location, protocol string, buf *bufio.ReadWriter, rwc io.Re
 		bw := bufio.NewWriter(rwc)
 		buf = bufio.NewReadWriter(br, bw)
 	}
-	ws := &Conn{origin, location, protocol, buf, rwc}
+	ws := &Conn{Origin: origin, Location: location, Protocol: protocol, PayloadType: TextFrame, buf: buf, rwc: rwc}
 	return ws
 }
 
 // Read implements the io.Reader interface for a Conn.
+// With draft-75 framing, each call returns the data of one
+// text message, or os.E2BIG if msg is too small to hold it.
 func (ws *Conn) Read(msg []byte) (n int, err os.Error) {
+	if !ws.hybi {
+		return ws.readDraft75Frame(msg)
+	}
+	for {
+		if ws.r == nil {
+			if _, ws.r, err = ws.NextReader(); err != nil {
+				return 0, err
+			}
+		}
+		n, err = ws.r.Read(msg)
+		if err == os.EOF {
+			ws.r = nil
+			if n == 0 {
+				continue
+			}
+			err = nil
+		}
+		return
+	}
+	panic("unreachable")
+}
+
+func (ws *Conn) readDraft75Frame(msg []byte) (n int, err os.Error) {
 	for {
 		frameByte, err := ws.buf.ReadByte()
 		if err != nil {
@@ -103,16 +188,280 @@ func (ws *Conn) Read(msg []byte) (n int, err os.Error) {
 }
 
 // Write implements the io.Writer interface for a Conn.
+// It sends msg as one message of type ws.PayloadType.
 func (ws *Conn) Write(msg []byte) (n int, err os.Error) {
-	ws.buf.WriteByte(0)
+	payloadType := ws.PayloadType
+	if payloadType == 0 {
+		payloadType = TextFrame
+	}
+	w, err := ws.NextWriter(payloadType)
+	if err != nil {
+		return 0, err
+	}
+	if n, err = w.Write(msg); err != nil {
+		return
+	}
+	return n, w.Close()
+}
+
+// NextReader returns the payload type and the contents of the next
+// message received on the connection.  The reader returns os.EOF at
+// the end of the message; any data of the message left unread is
+// discarded by the next call to NextReader or Read.  When the peer
+// starts the close handshake, NextReader answers it and returns
+// os.EOF; see CloseStatus.
+//
+// With hybi framing, ping frames received while reading are
+// answered automatically and pong frames are discarded.
+func (ws *Conn) NextReader() (payloadType byte, r io.Reader, err os.Error) {
+	ws.r = nil
+	if ws.rmsg != nil {
+		// Skip the rest of the previous message.
+		b := make([]byte, 512)
+		for {
+			if _, err = ws.rmsg.Read(b); err != nil {
+				break
+			}
+		}
+		if err != os.EOF {
+			return 0, nil, err
+		}
+		ws.rmsg = nil
+	}
+	if ws.hybi {
+		payloadType, err = ws.nextHybiMessage()
+	} else {
+		payloadType, err = ws.nextDraft75Message()
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+	ws.rmsg = &messageReader{ws: ws}
+	return payloadType, ws.rmsg, nil
+}
+
+// A messageReader reads the data of one message.
+type messageReader struct {
+	ws  *Conn
+	eof bool
+}
+
+func (r *messageReader) Read(p []byte) (n int, err os.Error) {
+	if r.eof || r.ws.rmsg != r {
+		return 0, os.EOF
+	}
+	if r.ws.hybi {
+		n, err = r.ws.readHybi(p)
+	} else {
+		n, err = r.ws.readDraft75(p)
+	}
+	if err == os.EOF {
+		r.eof = true
+	}
+	return
+}
+
+// nextDraft75Message reads the header of the next text or
+// binary frame, skipping text frames of unknown types.
+func (ws *Conn) nextDraft75Message() (payloadType byte, err os.Error) {
+	for {
+		frameByte, err := ws.buf.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		ws.rtype = frameByte
+		if (frameByte & 0x80) == 0x80 {
+			ws.rlen = 0
+			for {
+				c, err := ws.buf.ReadByte()
+				if err != nil {
+					return 0, err
+				}
+				ws.rlen = ws.rlen*128 + int64(c&0x7f)
+				if (c & 0x80) == 0 {
+					break
+				}
+			}
+			return BinaryFrame, nil
+		}
+		if frameByte == 0 {
+			return TextFrame, nil
+		}
+		for {
+			c, err := ws.buf.ReadByte()
+			if err != nil {
+				return 0, err
+			}
+			if c == '\xff' {
+				break
+			}
+		}
+	}
+	panic("unreachable")
+}
+
+func (ws *Conn) readDraft75(p []byte) (n int, err os.Error) {
+	if (ws.rtype & 0x80) == 0x80 {
+		if ws.rlen == 0 {
+			return 0, os.EOF
+		}
+		if int64(len(p)) > ws.rlen {
+			p = p[0:int(ws.rlen)]
+		}
+		n, err = ws.buf.Read(p)
+		ws.rlen -= int64(n)
+		return
+	}
+	for n < len(p) {
+		c, err := ws.buf.ReadByte()
+		if err != nil {
+			return n, err
+		}
+		if c == '\xff' {
+			return n, os.EOF
+		}
+		p[n] = c
+		n++
+	}
+	return n, nil
+}
+
+// NextWriter returns a writer for the next message to send on the
+// connection.  The payloadType is TextFrame or BinaryFrame.  The
+// message is complete when the writer is closed; closing the writer
+// does not close the connection.  With hybi framing, long messages
+// are sent as several fragments.
+func (ws *Conn) NextWriter(payloadType byte) (w io.WriteCloser, err os.Error) {
+	if payloadType != TextFrame && payloadType != BinaryFrame {
+		return nil, os.EINVAL
+	}
+	return &messageWriter{ws: ws, opcode: payloadType}, nil
+}
+
+// maxFragment is the payload size above which
+// hybi messages are sent as several frames.
+const maxFragment = 4096
+
+// A messageWriter accumulates the data of one message.
+type messageWriter struct {
+	ws     *Conn
+	opcode byte // opcode of the next frame
+	buf    []byte
+	closed bool
+}
+
+func (w *messageWriter) Write(p []byte) (n int, err os.Error) {
+	if w.closed {
+		return 0, os.EINVAL
+	}
+	for len(p) > 0 {
+		if w.ws.hybi && len(w.buf) >= maxFragment {
+			if err = w.ws.writeFrame(w.opcode, false, w.buf); err != nil {
+				return
+			}
+			w.opcode = continuationFrame
+			w.buf = w.buf[0:0]
+		}
+		m := len(p)
+		if w.ws.hybi && m > maxFragment-len(w.buf) {
+			m = maxFragment - len(w.buf)
+		}
+		w.buf = appendBytes(w.buf, p[0:m])
+		n += m
+		p = p[m:]
+	}
+	return
+}
+
+func (w *messageWriter) Close() os.Error {
+	if w.closed {
+		return os.EINVAL
+	}
+	w.closed = true
+	if w.ws.hybi {
+		return w.ws.writeFrame(w.opcode, true, w.buf)
+	}
+	return w.ws.writeDraft75Frame(w.opcode, w.buf)
+}
+
+func appendBytes(a, b []byte) []byte {
+	if len(a)+len(b) > cap(a) {
+		n := make([]byte, len(a), 2*cap(a)+len(b))
+		copy(n, a)
+		a = n
+	}
+	n := len(a)
+	a = a[0 : n+len(b)]
+	copy(a[n:], b)
+	return a
+}
+
+// writeDraft75Frame writes msg as a single text frame,
+// or as a length-prefixed binary frame.
+func (ws *Conn) writeDraft75Frame(payloadType byte, msg []byte) os.Error {
+	ws.wmu.Lock()
+	defer ws.wmu.Unlock()
+	if payloadType == TextFrame {
+		ws.buf.WriteByte(0)
+		ws.buf.Write(msg)
+		ws.buf.WriteByte(0xff)
+		return ws.buf.Flush()
+	}
+	ws.buf.WriteByte(0x80)
+	var length [10]byte
+	i := len(length) - 1
+	length[i] = byte(len(msg) & 0x7f)
+	for l := len(msg) >> 7; l > 0; l >>= 7 {
+		i--
+		length[i] = byte(l&0x7f) | 0x80
+	}
+	ws.buf.Write(length[i:])
 	ws.buf.Write(msg)
-	ws.buf.WriteByte(0xff)
-	err = ws.buf.Flush()
-	return len(msg), err
+	return ws.buf.Flush()
+}
+
+// Ping sends a ping frame carrying data, which must be at most
+// 125 bytes long.  The peer answers with a pong frame, which
+// the reading side of the connection discards.
+// Draft-75 connections return ErrNotSupported.
+func (ws *Conn) Ping(data []byte) os.Error {
+	if !ws.hybi {
+		return ErrNotSupported
+	}
+	if len(data) > maxControlPayload {
+		return ErrFrameTooBig
+	}
+	return ws.writeFrame(pingFrame, true, data)
+}
+
+// WriteClose starts the close handshake by sending a close frame
+// with the given status code and reason, without closing the
+// connection.  The peer's answer ends the messages returned by
+// Read and NextReader with os.EOF.  Draft-75 connections return
+// ErrNotSupported.
+func (ws *Conn) WriteClose(code int, reason string) os.Error {
+	if !ws.hybi {
+		return ErrNotSupported
+	}
+	return ws.writeClose(code, reason)
+}
+
+// CloseStatus returns the status code and reason of the close frame
+// received from the peer.  The code is 0 if no close frame has been
+// received and CloseNoStatusReceived if it carried no status.
+func (ws *Conn) CloseStatus() (code int, reason string) {
+	return ws.closeCode, ws.closeReason
 }
 
 // Close implements the io.Closer interface for a Conn.
-func (ws *Conn) Close() os.Error { return ws.rwc.Close() }
+// With hybi framing it first sends a close frame with status
+// CloseNormalClosure, unless one has already been sent.
+func (ws *Conn) Close() os.Error {
+	if ws.hybi {
+		ws.writeClose(CloseNormalClosure, "")
+	}
+	return ws.rwc.Close()
+}
 
 // LocalAddr returns the WebSocket Origin for the connection.
 func (ws *Conn) LocalAddr() net.Addr { return WebSocketAddr(ws.Origin) }
@@ -12,6 +12,7 @@ import (
 	"log"
 	"net"
 	"once"
+	"os"
 	"testing"
 )
 
@@ -19,6 +20,24 @@ var serverAddr string
 
 func echoServer(ws *Conn) { io.Copy(ws, ws) }
 
+// echoMessageServer echoes each message with its payload type.
+func echoMessageServer(ws *Conn) {
+	for {
+		payloadType, r, err := ws.NextReader()
+		if err != nil {
+			return
+		}
+		w, err := ws.NextWriter(payloadType)
+		if err != nil {
+			return
+		}
+		io.Copy(w, r)
+		if w.Close() != nil {
+			return
+		}
+	}
+}
+
 func startServer() {
 	l, e := net.Listen("tcp", ":0") // any available address
 	if e != nil {
@@ -27,6 +46,7 @@ func startServer() {
 	serverAddr = l.Addr().String()
 	log.Stderr("Test WebSocket server listening on ", serverAddr)
 	http.Handle("/echo", Handler(echoServer))
+	http.Handle("/echomsg", Handler(echoMessageServer))
 	go http.Serve(l, nil)
 }
 
@@ -73,3 +93,214 @@ func TestHTTP(t *testing.T) {
 		return
 	}
 }
+
+func dialHybi(t *testing.T, path string) *Conn {
+	client, err := net.Dial("tcp", "", serverAddr)
+	if err != nil {
+		t.Fatal("dialing", err)
+	}
+	ws, err := newHybiClient(path, "localhost", "http://localhost",
+		"ws://localhost"+path, "", client)
+	if err != nil {
+		t.Fatal("WebSocket handshake error", err)
+	}
+	return ws
+}
+
+func TestHybiEcho(t *testing.T) {
+	once.Do(startServer)
+
+	ws := dialHybi(t, "/echo")
+	defer ws.Close()
+	msg := []byte("hello, world\n")
+	if _, err := ws.Write(msg); err != nil {
+		t.Errorf("Write: error %v", err)
+	}
+	var actual_msg = make([]byte, 512)
+	n, err := ws.Read(actual_msg)
+	if err != nil {
+		t.Errorf("Read: error %v", err)
+	}
+	actual_msg = actual_msg[0:n]
+	if !bytes.Equal(msg, actual_msg) {
+		t.Errorf("Echo: expected %q got %q", msg, actual_msg)
+	}
+}
+
+func TestHybiMessages(t *testing.T) {
+	once.Do(startServer)
+
+	ws := dialHybi(t, "/echomsg")
+	defer ws.Close()
+
+	// Longer than a fragment, so sent in several frames.
+	msg := make([]byte, 3*maxFragment+10)
+	for i := range msg {
+		msg[i] = byte(i)
+	}
+	for _, payloadType := range []byte{BinaryFrame, TextFrame} {
+		w, err := ws.NextWriter(payloadType)
+		if err != nil {
+			t.Fatalf("NextWriter: %v", err)
+		}
+		w.Write(msg[0:10])
+		w.Write(msg[10:])
+		if err := w.Close(); err != nil {
+			t.Fatalf("Close: %v", err)
+		}
+		if err := ws.Ping([]byte("ping")); err != nil {
+			t.Fatalf("Ping: %v", err)
+		}
+		typ, r, err := ws.NextReader()
+		if err != nil {
+			t.Fatalf("NextReader: %v", err)
+		}
+		var b bytes.Buffer
+		io.Copy(&b, r)
+		if typ != payloadType || !bytes.Equal(b.Bytes(), msg) {
+			t.Errorf("echo of %d-byte message of type %d: got %d bytes of type %d", len(msg), payloadType, b.Len(), typ)
+		}
+	}
+}
+
+func TestHybiClose(t *testing.T) {
+	once.Do(startServer)
+
+	ws := dialHybi(t, "/echo")
+	defer ws.Close()
+	if err := ws.WriteClose(CloseGoingAway, "bye"); err != nil {
+		t.Fatalf("WriteClose: %v", err)
+	}
+	if _, err := ws.Write([]byte("late")); err == nil {
+		t.Errorf("Write after WriteClose succeeded")
+	}
+	var b [16]byte
+	if n, err := ws.Read(&b); err != os.EOF {
+		t.Fatalf("Read = %d, %v; want os.EOF", n, err)
+	}
+	if code, _ := ws.CloseStatus(); code != CloseGoingAway {
+		t.Errorf("CloseStatus code = %d, want %d", code, CloseGoingAway)
+	}
+}
+
+type bufferCloser struct {
+	bytes.Buffer
+}
+
+func (b *bufferCloser) Close() os.Error { return nil }
+
+type frameTest struct {
+	server bool // frames are read by a server
+	in     []byte
+	out    string
+	err    os.Error
+}
+
+// Examples from the hybi draft.
+var frameTests = []frameTest{
+	// unmasked text message
+	frameTest{false, []byte{0x81, 0x05, 0x48, 0x65, 0x6c, 0x6c, 0x6f}, "Hello", nil},
+	// masked text message
+	frameTest{true, []byte{0x81, 0x85, 0x37, 0xfa, 0x21, 0x3d, 0x7f, 0x9f, 0x4d, 0x51, 0x58}, "Hello", nil},
+	// fragmented unmasked text message
+	frameTest{false, []byte{0x01, 0x03, 0x48, 0x65, 0x6c, 0x80, 0x02, 0x6c, 0x6f}, "Hello", nil},
+	// ping between fragments
+	frameTest{false, []byte{0x01, 0x03, 0x48, 0x65, 0x6c, 0x89, 0x00, 0x80, 0x02, 0x6c, 0x6f}, "Hello", nil},
+	// unmasked frame sent to a server
+	frameTest{true, []byte{0x81, 0x05, 0x48, 0x65, 0x6c, 0x6c, 0x6f}, "", ErrBadFrame},
+	// continuation without a message
+	frameTest{false, []byte{0x80, 0x02, 0x6c, 0x6f}, "", ErrBadFrame},
+	// connection closed part way through a frame
+	frameTest{false, []byte{0x81, 0x05, 0x48, 0x65}, "He", io.ErrUnexpectedEOF},
+}
+
+func TestFrames(t *testing.T) {
+	for i, tt := range frameTests {
+		rwc := new(bufferCloser)
+		rwc.Write(tt.in)
+		ws := newConn("", "", "", nil, rwc)
+		ws.hybi = true
+		ws.client = !tt.server
+		_, r, err := ws.NextReader()
+		if err != nil {
+			if err != tt.err {
+				t.Errorf("#%d: NextReader error %v, want %v", i, err, tt.err)
+			}
+			continue
+		}
+		var b bytes.Buffer
+		_, err = io.Copy(&b, r)
+		if err != tt.err || b.String() != tt.out {
+			t.Errorf("#%d: read %q, %v; want %q, %v", i, b.String(), err, tt.out, tt.err)
+		}
+	}
+}
+
+func TestAcceptKey(t *testing.T) {
+	// Example from the hybi draft.
+	if k := getAcceptKey("dGhlIHNhbXBsZSBub25jZQ=="); k != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("getAcceptKey = %q, want %q", k, "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=")
+	}
+}
+
+type codecTest struct {
+	Msg   string
+	Count int
+}
+
+func testCodec(t *testing.T, name string, ws *Conn, cd Codec) {
+	in := codecTest{"hello", 2}
+	if err := cd.Send(ws, in); err != nil {
+		t.Fatalf("%s.Send: %v", name, err)
+	}
+	var out codecTest
+	if err := cd.Receive(ws, &out); err != nil {
+		t.Fatalf("%s.Receive: %v", name, err)
+	}
+	if out.Msg != in.Msg || out.Count != in.Count {
+		t.Errorf("%s: sent %v, received %v", name, in, out)
+	}
+}
+
+func TestCodecs(t *testing.T) {
+	once.Do(startServer)
+
+	ws := dialHybi(t, "/echomsg")
+	defer ws.Close()
+	testCodec(t, "JSON", ws, JSON)
+	testCodec(t, "Gob", ws, Gob)
+
+	if err := Message.Send(ws, []byte{0, 0xff, 1}); err != nil {
+		t.Fatalf("Message.Send: %v", err)
+	}
+	var b []byte
+	if err := Message.Receive(ws, &b); err != nil || !bytes.Equal(b, []byte{0, 0xff, 1}) {
+		t.Errorf("Message.Receive = %v, %v", b, err)
+	}
+	if err := Message.Send(ws, 1); err != ErrBadCodecType {
+		t.Errorf("Message.Send(1) = %v, want ErrBadCodecType", err)
+	}
+
+	Message.Send(ws, "{bad json")
+	var v codecTest
+	if err := JSON.Receive(ws, &v); err == nil {
+		t.Errorf("JSON.Receive of invalid JSON succeeded")
+	}
+}
+
+func TestCodecsDraft75(t *testing.T) {
+	once.Do(startServer)
+
+	client, err := net.Dial("tcp", "", serverAddr)
+	if err != nil {
+		t.Fatal("dialing", err)
+	}
+	ws, err := newClient("/echomsg", "localhost", "http://localhost",
+		"ws://localhost/echomsg", "", client)
+	if err != nil {
+		t.Fatal("WebSocket handshake error", err)
+	}
+	defer ws.Close()
+	testCodec(t, "JSON", ws, JSON)
+	testCodec(t, "Gob", ws, Gob)
+}